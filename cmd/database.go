@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/app"
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/db"
+)
+
+// openDatabase connects to the primary PostgreSQL database in cfg
+func openDatabase(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	return db.Connect(ctx, primaryOptions(cfg).DSN(), pool(cfg), retry(cfg))
+}
+
+// openServices connects to the primary database in cfg and wires the services the
+// server uses. close releases the connection.
+func openServices(ctx context.Context, cfg *config.Config, logger *zap.Logger) (svc *app.Services, close func(), err error) {
+	gormDB, err := openDatabase(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := gormDB.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	svc, err = app.NewServices(cfg, app.Databases{Primary: gormDB}, logger)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	return svc, func() { _ = db.Close() }, nil
+}
+
+// openReplica connects to the read replica in cfg
+func openReplica(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	return db.Connect(ctx, replicaOptions(cfg).DSN(), pool(cfg), retry(cfg))
+}
+
+func primaryOptions(cfg *config.Config) db.Options {
+	return db.Options{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		Name:     cfg.Database.Name,
+		SSLMode:  cfg.Database.SSLMode,
+	}
+}
+
+// replicaOptions fills the replica settings left empty with the primary's
+func replicaOptions(cfg *config.Config) db.Options {
+	opts, replica := primaryOptions(cfg), cfg.Database.Replica
+	opts.Host = replica.Host
+	if replica.Port != "" {
+		opts.Port = replica.Port
+	}
+	if replica.User != "" {
+		opts.User, opts.Password = replica.User, replica.Password
+	}
+	if replica.Name != "" {
+		opts.Name = replica.Name
+	}
+	if replica.SSLMode != "" {
+		opts.SSLMode = replica.SSLMode
+	}
+	return opts
+}
+
+func pool(cfg *config.Config) db.Pool {
+	return db.Pool{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	}
+}
+
+func retry(cfg *config.Config) db.Retry {
+	return db.Retry{
+		Attempts:   cfg.Database.ConnectAttempts,
+		Backoff:    cfg.Database.ConnectBackoff,
+		MaxBackoff: cfg.Database.ConnectMaxBackoff,
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/app"
+	"bone_appetit_r4_service/internal/config"
+)
+
+// runEgressIP implements the egress-ip subcommand. It prints the lookup as JSON and
+// fails when registered IPs are configured and the egress IP is not one of them.
+func runEgressIP(ctx context.Context, cfg *config.Config, _ []string, _ *zap.Logger) error {
+	result, err := app.NewEgressResolver(cfg).Lookup(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := printJSON(result); err != nil {
+		return err
+	}
+
+	if len(result.RegisteredIPs) > 0 && !result.Registered {
+		return errors.New("egress IP is not registered with R4")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+)
+
+const keysUsage = "usage: server keys create -name name [-store bone|appa] | list [-json] | revoke <id>"
+
+// runKeys implements the keys subcommand: create, list and revoke the API keys of the
+// stores, like /admin/api-keys
+func runKeys(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	var name, store *string
+	var asJSON *bool
+	switch args[0] {
+	case "create":
+		name = flags.String("name", "", "who the key is for, e.g. order-service")
+		store = flags.String("store", config.StoreBone, "store the key acts for")
+	case "list":
+		asJSON = flags.Bool("json", false, "print JSON instead of a table")
+	case "revoke":
+	default:
+		return errors.New(keysUsage)
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if *name == "" || len(*name) > 255 || flags.NArg() != 0 {
+			return errors.New(keysUsage)
+		}
+		if err := checkStore(*store); err != nil {
+			return err
+		}
+	case "revoke":
+		if flags.NArg() != 1 {
+			return errors.New(keysUsage)
+		}
+	}
+
+	svc, closeDB, err := openServices(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	switch args[0] {
+	case "create":
+		created, err := svc.APIKeys.Create(ctx, *name, *store)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "The key is shown only once, store it now:")
+		fmt.Println(created.Key)
+		return nil
+
+	case "list":
+		keys, err := svc.APIKeys.List(ctx)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(keys)
+		}
+		w := newTable("ID", "NAME", "STORE", "PREFIX", "CREATED AT", "LAST USED AT", "REVOKED AT")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.Name, k.Store, k.Prefix, k.CreatedAt.Format(time.RFC3339), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+		}
+		return w.Flush()
+
+	default:
+		id, err := strconv.Atoi(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid API key id %q", flags.Arg(0))
+		}
+		if err := svc.APIKeys.Revoke(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("API key %d revoked\n", id)
+		return nil
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,123 +1,119 @@
 package main
 
 import (
-	"log"
-	"time"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	_ "github.com/joho/godotenv/autoload"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"bone_appetit_r4_service/internal/config"
-	"bone_appetit_r4_service/internal/handlers"
-	"bone_appetit_r4_service/internal/routers"
-	"bone_appetit_r4_service/internal/services"
-	"bone_appetit_r4_service/pkg/db"
-	"bone_appetit_r4_service/pkg/ipfy"
 	"bone_appetit_r4_service/pkg/logs"
-	"bone_appetit_r4_service/pkg/middleware"
-	"bone_appetit_r4_service/pkg/r4bank"
-	"fmt"
 )
 
+const usage = `usage: server [command] [arguments]
+
+Commands:
+  server                          serve the API (the default)
+  migrate up | down [steps] | status
+  egress-ip                       check the IP R4 sees the service calling from
+  payments find [flags]           search the payments received by a store
+  operation refresh [flags] <id>  query an immediate debit with ConsultarOperaciones
+  webhooks replay [flags] [id...] send event deliveries again
+  keys create | list | revoke     manage the API keys of the stores
+  users create [flags]            add a back-office user, reading the password from stdin
+
+Every command reads the same configuration as the server. Run
+"server <command> <subcommand> -h" for its flags.
+`
+
+// command is a support subcommand, run with the arguments following its name
+type command func(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error
+
+var commands = map[string]command{
+	"migrate":   runMigrate,
+	"egress-ip": runEgressIP,
+	"payments":  runPayments,
+	"operation": runOperation,
+	"webhooks":  runWebhooks,
+	"keys":      runKeys,
+	"users":     runUsers,
+}
+
 func main() {
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("loading config: %v", err)
+	name, args := "server", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	if cfg.Port == "" {
-		cfg.Port = "8080"
+	// Only the server logs to stdout, the other commands print their results there
+	logger := logs.NewCLILogger()
+	if name == "server" {
+		logger = logs.NewZapLogger()
 	}
+	// Sync fails on stdout in some terminals, there is nothing left to report it to
+	defer func() { _ = logger.Sync() }()
+	zap.ReplaceGlobals(logger)
 
-	logger := logs.NewZapLogger()
-	defer func() {
-		if err := logger.Sync(); err != nil {
-			fmt.Printf("error syncing logger: %v\n", err)
-		}
-	}()
-
-	sslmode := cfg.SSLMode
-	fmt.Printf("sslmode -> %s\n", sslmode)
-	if len(sslmode) > 0 {
-		sslmode = "sslmode=" + sslmode
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Print(usage)
+		return
 	}
 
-	//connect the database
-	connStr := fmt.Sprintf("host=%s port=%s user=%s "+
-		"password=%s dbname=%s %s",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, sslmode)
-	// gorm connect
-	gormDB, err := db.NewDBSQLHandler(connStr)
+	cfg, err := config.Load()
 	if err != nil {
-		logger.Fatal(err.Error(), zap.Any("host", cfg.DBHost), zap.Any("port", cfg.DBPort), zap.Any("user", cfg.DBUser), zap.Any("dbname", cfg.DBName))
+		logger.Fatal("loading config", zap.Error(err))
 	}
 
-	db, err := gormDB.DB()
-	if err != nil {
-		logger.Fatal(err.Error(), zap.Any("host", cfg.DBHost), zap.Any("port", cfg.DBPort), zap.Any("user", cfg.DBUser), zap.Any("dbname", cfg.DBName))
-	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			fmt.Printf("error db body: %v\n", err)
+	if name == "server" {
+		if err := runServer(cfg, logger); err != nil {
+			logger.Fatal("server stopped after a failure", zap.Error(err))
 		}
-	}()
+		return
+	}
 
-	loc, err := time.LoadLocation("America/Caracas")
-	if err != nil {
-		logger.Fatal("could not load Venezuela time zone", zap.Error(err))
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("unknown command", zap.String("command", name))
 	}
 
-	router := gin.Default()
-	router.Use(cors.Default())
-
-	router.GET("/healthz", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "OK",
-		})
-	})
-
-	// Init resources
-	r4BoneRestClient := r4bank.NewClient(cfg.R4BoneEntryPoint, cfg.R4BoneCommerceToken, logger)
-	r4AppaRestClient := r4bank.NewClient(cfg.R4APPAEntryPoint, cfg.R4APPACommerceToken, logger)
-
-	// initialize services
-	r4BoneService := services.NewR4Service(logger, r4BoneRestClient)
-	r4AppaService := services.NewR4Service(logger, r4AppaRestClient)
-	webhookService := services.NewWebhookService(gormDB, loc, logger)
-
-	// initialize middleware
-	authBoneMiddleware := middleware.NewWebhookAuthMiddleware(cfg.BoneSecret, cfg.R4BoneCommerceToken)
-	authAppaMiddleware := middleware.NewWebhookAuthMiddleware(cfg.APPASecret, cfg.R4APPACommerceToken)
-
-	// Initialize handlers
-	r4BoneHandler := handlers.NewR4Handler(r4BoneService)
-	r4AppaHandler := handlers.NewR4Handler(r4AppaService)
-	webhookHandler := handlers.NewWebhookHandler(webhookService)
-
-	// Initialize webhook routes
-	r4BoneRoutes := routers.NewR4Routes(r4BoneHandler)
-	r4AppaRoutes := routers.NewR4AppaRoutes(r4AppaHandler)
-	webhookBoneRouter := routers.NewWebhookRouter(webhookHandler)
-	webhookAppaRouter := routers.NewWebhookAppaRouter(webhookHandler)
-
-	// Set up routes with authentication middleware
-	r4BoneRoutes.SetRouter(router, authBoneMiddleware)
-	webhookBoneRouter.SetRouter(router, authBoneMiddleware)
-	r4AppaRoutes.SetRouter(router, authAppaMiddleware)
-	webhookAppaRouter.SetRouter(router, authAppaMiddleware)
-
-	// Get IP public
-	ipfy.GetIPInfo()
-	if gormDB != nil {
-		logger.Info("database connected successfully")
-	} else {
-		logger.Info("database connection failed")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, cfg, args, logger); err != nil && !errors.Is(err, flag.ErrHelp) {
+		stop()
+		logger.Fatal(name+" failed", zap.Error(err))
 	}
+}
 
-	if err := router.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+// checkStore fails for store names the service is not wired for
+func checkStore(name string) error {
+	if slices.Contains(config.StoreNames, name) {
+		return nil
 	}
+	return fmt.Errorf("unknown store %q, must be one of %s", name, strings.Join(config.StoreNames, ", "))
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// newTable writes aligned columns to stdout, it must be flushed
+func newTable(header ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	return w
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/db/migrations"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate implements the migrate subcommand: up, down [steps] and status
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	gormDB, err := openDatabase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migrate(ctx, db, args, logger)
+}
+
+// migrate runs a migrate subcommand against db
+func migrate(ctx context.Context, db *sql.DB, args []string, logger *zap.Logger) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			logger.Info("migration applied", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("database schema is up to date", zap.Int64("version", migrator.Latest()))
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number: %s", migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			logger.Info("migration reverted", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := newTable("VERSION", "NAME", "APPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unknown migrate command %q: %s", args[0], migrateUsage)
+}
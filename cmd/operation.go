@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+)
+
+const operationUsage = "usage: server operation refresh [-store bone|appa] <operation id>"
+
+// runOperation implements the operation subcommand: refresh queries the current state
+// of an immediate debit with ConsultarOperaciones and prints it as JSON
+func runOperation(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	if len(args) == 0 || args[0] != "refresh" {
+		return errors.New(operationUsage)
+	}
+
+	flags := flag.NewFlagSet("operation refresh", flag.ContinueOnError)
+	store := flags.String("store", config.StoreBone, "store that requested the debit")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(operationUsage)
+	}
+	if err := checkStore(*store); err != nil {
+		return err
+	}
+
+	svc, closeDB, err := openServices(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	operation, err := svc.R4[*store].GetOperationByID(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printJSON(operation)
+}
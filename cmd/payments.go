@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/models"
+)
+
+const paymentsUsage = "usage: server payments find [-store bone|appa] [-reference ref] [-phone phone] [-bank code] [-from date] [-to date] [-limit n] [-json]"
+
+// runPayments implements the payments subcommand: find searches the payments R4
+// notified to a store, like GET /v1/<store>/payments
+func runPayments(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	if len(args) == 0 || args[0] != "find" {
+		return errors.New(paymentsUsage)
+	}
+
+	var query models.PaymentQuery
+	flags := flag.NewFlagSet("payments find", flag.ContinueOnError)
+	store := flags.String("store", config.StoreBone, "store the payments were made to")
+	flags.StringVar(&query.Reference, "reference", "", "payment reference")
+	flags.StringVar(&query.SenderPhone, "phone", "", "phone the payment was sent from")
+	flags.StringVar(&query.Bank, "bank", "", "issuing bank code, e.g. 0102")
+	flags.StringVar(&query.From, "from", "", "first day, YYYY-MM-DD")
+	flags.StringVar(&query.To, "to", "", "last day, YYYY-MM-DD")
+	flags.IntVar(&query.Limit, "limit", 50, "maximum payments listed, newest first")
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := checkStore(*store); err != nil {
+		return err
+	}
+	for _, date := range []string{query.From, query.To} {
+		if _, err := time.Parse(time.DateOnly, date); date != "" && err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if query.Limit < 1 || query.Limit > 1000 {
+		return errors.New("limit must be between 1 and 1000")
+	}
+
+	svc, closeDB, err := openServices(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	resp, err := svc.Payments.FindPayments(ctx, *store, &query)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(resp)
+	}
+
+	w := newTable("ID", "DATE", "REFERENCE", "AMOUNT", "SENDER", "BANK", "ORDER")
+	for _, p := range resp.Payments {
+		order := "-"
+		if p.OrderID != nil {
+			order = strconv.Itoa(*p.OrderID)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.2f\t%s\t%s\t%s\n",
+			p.ID, p.Date.Format(time.DateOnly), p.Reference, p.Amount, p.SenderPhone, p.IssuingBankName, order)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,59 @@
+// Command r4sim runs the R4 Conecta simulator. Point the service at it with
+// R4_BONE_ENTRY_POINT=http://localhost:9090 and the same commerce token.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/r4sim"
+)
+
+func main() {
+	var (
+		addr       = flag.String("addr", ":9090", "address to listen on")
+		token      = flag.String("commerce-token", "sim-commerce-token", "commerce token the service signs with")
+		secret     = flag.String("secret", "sim-secret", "webhook secret used to sign R4consulta/R4notifica")
+		rate       = flag.Float64("rate", 36.5, "BCV USD rate returned by MBbcv")
+		debitDelay = flag.Duration("debit-delay", 4*time.Second, "time a debit stays AC00")
+		debitCode  = flag.String("debit-code", r4sim.CodeAccepted, "final code of debits with a valid OTP (ACCP, AM04, AC01...)")
+		balance    = flag.Float64("balance", 0, "debits and payouts above this amount fail with AM04, 0 for unlimited")
+	)
+	flag.Parse()
+
+	logger, _ := zap.NewDevelopment()
+	defer func() { _ = logger.Sync() }()
+
+	sim := r4sim.New(r4sim.Config{
+		CommerceToken: *token,
+		Secret:        *secret,
+		Rate:          *rate,
+		DebitDelay:    *debitDelay,
+		DebitCode:     *debitCode,
+		Balance:       *balance,
+		Logger:        logger,
+	})
+
+	srv := &http.Server{Addr: *addr, Handler: sim, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("R4 simulator listening", zap.String("addr", *addr))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("r4sim stopped", zap.Error(err))
+	}
+}
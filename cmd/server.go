@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/app"
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/telemetry"
+)
+
+// traceFlushReserve is the end of the shutdown deadline kept for sending the last spans,
+// at most a fifth of it
+const traceFlushReserve = time.Second
+
+// runServer implements the server command: it serves the HTTP and gRPC APIs and runs
+// the background workers until SIGINT or SIGTERM, then drains them. A server failing to
+// serve is drained as well, and its error returned.
+func runServer(cfg *config.Config, logger *zap.Logger) error {
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		logger.Fatal("could not set up tracing", zap.Error(err))
+	}
+
+	gormDB, err := openDatabase(context.Background(), cfg)
+	if err != nil {
+		logger.Fatal(err.Error(), zap.Any("host", cfg.Database.Host), zap.Any("port", cfg.Database.Port), zap.Any("user", cfg.Database.User), zap.Any("dbname", cfg.Database.Name))
+	}
+
+	db, err := gormDB.DB()
+	if err != nil {
+		logger.Fatal(err.Error(), zap.Any("host", cfg.Database.Host), zap.Any("port", cfg.Database.Port), zap.Any("user", cfg.Database.User), zap.Any("dbname", cfg.Database.Name))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Error("could not close database", zap.Error(err))
+		}
+	}()
+
+	if cfg.Database.AutoMigrate {
+		if err := migrate(context.Background(), db, []string{"up"}, logger); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+	}
+
+	if err := telemetry.InstrumentGORM(gormDB); err != nil {
+		logger.Fatal("could not instrument database", zap.Error(err))
+	}
+
+	if err := metrics.RegisterDBStats(db, "primary"); err != nil {
+		logger.Fatal("could not register database metrics", zap.Error(err))
+	}
+
+	// The payment query and export endpoints read from the replica when there is one
+	var replica *gorm.DB
+	if cfg.Database.Replica.Enabled() {
+		replica, err = openReplica(context.Background(), cfg)
+		if err != nil {
+			logger.Fatal("could not connect to the read replica", zap.Error(err), zap.String("host", cfg.Database.Replica.Host))
+		}
+		if err := telemetry.InstrumentGORM(replica); err != nil {
+			logger.Fatal("could not instrument read replica", zap.Error(err))
+		}
+		replicaDB, err := replica.DB()
+		if err != nil {
+			logger.Fatal("could not get read replica pool", zap.Error(err))
+		}
+		defer func() {
+			if err := replicaDB.Close(); err != nil {
+				logger.Error("could not close read replica", zap.Error(err))
+			}
+		}()
+		if err := metrics.RegisterDBStats(replicaDB, "replica"); err != nil {
+			logger.Fatal("could not register read replica metrics", zap.Error(err))
+		}
+		logger.Info("read replica connected", zap.String("host", cfg.Database.Replica.Host))
+	}
+
+	service, err := app.New(cfg, app.Databases{Primary: gormDB, Replica: replica, PrimaryDSN: primaryOptions(cfg).DSN()}, logger)
+	if err != nil {
+		logger.Fatal("could not initialize the service", zap.Error(err))
+	}
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           service.Router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var grpcListener net.Listener
+	if cfg.GRPC.Enabled() {
+		grpcListener, err = net.Listen("tcp", ":"+cfg.GRPC.Port)
+		if err != nil {
+			logger.Fatal("could not listen for gRPC", zap.Error(err), zap.String("port", cfg.GRPC.Port))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	service.Start(ctx)
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	if grpcListener != nil {
+		go func() {
+			if err := service.GRPC.Serve(grpcListener); err != nil {
+				serverErr <- err
+			}
+		}()
+		logger.Info("gRPC server started", zap.String("port", cfg.GRPC.Port))
+	}
+	service.Readiness.SetReady()
+	logger.Info("server started", zap.String("port", cfg.Port))
+
+	var runErr error
+	select {
+	case runErr = <-serverErr:
+		logger.Error("failed to run server, shutting down", zap.Error(runErr))
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Every phase counts against a single deadline from the signal: Cloud Run and most
+	// orchestrators kill the instance a fixed time after SIGTERM, 10s by default there
+	deadline := time.Now().Add(cfg.ShutdownTimeout)
+	logger.Info("shutting down", zap.Duration("delay", cfg.ShutdownDelay), zap.Duration("timeout", cfg.ShutdownTimeout))
+
+	// Stop receiving traffic before draining so the load balancer routes elsewhere
+	service.Readiness.SetNotReady()
+	if runErr == nil {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	drainCtx, cancelDrain := context.WithDeadline(context.Background(),
+		deadline.Add(-min(traceFlushReserve, cfg.ShutdownTimeout/5)))
+	defer cancelDrain()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		logger.Error("could not drain in-flight requests", zap.Error(err))
+		if err := srv.Close(); err != nil {
+			logger.Error("could not close server", zap.Error(err))
+		}
+	}
+	stopGRPC(drainCtx, service.GRPC)
+	if err := service.Workers.Shutdown(drainCtx); err != nil {
+		logger.Error("background workers did not finish in time", zap.Error(err))
+	}
+	if err := service.Close(); err != nil {
+		logger.Error("could not close the broker connection", zap.Error(err))
+	}
+
+	// Flushed last, so the spans of the shutdown are sent too
+	flushCtx, cancelFlush := context.WithDeadline(context.Background(), deadline)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("could not flush traces", zap.Error(err))
+	}
+
+	logger.Info("server stopped")
+	return runErr
+}
+
+// stopGRPC waits for in-flight calls and streams to finish, cancelling them when ctx is done
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+)
+
+const usersUsage = "usage: server users create -email email -name name [-role support|finance] < password"
+
+// runUsers implements the users subcommand: create the back-office users. The password
+// is read from the first line of stdin so it stays out of the shell history.
+func runUsers(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New(usersUsage)
+	}
+
+	flags := flag.NewFlagSet("users create", flag.ContinueOnError)
+	email := flags.String("email", "", "email the user signs in with")
+	name := flags.String("name", "", "name shown in the back office")
+	role := flags.String("role", dbModels.RoleSupport, "support, or finance to approve change payouts")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *email == "" || *name == "" || flags.NArg() != 0 {
+		return errors.New(usersUsage)
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("reading password: %w", err)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	svc, closeDB, err := openServices(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	user, err := svc.Users.Create(ctx, *email, *name, *role, strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("user %d created for %s\n", user.ID, user.Email)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+)
+
+const webhooksUsage = "usage: server webhooks replay <delivery id>... | -subscription id [-status failed|delivered|pending]"
+
+// runWebhooks implements the webhooks subcommand: replay queues event deliveries to be
+// sent again by the running servers, like POST /admin/deliveries/<id>/retry
+func runWebhooks(ctx context.Context, cfg *config.Config, args []string, logger *zap.Logger) error {
+	if len(args) == 0 || args[0] != "replay" {
+		return errors.New(webhooksUsage)
+	}
+
+	flags := flag.NewFlagSet("webhooks replay", flag.ContinueOnError)
+	subscription := flags.Int("subscription", 0, "replay the deliveries of this subscription instead of the given ones")
+	status := flags.String("status", dbModels.DeliveryFailed, "status of the subscription's deliveries replayed, empty for all")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if (*subscription == 0) == (flags.NArg() == 0) {
+		return errors.New(webhooksUsage)
+	}
+
+	var ids []int64
+	for _, arg := range flags.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid delivery id %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	svc, closeDB, err := openServices(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if *subscription != 0 {
+		deliveries, err := svc.Subscriptions.Deliveries(ctx, *subscription, *status)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+	}
+
+	for _, id := range ids {
+		if err := svc.Subscriptions.Retry(ctx, id); err != nil {
+			return fmt.Errorf("replaying delivery %d: %w", id, err)
+		}
+		fmt.Printf("delivery %d queued\n", id)
+	}
+	fmt.Printf("%d deliveries queued, the servers send them on their next poll\n", len(ids))
+	return nil
+}
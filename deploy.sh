@@ -10,6 +10,9 @@ go build -trimpath -ldflags "-s -w" -o bin/server ./cmd
 echo ">> setcap"
 sudo setcap 'cap_net_bind_service=+ep' /app/bin/server
 
+echo ">> Migrating database"
+./bin/server migrate up
+
 echo ">> Restart service"
 sudo systemctl restart goapp
 
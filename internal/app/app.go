@@ -0,0 +1,336 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/backoffice"
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/delivery"
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/feed"
+	"bone_appetit_r4_service/internal/handlers"
+	"bone_appetit_r4_service/internal/outbox"
+	"bone_appetit_r4_service/internal/reload"
+	"bone_appetit_r4_service/internal/routers"
+	"bone_appetit_r4_service/internal/rpc"
+	"bone_appetit_r4_service/pkg/banks"
+	"bone_appetit_r4_service/pkg/broker"
+	"bone_appetit_r4_service/pkg/db/migrations"
+	"bone_appetit_r4_service/pkg/health"
+	"bone_appetit_r4_service/pkg/ipfy"
+	"bone_appetit_r4_service/pkg/lifecycle"
+	"bone_appetit_r4_service/pkg/middleware"
+	"bone_appetit_r4_service/pkg/ratelimit"
+	"bone_appetit_r4_service/pkg/telemetry"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
+// Databases are the connections the service reads and writes through
+type Databases struct {
+	Primary *gorm.DB
+	// Replica serves the payment query and export endpoints, Primary is used when nil
+	Replica *gorm.DB
+	// PrimaryDSN is where the live feed opens its LISTEN connection, outside the pool
+	PrimaryDSN string
+}
+
+// App is the service wired together: the router with every handler, service and
+// R4 client behind it, plus the background workers they share.
+type App struct {
+	Router    *gin.Engine
+	GRPC      *grpc.Server     // served by cmd when grpc.port is set
+	Broker    broker.Publisher // nil when broker.kind is none
+	Readiness *health.Readiness
+	Workers   *lifecycle.Workers
+
+	cfg        *config.Config
+	logger     *zap.Logger
+	reloader   *reload.Reloader
+	egress     *ipfy.Resolver
+	dispatcher *delivery.Dispatcher
+	relay      *outbox.Relay
+	feed       *feed.Hub
+}
+
+// New wires the service from cfg. The databases must already be migrated, or
+// readiness reports the schema as behind.
+func New(cfg *config.Config, dbs Databases, logger *zap.Logger) (*App, error) {
+	svc, err := NewServices(cfg, dbs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validation.RegisterGinValidators(); err != nil {
+		return nil, fmt.Errorf("registering request validators: %w", err)
+	}
+
+	db, err := dbs.Primary.DB()
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return nil, err
+	}
+	readiness := health.NewReadiness()
+	workers := lifecycle.NewWorkers()
+
+	router := gin.New()
+	// Propagate request cancellation to handlers so draining can interrupt debit polling
+	router.ContextWithFallback = true
+	// The client IP the webhooks are limited by comes from X-Forwarded-For only when sent
+	// by these, without any the peer address is used
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("setting trusted proxies: %w", err)
+	}
+	router.Use(
+		cors.Default(),
+		otelgin.Middleware(telemetry.ServiceName, otelgin.WithFilter(telemetry.SkipProbes)),
+		middleware.RequestID(logger),
+		middleware.AccessLog(),
+		middleware.Recovery(),
+		middleware.Metrics(),
+	)
+
+	// Init resources
+	boneStore, appaStore := cfg.Store(config.StoreBone), cfg.Store(config.StoreAppa)
+	r4BoneRestClient, r4AppaRestClient := svc.clients[config.StoreBone], svc.clients[config.StoreAppa]
+
+	// Readiness checks
+	checker := health.NewChecker(readiness, cfg.HealthCheckTimeout)
+	checker.Add("database", health.DatabaseCheck(db))
+	if dbs.Replica != nil {
+		replicaDB, err := dbs.Replica.DB()
+		if err != nil {
+			return nil, err
+		}
+		checker.Add("database_replica", health.Optional(health.DatabaseCheck(replicaDB)))
+	}
+	checker.Add("schema", health.SchemaCheck(migrator))
+	checker.Add("r4_bone", health.R4ClientCheck(r4BoneRestClient))
+	checker.Add("r4_appa", health.R4ClientCheck(r4AppaRestClient))
+
+	// initialize services
+	subscriptionService := svc.Subscriptions
+	r4BoneService, r4AppaService := svc.R4[config.StoreBone], svc.R4[config.StoreAppa]
+	egressResolver := NewEgressResolver(cfg)
+	webhookService := svc.Webhooks
+	paymentService := svc.Payments
+	apiKeyService := svc.APIKeys
+	feedHub := feed.NewHub(dbs.Primary, dbs.PrimaryDSN, feed.Config{
+		Buffer:           cfg.LiveFeed.Buffer,
+		ReplayLimit:      cfg.LiveFeed.ReplayLimit,
+		ReconnectBackoff: cfg.LiveFeed.ReconnectBackoff,
+	}, logger)
+	limiter := NewRateLimiter(cfg, db)
+
+	// initialize middleware
+	authBoneMiddleware := middleware.NewWebhookAuthMiddleware(config.StoreBone, boneStore.Secret, boneStore.CommerceToken)
+	authAppaMiddleware := middleware.NewWebhookAuthMiddleware(config.StoreAppa, appaStore.Secret, appaStore.CommerceToken)
+
+	// Initialize handlers
+	r4BoneHandler := handlers.NewR4Handler(r4BoneService, config.StoreBone, limiter, handlers.Legacy)
+	r4AppaHandler := handlers.NewR4Handler(r4AppaService, config.StoreAppa, limiter, handlers.Legacy)
+	r4BoneV1Handler := handlers.NewR4Handler(r4BoneService, config.StoreBone, limiter, handlers.V1)
+	r4AppaV1Handler := handlers.NewR4Handler(r4AppaService, config.StoreAppa, limiter, handlers.V1)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, workers, limiter)
+	healthHandler := handlers.NewHealthHandler(readiness, checker)
+	adminHandler := handlers.NewAdminHandler(egressResolver, apiKeyService, subscriptionService)
+	bankHandler := handlers.NewBankHandler(banks.Default(), handlers.Legacy)
+	bankV1Handler := handlers.NewBankHandler(banks.Default(), handlers.V1)
+	paymentBoneHandler := handlers.NewPaymentHandler(paymentService, config.StoreBone, handlers.Legacy)
+	paymentAppaHandler := handlers.NewPaymentHandler(paymentService, config.StoreAppa, handlers.Legacy)
+	paymentBoneV1Handler := handlers.NewPaymentHandler(paymentService, config.StoreBone, handlers.V1)
+	paymentAppaV1Handler := handlers.NewPaymentHandler(paymentService, config.StoreAppa, handlers.V1)
+	feedBoneHandler := handlers.NewFeedHandler(feedHub, config.StoreBone, cfg.LiveFeed.Heartbeat)
+	feedAppaHandler := handlers.NewFeedHandler(feedHub, config.StoreAppa, cfg.LiveFeed.Heartbeat)
+	docsHandler, err := handlers.NewDocsHandler()
+	if err != nil {
+		return nil, err
+	}
+	backofficePages, err := backoffice.Parse(svc.loc)
+	if err != nil {
+		return nil, err
+	}
+	backofficeHandler := handlers.NewBackofficeHandler(backofficePages, handlers.BackofficeServices{
+		Users:      svc.Users,
+		Payments:   paymentService,
+		Operations: svc.Operations,
+		Payouts:    svc.Payouts,
+		R4:         svc.R4,
+	}, config.StoreNames, cfg.Backoffice.InsecureCookie)
+
+	// Initialize webhook routes
+	healthRouter := routers.NewHealthRouter(healthHandler)
+	metricsRouter := routers.NewMetricsRouter(cfg.MetricsToken)
+	adminRouter := routers.NewAdminRouter(adminHandler, cfg.AdminToken)
+	docsRouter := routers.NewDocsRouter(docsHandler)
+	r4BoneRoutes := routers.NewR4Routes(r4BoneHandler, bankHandler, paymentBoneHandler)
+	r4AppaRoutes := routers.NewR4AppaRoutes(r4AppaHandler, bankHandler, paymentAppaHandler)
+	v1BoneRoutes := routers.NewV1Routes(config.StoreBone, r4BoneV1Handler, bankV1Handler, paymentBoneV1Handler)
+	v1AppaRoutes := routers.NewV1Routes(config.StoreAppa, r4AppaV1Handler, bankV1Handler, paymentAppaV1Handler)
+	webhookBoneRouter := routers.NewWebhookRouter(webhookHandler)
+	webhookAppaRouter := routers.NewWebhookAppaRouter(webhookHandler)
+	feedBoneRoutes := routers.NewFeedRoutes(config.StoreBone, feedBoneHandler, apiKeyService)
+	feedAppaRoutes := routers.NewFeedRoutes(config.StoreAppa, feedAppaHandler, apiKeyService)
+	backofficeRouter := routers.NewBackofficeRouter(backofficeHandler)
+
+	healthRouter.SetRouter(router)
+	metricsRouter.SetRouter(router)
+	adminRouter.SetRouter(router)
+	docsRouter.SetRouter(router)
+
+	// Set up routes with authentication middleware
+	r4BoneRoutes.SetRouter(router, authBoneMiddleware)
+	v1BoneRoutes.SetRouter(router, authBoneMiddleware)
+	webhookBoneRouter.SetRouter(router, authBoneMiddleware)
+	r4AppaRoutes.SetRouter(router, authAppaMiddleware)
+	v1AppaRoutes.SetRouter(router, authAppaMiddleware)
+	webhookAppaRouter.SetRouter(router, authAppaMiddleware)
+
+	// The live feeds are authenticated with the store's API keys, cashiers do not hold the R4 credentials
+	if !cfg.LiveFeed.Disabled {
+		feedBoneRoutes.SetRouter(router)
+		feedAppaRoutes.SetRouter(router)
+	}
+
+	// The support and finance staff sign in with their own back-office users
+	if !cfg.Backoffice.Disabled {
+		backofficeRouter.SetRouter(router)
+	}
+
+	// gRPC API for internal clients, on the same services
+	rpcServer := rpc.NewServer(
+		svc.R4,
+		paymentService,
+		apiKeyService,
+		limiter,
+		rpc.Watch{Interval: cfg.R4.DebitPollInterval, Timeout: cfg.GRPC.WatchTimeout},
+		logger,
+	)
+
+	// The outbox events go to the webhook subscriptions and the broker, then the
+	// queued webhooks are sent in the background
+	eventBroker, err := NewBroker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sinks := []events.Publisher{subscriptionService}
+	if eventBroker != nil {
+		sinks = append(sinks, outbox.BrokerSink(eventBroker))
+	}
+	relay := outbox.NewRelay(dbs.Primary, outbox.RelayConfig{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		PublishTimeout: cfg.Outbox.PublishTimeout,
+		InitialBackoff: cfg.Outbox.InitialBackoff,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}, logger, sinks...)
+	dispatcher := delivery.NewDispatcher(dbs.Primary, delivery.Config{
+		PollInterval:   cfg.OutboundWebhooks.PollInterval,
+		BatchSize:      cfg.OutboundWebhooks.BatchSize,
+		Timeout:        cfg.OutboundWebhooks.DeliveryTimeout,
+		MaxAttempts:    cfg.OutboundWebhooks.MaxAttempts,
+		InitialBackoff: cfg.OutboundWebhooks.InitialBackoff,
+		MaxBackoff:     cfg.OutboundWebhooks.MaxBackoff,
+	}, logger)
+
+	// Store credentials can be rotated without a restart, see internal/reload
+	reloader := reload.New(cfg, map[string]reload.Store{
+		config.StoreBone: {Client: r4BoneRestClient, Auth: authBoneMiddleware},
+		config.StoreAppa: {Client: r4AppaRestClient, Auth: authAppaMiddleware},
+	}, logger)
+
+	return &App{
+		Router:     router,
+		GRPC:       rpcServer.GRPCServer(readiness),
+		Broker:     eventBroker,
+		Readiness:  readiness,
+		Workers:    workers,
+		cfg:        cfg,
+		logger:     logger,
+		reloader:   reloader,
+		egress:     egressResolver,
+		dispatcher: dispatcher,
+		relay:      relay,
+		feed:       feedHub,
+	}, nil
+}
+
+// Start runs the background tasks that live until ctx is done: credential
+// reloading, the live feed, the outbox relay, event webhook delivery and, when
+// enabled, the egress IP check
+func (a *App) Start(ctx context.Context) {
+	a.reloader.Start(ctx)
+
+	if !a.cfg.LiveFeed.Disabled {
+		// The open feeds are closed when ctx is done, before the server drains
+		go a.feed.Run(ctx)
+	}
+
+	a.Workers.Go(func(context.Context) { a.relay.Run(ctx) })
+
+	if !a.cfg.OutboundWebhooks.Disabled {
+		// Shutdown waits for the deliveries in flight
+		a.Workers.Go(func(context.Context) { a.dispatcher.Run(ctx) })
+	}
+
+	// Egress IP discovery is a diagnostic, it must never keep the service from starting
+	if a.cfg.EgressIP.CheckOnStartup {
+		a.Workers.Go(func(ctx context.Context) {
+			if _, err := a.egress.Lookup(ctx); err != nil {
+				a.logger.Warn("could not look up the egress IP", zap.Error(err))
+			}
+		})
+	}
+}
+
+// Close releases the connections the workers used, once they are stopped
+func (a *App) Close() error {
+	if a.Broker == nil {
+		return nil
+	}
+	return a.Broker.Close()
+}
+
+// NewBroker connects to the message broker configured in cfg, nil when there is none
+func NewBroker(cfg *config.Config) (broker.Publisher, error) {
+	switch cfg.Broker.Kind {
+	case config.BrokerMemory:
+		return broker.NewMemory(), nil
+	case config.BrokerNATS:
+		return broker.NewNATS(cfg.Broker.URL, cfg.Broker.Topic)
+	case config.BrokerKafka:
+		return broker.NewKafka(cfg.Broker.Brokers, cfg.Broker.Topic), nil
+	case config.BrokerRabbitMQ:
+		return broker.NewRabbitMQ(cfg.Broker.URL, cfg.Broker.Topic), nil
+	default:
+		return nil, nil
+	}
+}
+
+// NewRateLimiter creates the limiter configured in cfg, nil when rate limiting is disabled
+func NewRateLimiter(cfg *config.Config, db *sql.DB) *ratelimit.Limiter {
+	if cfg.RateLimit.Disabled {
+		return nil
+	}
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == config.RateLimitPostgres {
+		store = ratelimit.NewPostgresStore(db)
+	}
+	return ratelimit.NewLimiter(store, cfg.RateLimit.Limits())
+}
+
+// NewEgressResolver creates the egress IP diagnostic configured in cfg
+func NewEgressResolver(cfg *config.Config) *ipfy.Resolver {
+	return ipfy.NewResolver(cfg.EgressIP.ProviderURL, cfg.EgressIP.Timeout, cfg.EgressIP.CacheTTL, cfg.EgressIP.RegisteredIPs)
+}
@@ -0,0 +1,255 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/r4sim"
+)
+
+const phone, dni = "04141234567", "V12345678"
+
+func otpRequest(amount float64) map[string]any {
+	return map[string]any{"bank": "0102", "amount": amount, "phone": phone, "dni": dni}
+}
+
+func debitRequest(amount float64, otp string) map[string]any {
+	req := otpRequest(amount)
+	req["name"] = "Maria Perez"
+	req["otp"] = otp
+	req["concept"] = "Orden 42"
+	return req
+}
+
+// debit generates an OTP through the service and uses it to request the debit
+func (e *env) debit(prefix, store string, sim *r4sim.Simulator, amount float64) (int, map[string]any) {
+	e.t.Helper()
+
+	status, body := e.do(http.MethodPost, prefix+"/generate-otp", store, otpRequest(amount))
+	if status != http.StatusOK {
+		e.t.Fatalf("generate-otp = %d %v", status, body)
+	}
+	otp, ok := sim.OTP(phone)
+	if !ok {
+		e.t.Fatal("the simulator did not issue an OTP")
+	}
+
+	return e.do(http.MethodPost, prefix+"/validate-immediate-debit", store, debitRequest(amount, otp))
+}
+
+func TestReadiness(t *testing.T) {
+	e := newEnv(t, nil)
+
+	status, body := e.do(http.MethodGet, "/readyz", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("readyz = %d %v", status, body)
+	}
+}
+
+func TestImmediateDebitAccepted(t *testing.T) {
+	e := newEnv(t, func(bone, _ *r4sim.Config, _ *config.Config) {
+		bone.DebitDelay = 30 * time.Millisecond
+	})
+
+	status, body := e.debit("/r4", config.StoreBone, e.bone, 150.5)
+	if status != http.StatusOK || body["code"] != r4sim.CodeAccepted || body["status"] != true || body["reference"] == "" {
+		t.Fatalf("validate-immediate-debit = %d %v, want an accepted debit", status, body)
+	}
+}
+
+func TestImmediateDebitStillPendingWhenPollingGivesUp(t *testing.T) {
+	e := newEnv(t, func(bone, _ *r4sim.Config, cfg *config.Config) {
+		bone.DebitDelay = time.Hour
+		cfg.R4.DebitPollAttempts = 2
+	})
+
+	status, body := e.debit("/r4", config.StoreBone, e.bone, 10)
+	if status != http.StatusOK || body["code"] != r4sim.CodePending || body["status"] != false {
+		t.Fatalf("validate-immediate-debit = %d %v, want a pending debit", status, body)
+	}
+	if calls := e.bone.Calls("ConsultarOperaciones"); calls != 2 {
+		t.Fatalf("ConsultarOperaciones called %d times, want 2", calls)
+	}
+}
+
+func TestImmediateDebitRejected(t *testing.T) {
+	t.Run("wrong OTP", func(t *testing.T) {
+		e := newEnv(t, nil)
+
+		status, body := e.do(http.MethodPost, "/r4/generate-otp", config.StoreBone, otpRequest(10))
+		if status != http.StatusOK {
+			t.Fatalf("generate-otp = %d %v", status, body)
+		}
+		status, body = e.do(http.MethodPost, "/r4/validate-immediate-debit", config.StoreBone, debitRequest(10, "00000000"))
+		if status != http.StatusOK || body["code"] != r4sim.CodeInvalidOTP || body["status"] != false {
+			t.Fatalf("validate-immediate-debit = %d %v, want %s", status, body, r4sim.CodeInvalidOTP)
+		}
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		e := newEnv(t, func(bone, _ *r4sim.Config, _ *config.Config) {
+			bone.Balance = 100
+		})
+
+		status, body := e.debit("/r4", config.StoreBone, e.bone, 250)
+		if status != http.StatusOK || body["code"] != r4sim.CodeInsufficientFunds {
+			t.Fatalf("validate-immediate-debit = %d %v, want %s", status, body, r4sim.CodeInsufficientFunds)
+		}
+	})
+}
+
+func TestImmediateDebitTimeout(t *testing.T) {
+	e := newEnv(t, func(_, _ *r4sim.Config, cfg *config.Config) {
+		cfg.R4.RequestTimeout = 200 * time.Millisecond
+	})
+	e.bone.Inject(r4sim.Fault{Endpoint: "DebitoInmediato", Delay: time.Second})
+
+	status, body := e.debit("/r4", config.StoreBone, e.bone, 10)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("validate-immediate-debit = %d %v, want 500 when R4 does not answer", status, body)
+	}
+}
+
+func TestR4ErrorBodiesStayOutOfResponses(t *testing.T) {
+	e := newEnv(t, nil)
+	e.bone.Inject(r4sim.Fault{Endpoint: "GenerarOtp", Status: http.StatusBadGateway})
+
+	status, body := e.do(http.MethodPost, "/v1/bone/generate-otp", config.StoreBone, otpRequest(10))
+	errBody, _ := body["error"].(map[string]any)
+	message, _ := errBody["message"].(string)
+	if status != http.StatusInternalServerError || !strings.Contains(message, "HTTP 502, code 502") {
+		t.Fatalf("generate-otp with R4 failing = %d %v, want its status and code", status, body)
+	}
+	if strings.Contains(message, "injected failure") {
+		t.Fatalf("error message %q carries the R4 response body", message)
+	}
+}
+
+func TestChangePayout(t *testing.T) {
+	e := newEnv(t, func(bone, _ *r4sim.Config, _ *config.Config) {
+		bone.Balance = 500
+	})
+	req := map[string]any{"bank": "0105", "amount": 35.75, "phone": phone, "dni": dni, "concept": "Vuelto"}
+
+	status, body := e.do(http.MethodPost, "/r4/change-paid", config.StoreBone, req)
+	if status != http.StatusOK || body["reference"] == "" {
+		t.Fatalf("change-paid = %d %v, want a reference", status, body)
+	}
+
+	req["amount"] = 900
+	if status, body = e.do(http.MethodPost, "/r4/change-paid", config.StoreBone, req); status != http.StatusInternalServerError {
+		t.Fatalf("change-paid above the balance = %d %v, want 500", status, body)
+	}
+}
+
+func TestWebhookAuthFailures(t *testing.T) {
+	e := newEnv(t, nil)
+	notifica := map[string]any{"Referencia": "12345678", "Monto": "10.00"}
+
+	status, body := e.do(http.MethodPost, "/R4notifica", "", notifica)
+	if status != http.StatusUnauthorized || body["abono"] != false {
+		t.Fatalf("unsigned R4notifica = %d %v, want 401", status, body)
+	}
+
+	// The appa signature is not valid on the bone routes
+	if status, _ = e.do(http.MethodPost, "/R4notifica", config.StoreAppa, notifica); status != http.StatusUnauthorized {
+		t.Fatalf("R4notifica signed for the other store = %d, want 401", status)
+	}
+	if status, _ = e.do(http.MethodPost, "/r4/generate-otp", "", otpRequest(10)); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned generate-otp = %d, want 401", status)
+	}
+	if n := e.count("r4_mobile_payments"); n != 0 {
+		t.Fatalf("%d payments registered from unauthenticated webhooks", n)
+	}
+}
+
+func TestV1Envelope(t *testing.T) {
+	e := newEnv(t, nil)
+
+	status, body := e.debit("/v1/appa", config.StoreAppa, e.appa, 20)
+	data, _ := body["data"].(map[string]any)
+	if status != http.StatusOK || data["code"] != r4sim.CodeAccepted || body["requestId"] == "" || body["error"] != nil {
+		t.Fatalf("validate-immediate-debit = %d %v, want the accepted debit in data", status, body)
+	}
+
+	status, body = e.do(http.MethodPost, "/v1/appa/generate-otp", config.StoreAppa, map[string]any{"bank": "0102"})
+	apiErr, _ := body["error"].(map[string]any)
+	if status != http.StatusBadRequest || apiErr["code"] != "invalid_payload" || apiErr["fields"] == nil {
+		t.Fatalf("invalid generate-otp = %d %v, want invalid_payload with the fields", status, body)
+	}
+
+	status, body = e.do(http.MethodGet, "/v1/appa/banks", config.StoreBone, nil)
+	apiErr, _ = body["error"].(map[string]any)
+	if status != http.StatusUnauthorized || apiErr["code"] != "unauthorized" {
+		t.Fatalf("banks signed for the other store = %d %v, want unauthorized", status, body)
+	}
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	e := newEnv(t, nil)
+
+	status, header, body := e.send(http.MethodGet, "/r4/appa/banks", config.StoreAppa, nil)
+	if status != http.StatusOK || body["banks"] == nil {
+		t.Fatalf("legacy banks = %d %v, want the bare body", status, body)
+	}
+	if header.Get("Deprecation") == "" || header.Get("Link") != `</v1/appa/banks>; rel="successor-version"` {
+		t.Fatalf("legacy banks headers = %v, want Deprecation and a Link to /v1/appa/banks", header)
+	}
+}
+
+func TestDuplicateNotificationIsRegisteredOnce(t *testing.T) {
+	e := newEnv(t, nil)
+	notifica := r4sim.Notifica{
+		IdComercio:       "J123456789",
+		TelefonoComercio: "04241112233",
+		TelefonoEmisor:   phone,
+		Monto:            "120.00",
+		Referencia:       "00001234",
+	}
+
+	for i := 0; i < 2; i++ {
+		status, err := e.bone.SendNotifica(context.Background(), e.server.URL, notifica)
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("R4notifica #%d = %d, %v", i+1, status, err)
+		}
+	}
+
+	if n := e.count("r4_mobile_payments"); n != 1 {
+		t.Fatalf("%d payments registered, want 1", n)
+	}
+}
+
+func TestStoresAreIsolated(t *testing.T) {
+	e := newEnv(t, nil)
+
+	status, body := e.debit("/r4/appa", config.StoreAppa, e.appa, 20)
+	if status != http.StatusOK || body["code"] != r4sim.CodeAccepted {
+		t.Fatalf("appa validate-immediate-debit = %d %v", status, body)
+	}
+	if calls := e.bone.Calls("GenerarOtp"); calls != 0 {
+		t.Fatalf("appa OTP went to the bone commerce %d times", calls)
+	}
+
+	ctx := context.Background()
+	status, err := e.appa.SendNotifica(ctx, e.server.URL+"/appa", r4sim.Notifica{Monto: "20.00", TelefonoEmisor: phone})
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("appa R4notifica = %d, %v", status, err)
+	}
+	status, err = e.appa.SendConsulta(ctx, e.server.URL+"/appa", r4sim.Consulta{Monto: "20.00"})
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("appa R4consulta = %d, %v", status, err)
+	}
+
+	if n := e.count("r4_appa_mobile_payments"); n != 1 {
+		t.Fatalf("%d appa payments registered, want 1", n)
+	}
+	if n := e.count("r4_mobile_payments"); n != 0 {
+		t.Fatalf("%d bone payments registered from an appa webhook", n)
+	}
+	// R4consulta is registered in the background
+	eventually(t, func() bool { return e.count("r4_appa_mobile_payments_previews") == 1 })
+}
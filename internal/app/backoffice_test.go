@@ -0,0 +1,234 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/app"
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/r4sim"
+)
+
+var csrfField = regexp.MustCompile(`name="csrf_token" value="([0-9a-f]+)"`)
+
+// staff is a back-office user browsing with its own cookies
+type staff struct {
+	e      *env
+	client *http.Client
+	csrf   string
+}
+
+// signIn creates a back-office user with role and signs it in
+func (e *env) signIn(email, role string) *staff {
+	e.t.Helper()
+
+	svc, err := app.NewServices(e.cfg, app.Databases{Primary: testDB}, zap.NewNop())
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	const password = "correct horse battery"
+	if _, err := svc.Users.Create(context.Background(), email, "Staff", role, password); err != nil {
+		e.t.Fatalf("creating %s: %v", email, err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	s := &staff{e: e, client: &http.Client{Jar: jar}}
+	status, page := s.post("/backoffice/login", url.Values{"email": {email}, "password": {password}})
+	if status != http.StatusOK || !strings.Contains(page, "Pagos recibidos") {
+		e.t.Fatalf("login as %s = %d, want the payments page", email, status)
+	}
+	s.csrf = csrfField.FindStringSubmatch(page)[1]
+	return s
+}
+
+func (s *staff) get(path string) (int, string) {
+	s.e.t.Helper()
+	resp, err := s.client.Get(s.e.server.URL + path)
+	if err != nil {
+		s.e.t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+// post submits a form, following the redirect to the page showing the outcome
+func (s *staff) post(path string, form url.Values) (int, string) {
+	s.e.t.Helper()
+	if s.csrf != "" && !form.Has("csrf_token") {
+		form.Set("csrf_token", s.csrf)
+	}
+	resp, err := s.client.PostForm(s.e.server.URL+path, form)
+	if err != nil {
+		s.e.t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestBackofficeRequiresLogin(t *testing.T) {
+	e := newEnv(t, nil)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	resp, err := client.Get(e.server.URL + "/backoffice/payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/backoffice/login" {
+		t.Fatalf("GET /backoffice/payments = %d to %q, want the login page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	support := e.signIn("soporte@example.com", "support")
+	if status, _ := support.post("/backoffice/logout", url.Values{"csrf_token": {"forged"}}); status != http.StatusForbidden {
+		t.Fatalf("POST without the session's CSRF token = %d, want 403", status)
+	}
+}
+
+func TestBackofficeOperationsAndPayments(t *testing.T) {
+	e := newEnv(t, nil)
+	support := e.signIn("soporte@example.com", "support")
+
+	status, debit := e.debit("/r4", config.StoreBone, e.bone, 75)
+	if status != http.StatusOK || debit["code"] != r4sim.CodeAccepted {
+		t.Fatalf("validate-immediate-debit = %d %v", status, debit)
+	}
+	operationID := debit["id"].(string)
+
+	if _, page := support.get("/backoffice/operations?store=bone"); !strings.Contains(page, operationID) {
+		t.Fatal("the debit is not listed in the back office")
+	}
+	status, page := support.post("/backoffice/operations/bone/"+operationID+"/refresh", url.Values{})
+	if status != http.StatusOK || !strings.Contains(page, "Estado consultado en R4") || !strings.Contains(page, r4sim.CodeAccepted) {
+		t.Fatalf("refreshing the debit = %d, want its history with %s", status, r4sim.CodeAccepted)
+	}
+
+	_, err := e.bone.SendNotifica(context.Background(), e.server.URL, r4sim.Notifica{Monto: "75.00", TelefonoEmisor: "+58 414 1234567", Referencia: "00009876"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, page = support.post("/backoffice/payments/bone/1/order", url.Values{"order_id": {"42"}, "back": {"store=bone"}})
+	if status != http.StatusOK || !strings.Contains(page, "Pago vinculado al pedido") {
+		t.Fatalf("linking the payment = %d, want it linked", status)
+	}
+	var orderID int
+	testDB.Raw("SELECT order_id FROM r4_mobile_payments WHERE id = 1").Scan(&orderID)
+	if orderID != 42 {
+		t.Fatalf("payment linked to order %d, want 42", orderID)
+	}
+	if _, page = support.post("/backoffice/payments/bone/1/order", url.Values{"order_id": {"43"}}); !strings.Contains(page, "ya está vinculado") {
+		t.Fatal("a linked payment was moved to another order")
+	}
+
+	status, csv := support.get("/backoffice/payments/export?store=bone")
+	if status != http.StatusOK || !strings.Contains(csv, "00009876") || !strings.Contains(csv, ",42,") {
+		t.Fatalf("export = %d %q, want the linked payment", status, csv)
+	}
+	// Phones are compared normalized, however R4 or the user wrote them
+	filter := url.Values{"store": {"bone"}, "sender_phone": {"0414-123.45.67"}}
+	if _, csv := support.get("/backoffice/payments/export?" + filter.Encode()); !strings.Contains(csv, "00009876") {
+		t.Fatalf("export filtered by sender phone = %q, want the payment", csv)
+	}
+}
+
+func TestBackofficeApprovesHeldPayouts(t *testing.T) {
+	e := newEnv(t, func(bone, _ *r4sim.Config, cfg *config.Config) {
+		bone.Balance = 1000
+		cfg.Backoffice.ChangeApprovalAbove = 100
+	})
+	req := map[string]any{"bank": "0105", "amount": 150, "phone": phone, "dni": dni, "concept": "Vuelto"}
+
+	status, body := e.do(http.MethodPost, "/v1/bone/change-paid", config.StoreBone, req)
+	data, _ := body["data"].(map[string]any)
+	if status != http.StatusAccepted || data["status"] != "pending" || data["payoutId"] != float64(1) {
+		t.Fatalf("change-paid above the threshold = %d %v, want it held", status, body)
+	}
+	if calls := e.bone.Calls("MBvuelto"); calls != 0 {
+		t.Fatalf("a held payout was paid %d times", calls)
+	}
+
+	support := e.signIn("soporte@example.com", "support")
+	if status, _ := support.post("/backoffice/payouts/bone/1/approve", url.Values{}); status != http.StatusForbidden {
+		t.Fatalf("support approving = %d, want 403", status)
+	}
+
+	finance := e.signIn("finanzas@example.com", "finance")
+	status, page := finance.post("/backoffice/payouts/bone/1/approve", url.Values{})
+	if status != http.StatusOK || !strings.Contains(page, "Vuelto aprobado y pagado") {
+		t.Fatalf("finance approving = %d, want it paid", status)
+	}
+	if _, page = finance.post("/backoffice/payouts/bone/1/approve", url.Values{}); !strings.Contains(page, "ya fue decidido") {
+		t.Fatal("a payout was approved twice")
+	}
+	if calls := e.bone.Calls("MBvuelto"); calls != 1 {
+		t.Fatalf("MBvuelto called %d times, want 1", calls)
+	}
+
+	var payout struct{ Status, Reference, DecidedBy string }
+	testDB.Raw("SELECT status, reference, decided_by FROM change_payouts WHERE id = 1").Scan(&payout)
+	if payout.Status != "paid" || payout.Reference == "" || payout.DecidedBy != "finanzas@example.com" {
+		t.Fatalf("payout = %+v, want it paid by finance", payout)
+	}
+}
+
+func TestBackofficeResolvesStuckPayouts(t *testing.T) {
+	e := newEnv(t, nil)
+	finance := e.signIn("finanzas@example.com", "finance")
+	err := testDB.Exec(`INSERT INTO change_payouts (store, bank, amount, phone, dni, concept, status, decided_by, decided_at)
+		VALUES ('bone', '0105', 150, ?, ?, 'Vuelto', 'paying', 'finanzas@example.com', ?),
+		       ('bone', '0105', 200, ?, ?, 'Vuelto', 'paying', 'finanzas@example.com', ?)`, phone, dni, time.Now(), phone, dni, time.Now()).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paid := url.Values{"outcome": {"paid"}, "reference": {"00012345"}}
+	if _, page := finance.post("/backoffice/payouts/bone/1/resolve", paid); !strings.Contains(page, "más de 10 minutos") {
+		t.Fatal("a payout still being approved was resolved")
+	}
+	testDB.Exec("UPDATE change_payouts SET decided_at = ?", time.Now().Add(-services.PayoutStuckAfter-time.Minute))
+
+	if _, page := finance.post("/backoffice/payouts/bone/1/resolve", url.Values{"outcome": {"paid"}}); !strings.Contains(page, "Indica la referencia") {
+		t.Fatal("a payout was marked paid without its reference")
+	}
+	if status, page := finance.post("/backoffice/payouts/bone/1/resolve", paid); status != http.StatusOK || !strings.Contains(page, "Vuelto resuelto") {
+		t.Fatalf("resolving as paid = %d, want it resolved", status)
+	}
+	if _, page := finance.post("/backoffice/payouts/bone/2/resolve", url.Values{"outcome": {"failed"}}); !strings.Contains(page, "Vuelto resuelto") {
+		t.Fatal("resolving as failed did not resolve")
+	}
+	if _, page := finance.post("/backoffice/payouts/bone/1/resolve", url.Values{"outcome": {"failed"}}); !strings.Contains(page, "más de 10 minutos") {
+		t.Fatal("a resolved payout was resolved again")
+	}
+
+	var payouts []struct{ Status, Reference string }
+	testDB.Raw("SELECT status, reference FROM change_payouts ORDER BY id").Scan(&payouts)
+	if len(payouts) != 2 || payouts[0].Status != "paid" || payouts[0].Reference != "00012345" || payouts[1].Status != "failed" {
+		t.Fatalf("payouts = %+v, want the first paid and the second failed", payouts)
+	}
+}
+
+func TestBackofficeUsersAreUnique(t *testing.T) {
+	e := newEnv(t, nil)
+	e.signIn("soporte@example.com", "support")
+
+	svc, err := app.NewServices(e.cfg, app.Databases{Primary: testDB}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Users.Create(context.Background(), "soporte@example.com", "Otra", "finance", "another long password")
+	if !errors.Is(err, services.ErrUserExists) {
+		t.Fatalf("creating the same user twice = %v, want ErrUserExists", err)
+	}
+}
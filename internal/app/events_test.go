@@ -0,0 +1,216 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/broker"
+	"bone_appetit_r4_service/pkg/r4sim"
+	"bone_appetit_r4_service/pkg/webhooks"
+)
+
+// subscriber is a downstream endpoint failing its first failures requests
+type subscriber struct {
+	mu       sync.Mutex
+	failures int
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (s *subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, r)
+	s.bodies = append(s.bodies, body)
+	if len(s.received) <= s.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func (s *subscriber) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.received)
+}
+
+// waitOutboxRelayed waits for the relay to publish every outbox event
+func (e *env) waitOutboxRelayed() {
+	e.t.Helper()
+	eventually(e.t, func() bool {
+		var unpublished int64
+		_ = testDB.Raw("SELECT COUNT(*) FROM outbox WHERE published_at IS NULL").Scan(&unpublished).Error
+		return unpublished == 0
+	})
+}
+
+func TestEventWebhooks(t *testing.T) {
+	e := newEnv(t, func(_, _ *r4sim.Config, cfg *config.Config) {
+		cfg.AdminToken = adminToken
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	e.service.Start(ctx)
+
+	sub := &subscriber{failures: 1}
+	endpoint := httptest.NewServer(sub)
+	t.Cleanup(endpoint.Close)
+
+	var created models.CreatedSubscription
+	status := e.admin(http.MethodPost, "/admin/subscriptions", models.CreateSubscriptionRequest{
+		Store:  config.StoreBone,
+		URL:    endpoint.URL,
+		Events: []string{events.PaymentReceived},
+	}, &created)
+	if status != http.StatusCreated || created.Secret == "" {
+		t.Fatalf("create subscription = %d %+v", status, created)
+	}
+
+	notifica := r4sim.Notifica{TelefonoEmisor: phone, Monto: "75.00", Referencia: "00004321"}
+	if status, err := e.bone.SendNotifica(ctx, e.server.URL, notifica); err != nil || status != http.StatusOK {
+		t.Fatalf("R4notifica = %d, %v", status, err)
+	}
+	// Other stores and event types are not sent
+	if status, _ := e.debit("/r4", config.StoreBone, e.bone, 10); status != http.StatusOK {
+		t.Fatalf("validate-immediate-debit = %d", status)
+	}
+	if status, err := e.appa.SendNotifica(ctx, e.server.URL+"/appa", notifica); err != nil || status != http.StatusOK {
+		t.Fatalf("appa R4notifica = %d, %v", status, err)
+	}
+
+	// The first attempt fails and is retried
+	eventually(t, func() bool { return sub.count() == 2 })
+	req, body := sub.received[1], sub.bodies[1]
+	if err := webhooks.Verify(created.Secret, req.Header.Get(webhooks.SignatureHeader), body, time.Minute); err != nil {
+		t.Fatalf("signature: %v", err)
+	}
+	var event struct {
+		events.Event
+		Data events.Payment `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != events.PaymentReceived || event.Store != config.StoreBone || event.Data.Reference != "00004321" || event.Data.Amount != 75 {
+		t.Fatalf("event = %s, want the bone payment", body)
+	}
+	if req.Header.Get(webhooks.EventIDHeader) != event.ID || req.Header.Get(webhooks.EventIDHeader) != sub.received[0].Header.Get(webhooks.EventIDHeader) {
+		t.Fatal("the retry does not carry the same event ID")
+	}
+
+	var deliveries models.DeliveryListResponse
+	path := fmt.Sprintf("/admin/subscriptions/%d/deliveries", created.ID)
+	eventually(t, func() bool {
+		e.admin(http.MethodGet, path, nil, &deliveries)
+		return len(deliveries.Deliveries) == 1 && deliveries.Deliveries[0].Status == "delivered"
+	})
+	var detail models.DeliveryDetail
+	if status := e.admin(http.MethodGet, fmt.Sprintf("/admin/deliveries/%d", deliveries.Deliveries[0].ID), nil, &detail); status != http.StatusOK {
+		t.Fatalf("get delivery = %d", status)
+	}
+	if detail.Attempts != 2 || len(detail.Log) != 2 || *detail.Log[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("delivery = %+v, want a failed then a successful attempt", detail)
+	}
+
+	// A retry sends a delivered event again
+	if status := e.admin(http.MethodPost, fmt.Sprintf("/admin/deliveries/%d/retry", detail.ID), nil, nil); status != http.StatusAccepted {
+		t.Fatalf("retry = %d", status)
+	}
+	eventually(t, func() bool { return sub.count() == 3 })
+
+	// Nothing is queued for a disabled subscription
+	if status := e.admin(http.MethodPost, fmt.Sprintf("/admin/subscriptions/%d/disable", created.ID), nil, nil); status != http.StatusNoContent {
+		t.Fatalf("disable = %d", status)
+	}
+	notifica.Referencia = "00004322"
+	if status, err := e.bone.SendNotifica(ctx, e.server.URL, notifica); err != nil || status != http.StatusOK {
+		t.Fatalf("R4notifica = %d, %v", status, err)
+	}
+	e.waitOutboxRelayed()
+	if n := e.count("event_deliveries"); n != 1 {
+		t.Fatalf("%d deliveries queued, want 1", n)
+	}
+}
+
+func TestOutboxRelaysToTheBroker(t *testing.T) {
+	e := newEnv(t, nil)
+	memory := e.service.Broker.(*broker.Memory)
+	memory.Fail(errors.New("broker unavailable"))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	e.service.Start(ctx)
+
+	notifica := r4sim.Notifica{TelefonoEmisor: phone, Monto: "12.50", Referencia: "00007777"}
+	for i := 0; i < 2; i++ {
+		if status, err := e.appa.SendNotifica(ctx, e.server.URL+"/appa", notifica); err != nil || status != http.StatusOK {
+			t.Fatalf("R4notifica #%d = %d, %v", i+1, status, err)
+		}
+	}
+	// The duplicate notification stores no payment, so no event either
+	if n := e.count("outbox"); n != 1 {
+		t.Fatalf("%d outbox events, want 1", n)
+	}
+
+	// The event waits in the outbox while the broker fails
+	var attempts int
+	eventually(t, func() bool {
+		_ = testDB.Raw("SELECT attempts FROM outbox").Scan(&attempts).Error
+		return attempts >= 2
+	})
+	if len(memory.Messages()) != 0 {
+		t.Fatal("an event was published while the broker failed")
+	}
+
+	memory.Fail(nil)
+	eventually(t, func() bool { return len(memory.Messages()) == 1 })
+	msg := memory.Messages()[0]
+	var eventID string
+	_ = testDB.Raw("SELECT event_id FROM outbox").Scan(&eventID).Error
+	if msg.ID != eventID || msg.Key != "appa.payment.received" {
+		t.Fatalf("message = %+v, want the outbox event %s", msg, eventID)
+	}
+	e.waitOutboxRelayed()
+}
+
+func TestDebitEventIsEmittedOnceWhenTheDebitEnds(t *testing.T) {
+	e := newEnv(t, func(bone, _ *r4sim.Config, cfg *config.Config) {
+		bone.DebitDelay = 200 * time.Millisecond
+		cfg.R4.DebitPollAttempts = 1
+	})
+	debitEvents := func() (types []string) {
+		testDB.Raw("SELECT event_type FROM outbox WHERE event_type LIKE 'debit.%' ORDER BY id").Scan(&types)
+		return types
+	}
+
+	status, body := e.debit("/r4", config.StoreBone, e.bone, 10)
+	id, _ := body["id"].(string)
+	if status != http.StatusOK || body["code"] != r4sim.CodePending || id == "" {
+		t.Fatalf("validate-immediate-debit = %d %v, want a pending debit", status, body)
+	}
+	if types := debitEvents(); len(types) != 0 {
+		t.Fatalf("events %v for a pending debit", types)
+	}
+
+	// Seen accepted later, twice, by the order service polling it
+	time.Sleep(200 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if status, body := e.do(http.MethodGet, "/r4/get-operation/"+id, config.StoreBone, nil); status != http.StatusOK || body["code"] != r4sim.CodeAccepted {
+			t.Fatalf("get-operation = %d %v, want it accepted", status, body)
+		}
+	}
+	if types := debitEvents(); len(types) != 1 || types[0] != events.DebitAccepted {
+		t.Fatalf("events = %v, want a single debit.accepted", types)
+	}
+}
@@ -0,0 +1,139 @@
+package app_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/r4sim"
+)
+
+// sseEvent is one server-sent event read from a feed
+type sseEvent struct {
+	id, name string
+	data     []byte
+}
+
+// openFeed connects to a live feed, the stream is closed with the test
+func (e *env) openFeed(path, key, lastEventID string) (*http.Response, <-chan sseEvent) {
+	e.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, e.server.URL+path, nil)
+	req.Header.Set("X-API-Key", key)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatalf("GET %s: %v", path, err)
+	}
+	e.t.Cleanup(func() { resp.Body.Close() })
+
+	received := make(chan sseEvent, 16)
+	go func() {
+		defer close(received)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				event.id = value
+			case "event":
+				event.name = value
+			case "data":
+				event.data = []byte(value)
+			case "":
+				if event.id != "" {
+					received <- event
+				}
+				event = sseEvent{}
+			}
+		}
+	}()
+	return resp, received
+}
+
+func next(t *testing.T, received <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-received:
+		if !ok {
+			t.Fatal("the feed was closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received in time")
+	}
+	return sseEvent{}
+}
+
+func TestLiveFeed(t *testing.T) {
+	e := newEnv(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	e.service.Start(ctx)
+
+	var key, appaKey models.CreatedAPIKey
+	e.admin(http.MethodPost, "/admin/api-keys", map[string]string{"name": "caja-1", "store": config.StoreBone}, &key)
+	e.admin(http.MethodPost, "/admin/api-keys", map[string]string{"name": "caja-appa", "store": config.StoreAppa}, &appaKey)
+
+	// Only the store's own keys open its feed
+	if resp, _ := e.openFeed("/v1/bone/feed", appaKey.Key, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("feed with another store's key = %d", resp.StatusCode)
+	}
+	resp, store := e.openFeed("/v1/bone/feed", key.Key, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("feed = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	_, branch := e.openFeed("/v1/bone/feed?branch=0412-000.00.02", key.Key, "")
+	// Let the feeds subscribe before the payments arrive
+	time.Sleep(100 * time.Millisecond)
+
+	for i, notifica := range []r4sim.Notifica{
+		{TelefonoEmisor: phone, TelefonoComercio: "04120000001", Monto: "20.00", Referencia: "00005551"},
+		{TelefonoEmisor: phone, TelefonoComercio: "04120000002", Monto: "30.00", Referencia: "00005552"},
+	} {
+		if status, err := e.bone.SendNotifica(ctx, e.server.URL, notifica); err != nil || status != http.StatusOK {
+			t.Fatalf("R4notifica #%d = %d, %v", i+1, status, err)
+		}
+	}
+	// Other stores are not sent
+	if status, err := e.appa.SendNotifica(ctx, e.server.URL+"/appa", r4sim.Notifica{TelefonoEmisor: phone, Monto: "1.00", Referencia: "00005553"}); err != nil || status != http.StatusOK {
+		t.Fatalf("appa R4notifica = %d, %v", status, err)
+	}
+
+	first, second := next(t, store), next(t, store)
+	var payment struct {
+		events.Event
+		Data events.Payment `json:"data"`
+	}
+	if err := json.Unmarshal(second.data, &payment); err != nil {
+		t.Fatal(err)
+	}
+	if first.name != events.PaymentReceived || payment.Data.Reference != "00005552" || payment.Store != config.StoreBone {
+		t.Fatalf("events = %s, %s; want both bone payments", first.data, second.data)
+	}
+	if got := next(t, branch); got.id != second.id {
+		t.Fatalf("branch feed got event %s, want only the payment to its phone (%s)", got.id, second.id)
+	}
+
+	// Resuming after the first payment replays the second one, after sending again the
+	// recent ones the client already has in case one committed late
+	_, resumed := e.openFeed("/v1/bone/feed", key.Key, first.id)
+	if got := next(t, resumed); got.id != first.id {
+		t.Fatalf("resumed feed replayed %s first, want the recent %s again", got.id, first.id)
+	}
+	if got := next(t, resumed); got.id != second.id {
+		t.Fatalf("resumed feed replayed %s, want %s", got.id, second.id)
+	}
+}
@@ -0,0 +1,110 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/rpc"
+	r4v1 "bone_appetit_r4_service/pkg/pb/r4/v1"
+	"bone_appetit_r4_service/pkg/r4sim"
+)
+
+const adminToken = "admin-token"
+
+// admin calls an /admin route with the admin bearer token
+func (e *env) admin(method, path string, body, out any) int {
+	e.t.Helper()
+
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, e.server.URL+path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		_ = json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// grpcConn connects to the gRPC API of the service over an in-memory listener
+func (e *env) grpcConn() *grpc.ClientConn {
+	e.t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = e.service.GRPC.Serve(listener) }()
+	e.t.Cleanup(e.service.GRPC.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestGRPCWithAPIKey(t *testing.T) {
+	e := newEnv(t, func(_, _ *r4sim.Config, cfg *config.Config) {
+		cfg.AdminToken = adminToken
+	})
+
+	var key models.CreatedAPIKey
+	if code := e.admin(http.MethodPost, "/admin/api-keys", map[string]string{"name": "order-service", "store": config.StoreAppa}, &key); code != http.StatusCreated || key.Key == "" {
+		t.Fatalf("create API key = %d %+v", code, key)
+	}
+
+	client := r4v1.NewR4ServiceClient(e.grpcConn())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), rpc.APIKeyMetadata, key.Key)
+
+	if _, err := client.GenerateOTP(ctx, &r4v1.GenerateOTPRequest{Bank: "0102", Amount: 15, Phone: phone, Dni: dni}); err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	// The key belongs to appa, so the OTP comes from the appa commerce
+	otp, ok := e.appa.OTP(phone)
+	if !ok {
+		t.Fatal("the appa simulator did not issue an OTP")
+	}
+
+	debit, err := client.ValidateImmediateDebit(ctx, &r4v1.ValidateImmediateDebitRequest{
+		Bank: "0102", Amount: 15, Phone: phone, Dni: dni, Name: "Maria Perez", Otp: otp,
+	})
+	if err != nil || !debit.Accepted {
+		t.Fatalf("ValidateImmediateDebit = %v, %v, want an accepted debit", debit, err)
+	}
+
+	stream, err := client.WatchOperation(ctx, &r4v1.WatchOperationRequest{Id: debit.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.Operation.Code != r4sim.CodeAccepted {
+		t.Fatalf("WatchOperation = %v, %v, want the accepted state", resp, err)
+	}
+
+	if code := e.admin(http.MethodDelete, fmt.Sprintf("/admin/api-keys/%d", key.ID), nil, nil); code != http.StatusNoContent {
+		t.Fatalf("revoke API key = %d", code)
+	}
+	if _, err := client.GetBCVRate(ctx, &r4v1.GetBCVRateRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("call with a revoked key = %v, want Unauthenticated", err)
+	}
+}
@@ -0,0 +1,83 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/r4sim"
+	"bone_appetit_r4_service/pkg/ratelimit"
+)
+
+func TestOTPsAreRateLimitedPerPhone(t *testing.T) {
+	e := newEnv(t, func(_, _ *r4sim.Config, cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{
+			Store:        config.RateLimitPostgres,
+			OTPPerTarget: ratelimit.Limit{Requests: 2, Per: time.Hour},
+		}
+	})
+
+	for _, path := range []string{"/r4/generate-otp", "/v1/bone/generate-otp"} {
+		if status, body := e.do(http.MethodPost, path, config.StoreBone, otpRequest(10)); status != http.StatusOK {
+			t.Fatalf("%s = %d %v", path, status, body)
+		}
+	}
+
+	// The bucket of the phone is shared by both stores and API versions
+	status, header, body := e.send(http.MethodPost, "/v1/appa/generate-otp", config.StoreAppa, otpRequest(10))
+	errBody, _ := body["error"].(map[string]any)
+	if status != http.StatusTooManyRequests || errBody["code"] != "rate_limited" {
+		t.Fatalf("third OTP to the phone = %d %v, want 429", status, body)
+	}
+	if retryAfter, _ := strconv.Atoi(header.Get("Retry-After")); retryAfter < 1700 || retryAfter > 1800 {
+		t.Fatalf("Retry-After = %q, want about half an hour", header.Get("Retry-After"))
+	}
+	if calls := e.bone.Calls("GenerarOtp") + e.appa.Calls("GenerarOtp"); calls != 2 {
+		t.Fatalf("GenerarOtp called %d times, want 2", calls)
+	}
+
+	other := otpRequest(10)
+	other["phone"], other["dni"] = "04241234567", "V87654321"
+	if status, body := e.do(http.MethodPost, "/r4/generate-otp", config.StoreBone, other); status != http.StatusOK {
+		t.Fatalf("OTP to another customer = %d %v", status, body)
+	}
+}
+
+func TestFailedWebhooksAreRateLimitedPerIP(t *testing.T) {
+	e := newEnv(t, func(_, _ *r4sim.Config, cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{
+			Store:        config.RateLimitMemory,
+			WebhookPerIP: ratelimit.Limit{Requests: 1, Per: time.Minute},
+		}
+	})
+	ctx := context.Background()
+	forged := func() int {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, e.server.URL+"/R4notifica", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "forged")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := forged(); status != http.StatusUnauthorized {
+		t.Fatalf("first forged webhook = %d, want 401", status)
+	}
+	if status := forged(); status != http.StatusTooManyRequests {
+		t.Fatalf("second forged webhook = %d, want 429", status)
+	}
+
+	// R4 shares the IP of the forged ones and is still let in
+	for i := 0; i < 3; i++ {
+		status, err := e.bone.SendConsulta(ctx, e.server.URL, r4sim.Consulta{Monto: "20.00"})
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("signed webhook %d = %d, %v", i+1, status, err)
+		}
+	}
+}
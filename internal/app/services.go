@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/outbox"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/banks"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// Services are the business services behind the HTTP and gRPC APIs, also used by the
+// support commands so they behave exactly like the server
+type Services struct {
+	// R4 holds the R4 service of each store, keyed by store name
+	R4            map[string]services.R4Service
+	Payments      services.PaymentService
+	Webhooks      services.WebhookService
+	APIKeys       services.APIKeyService
+	Subscriptions services.SubscriptionService
+	Operations    services.OperationService
+	Payouts       services.ChangePayoutService
+	Users         services.BackofficeUserService
+
+	clients map[string]*r4bank.RestClient
+	loc     *time.Location
+}
+
+// NewServices wires the services from cfg, loading the time zone and bank catalog they use
+func NewServices(cfg *config.Config, dbs Databases, logger *zap.Logger) (*Services, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("loading time zone %s: %w", cfg.Timezone, err)
+	}
+
+	if cfg.BankCatalogFile != "" {
+		catalog, err := banks.LoadFile(cfg.BankCatalogFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading bank catalog %s: %w", cfg.BankCatalogFile, err)
+		}
+		banks.SetDefault(catalog)
+	}
+	logger.Info("bank catalog loaded", zap.String("version", banks.Default().Version))
+
+	readDB := dbs.Primary
+	if dbs.Replica != nil {
+		readDB = dbs.Replica
+	}
+
+	debitPolling := services.DebitPolling{Attempts: cfg.R4.DebitPollAttempts, Interval: cfg.R4.DebitPollInterval}
+	eventOutbox := outbox.New(dbs.Primary)
+	s := &Services{
+		R4:            make(map[string]services.R4Service, len(config.StoreNames)),
+		Payments:      services.NewPaymentService(dbs.Primary, readDB, loc, banks.Default()),
+		Webhooks:      services.NewWebhookService(dbs.Primary, loc),
+		APIKeys:       services.NewAPIKeyService(dbs.Primary),
+		Subscriptions: services.NewSubscriptionService(dbs.Primary),
+		Operations:    services.NewOperationService(dbs.Primary),
+		Payouts:       services.NewChangePayoutService(dbs.Primary, cfg.Backoffice.ChangeApprovalAbove),
+		Users:         services.NewBackofficeUserService(dbs.Primary, cfg.Backoffice.SessionTTL),
+		clients:       make(map[string]*r4bank.RestClient, len(config.StoreNames)),
+		loc:           loc,
+	}
+	for _, name := range config.StoreNames {
+		store := cfg.Store(name)
+		client := r4bank.NewClient(name, store.EntryPoint, store.CommerceToken, cfg.R4.RequestTimeout, logger)
+		s.clients[name] = client
+		s.R4[name] = services.NewR4Service(name, client, debitPolling, eventOutbox, s.Operations, s.Payouts)
+	}
+	return s, nil
+}
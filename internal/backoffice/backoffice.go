@@ -0,0 +1,85 @@
+// Package backoffice embeds the pages of the back office, the UI the support and
+// finance staff use to follow payments, debits and change payouts
+package backoffice
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strconv"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+//go:embed static
+var staticFiles embed.FS
+
+// pageNames lists the pages, each one rendered inside templates/layout.html
+var pageNames = []string{"login", "payments", "operations", "operation", "payouts"}
+
+// payoutStatuses names the change payout statuses on the pages
+var payoutStatuses = map[string]string{
+	"pending":  "Pendiente",
+	"paying":   "Pagando",
+	"paid":     "Pagado",
+	"failed":   "Falló",
+	"rejected": "Rechazado",
+}
+
+// Pages renders the back-office pages
+type Pages struct {
+	pages map[string]*template.Template
+}
+
+// Parse parses the embedded pages, showing times in loc
+func Parse(loc *time.Location) (*Pages, error) {
+	funcs := template.FuncMap{
+		"amount": func(amount float64) string {
+			return strconv.FormatFloat(amount, 'f', 2, 64)
+		},
+		"datetime": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.In(loc).Format("2006-01-02 15:04:05")
+		},
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02")
+		},
+		"payoutStatus": func(status string) string {
+			if name, ok := payoutStatuses[status]; ok {
+				return name
+			}
+			return status
+		},
+	}
+
+	p := &Pages{pages: make(map[string]*template.Template, len(pageNames))}
+	for _, name := range pageNames {
+		page, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFiles, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parsing back-office page %s: %w", name, err)
+		}
+		p.pages[name] = page
+	}
+	return p, nil
+}
+
+// Render writes the page name filled with data
+func (p *Pages) Render(w io.Writer, name string, data any) error {
+	page, ok := p.pages[name]
+	if !ok {
+		return fmt.Errorf("unknown back-office page %s", name)
+	}
+	return page.Execute(w, data)
+}
+
+// Static returns the stylesheet and other assets of the pages
+func Static() fs.FS {
+	static, _ := fs.Sub(staticFiles, "static")
+	return static
+}
@@ -1,97 +1,760 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"bone_appetit_r4_service/pkg/ratelimit"
 )
 
+// Store names, each one has its own R4 commerce and routes
+const (
+	StoreBone = "bone"
+	StoreAppa = "appa"
+)
+
+// StoreNames lists the stores the service is wired for
+var StoreNames = []string{StoreBone, StoreAppa}
+
 // Config holds the application configuration
 type Config struct {
-	Port string
+	Port     string `yaml:"port"`
+	Timezone string `yaml:"timezone"`
+
+	// ShutdownTimeout bounds the whole shutdown from the signal: the delay, draining
+	// requests and workers and flushing traces. Keep it below the platform's grace period.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownDelay is how long the instance reports not-ready before draining, part of
+	// ShutdownTimeout
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// HealthCheckTimeout bounds each readiness check
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout"`
+	// SecretRotationOverlap is how long the previous store secret is still accepted after a reload
+	SecretRotationOverlap time.Duration `yaml:"secret_rotation_overlap"`
+
+	Database DatabaseConfig `yaml:"database"`
+	R4       R4Config       `yaml:"r4"`
+
+	// Stores holds the R4 credentials of each store, keyed by store name
+	Stores map[string]*StoreConfig `yaml:"stores"`
+
+	// BankCatalogFile optionally replaces the embedded bank catalog
+	BankCatalogFile string `yaml:"bank_catalog_file"`
 
-	// DATABASE
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	SSLMode    string
+	// MetricsToken protects /metrics with a bearer token when set
+	MetricsToken string `yaml:"metrics_token"`
+	// AdminToken enables the /admin routes, protected with this bearer token
+	AdminToken string `yaml:"admin_token"`
+	// TrustedProxies are the IPs or CIDRs of the load balancers whose X-Forwarded-For
+	// is believed. Empty trusts none, the client IP is the peer address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 
-	// R4 API credentials
-	R4BoneEntryPoint    string
-	R4BoneCommerceToken string
-	BoneSecret          string
+	EgressIP         EgressIPConfig         `yaml:"egress_ip"`
+	GRPC             GRPCConfig             `yaml:"grpc"`
+	OutboundWebhooks OutboundWebhooksConfig `yaml:"outbound_webhooks"`
+	Outbox           OutboxConfig           `yaml:"outbox"`
+	Broker           BrokerConfig           `yaml:"broker"`
+	LiveFeed         LiveFeedConfig         `yaml:"live_feed"`
+	Backoffice       BackofficeConfig       `yaml:"backoffice"`
+	RateLimit        RateLimitConfig        `yaml:"rate_limit"`
 
-	R4APPAEntryPoint    string
-	R4APPACommerceToken string
-	APPASecret          string
+	// TracesExporter is one of none, otlp or stdout. The OTLP collector is set
+	// with the standard OTEL_EXPORTER_OTLP_* variables.
+	TracesExporter string `yaml:"traces_exporter"`
 }
 
-// Load reads configuration from environment variables and returns a Config struct
-func Load() (*Config, error) {
-	cfg := &Config{
-		Port: os.Getenv("PORT"),
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"ssl_mode"`
+
+	// AutoMigrate applies pending migrations at boot instead of requiring the migrate command
+	AutoMigrate bool `yaml:"auto_migrate"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// ConnectAttempts is how many times startup tries to reach the database, waiting
+	// ConnectBackoff after the first failure and doubling it up to ConnectMaxBackoff
+	ConnectAttempts   int           `yaml:"connect_attempts"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff"`
 
-		// DATABASE
-		DBHost:     os.Getenv("DB_HOST"),
-		DBPort:     os.Getenv("DB_PORT"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		SSLMode:    os.Getenv("SSL_MODE"),
+	// Replica optionally serves the payment query and export endpoints
+	Replica ReplicaConfig `yaml:"replica"`
+}
+
+// ReplicaConfig is a read replica of the database. It is enabled by setting Host,
+// the other fields default to the primary's.
+type ReplicaConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"ssl_mode"`
+}
 
-		R4BoneEntryPoint:    os.Getenv("R4_BONE_ENTRY_POINT"),
-		R4BoneCommerceToken: os.Getenv("R4_BONE_COMMERCE_TOKEN"),
-		BoneSecret:          os.Getenv("BONE_SECRET"),
+// Enabled reports whether a read replica is configured
+func (r ReplicaConfig) Enabled() bool {
+	return r.Host != ""
+}
+
+type R4Config struct {
+	// RequestTimeout bounds each call to the R4 API
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// DebitPollAttempts is how many times ConsultarOperaciones is called while a debit is AC00
+	DebitPollAttempts int `yaml:"debit_poll_attempts"`
+	// DebitPollInterval is the wait between two ConsultarOperaciones calls
+	DebitPollInterval time.Duration `yaml:"debit_poll_interval"`
+}
+
+// EgressIPConfig configures the diagnostic comparing the server's public IP with the ones registered with R4
+type EgressIPConfig struct {
+	// ProviderURL answers with the public IP, IPv4 by default since R4 registers IPv4
+	ProviderURL string        `yaml:"provider_url"`
+	Timeout     time.Duration `yaml:"timeout"`
+	CacheTTL    time.Duration `yaml:"cache_ttl"`
+	// RegisteredIPs are the IPs R4 accepts calls from for our commerces
+	RegisteredIPs []string `yaml:"registered_ips"`
+	// CheckOnStartup looks the IP up in the background at boot and warns if it is not registered
+	CheckOnStartup bool `yaml:"check_on_startup"`
+}
+
+// GRPCConfig configures the gRPC API offered to internal clients
+type GRPCConfig struct {
+	// Port enables the gRPC server next to the HTTP one when set
+	Port string `yaml:"port"`
+	// WatchTimeout ends the WatchOperation streams of debits still pending after it
+	WatchTimeout time.Duration `yaml:"watch_timeout"`
+}
+
+// Enabled reports whether the gRPC server should run
+func (g GRPCConfig) Enabled() bool {
+	return g.Port != ""
+}
+
+// OutboundWebhooksConfig controls the delivery of events to the subscriptions created in /admin/subscriptions
+type OutboundWebhooksConfig struct {
+	// Disabled stops this instance from sending deliveries, events are still queued
+	Disabled        bool          `yaml:"disabled"`
+	PollInterval    time.Duration `yaml:"poll_interval"`
+	BatchSize       int           `yaml:"batch_size"`
+	DeliveryTimeout time.Duration `yaml:"delivery_timeout"`
+	// MaxAttempts is how many times an event is sent before its delivery is marked failed
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the wait after the first failure, doubled after each one up to MaxBackoff
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// OutboxConfig controls the relay of the outbox events to the broker and the webhook subscriptions
+type OutboxConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	BatchSize      int           `yaml:"batch_size"`
+	PublishTimeout time.Duration `yaml:"publish_timeout"`
+	// InitialBackoff is the wait after an event first fails, doubled after each failure up to MaxBackoff
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// Broker kinds
+const (
+	BrokerNone     = "none"
+	BrokerMemory   = "memory"
+	BrokerNATS     = "nats"
+	BrokerKafka    = "kafka"
+	BrokerRabbitMQ = "rabbitmq"
+)
+
+// BrokerConfig is the message broker the domain events are published to
+type BrokerConfig struct {
+	// Kind is none, memory (kept in process, for local use), nats, kafka or rabbitmq
+	Kind string `yaml:"kind"`
+	// URL of the NATS server or the RabbitMQ broker
+	URL string `yaml:"url"`
+	// Brokers are the Kafka bootstrap servers
+	Brokers []string `yaml:"brokers"`
+	// Topic is the NATS subject prefix, the Kafka topic or the RabbitMQ exchange
+	Topic string `yaml:"topic"`
+}
 
-		R4APPAEntryPoint:    os.Getenv("R4_APPA_ENTRY_POINT"),
-		R4APPACommerceToken: os.Getenv("R4_APPA_COMMERCE_TOKEN"),
-		APPASecret:          os.Getenv("APPA_SECRET"),
+// LiveFeedConfig controls the /v1/<store>/feed server-sent events
+type LiveFeedConfig struct {
+	// Disabled stops this instance from serving the feeds and listening for events
+	Disabled bool `yaml:"disabled"`
+	// Heartbeat is how often an idle feed sends a comment to keep proxies from closing it
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Buffer is how many events a feed can fall behind before it is closed
+	Buffer int `yaml:"buffer"`
+	// ReplayLimit bounds the missed events sent to a feed resuming with Last-Event-ID
+	ReplayLimit      int           `yaml:"replay_limit"`
+	ReconnectBackoff time.Duration `yaml:"reconnect_backoff"`
+}
+
+// BackofficeConfig controls the /backoffice UI of the support and finance staff
+type BackofficeConfig struct {
+	Disabled   bool          `yaml:"disabled"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	// InsecureCookie sends the session cookie over plain HTTP, for local development only
+	InsecureCookie bool `yaml:"insecure_cookie"`
+	// ChangeApprovalAbove holds the change payouts above this amount until a finance
+	// user approves them, 0 pays them all right away
+	ChangeApprovalAbove float64 `yaml:"change_approval_above"`
+}
+
+// Rate limit stores
+const (
+	RateLimitMemory   = "memory"
+	RateLimitPostgres = "postgres"
+)
+
+// RateLimitConfig throttles the OTPs, change payouts and webhooks. Limits are written as
+// requests/period, e.g. 3/10m, and 0 disables one.
+type RateLimitConfig struct {
+	Disabled bool `yaml:"disabled"`
+	// Store is memory, limiting each instance on its own, or postgres, shared by all of them
+	Store string `yaml:"store"`
+	// OTPPerClient limits the OTPs requested by each API client
+	OTPPerClient ratelimit.Limit `yaml:"otp_per_client"`
+	// OTPPerTarget limits the OTPs sent to each customer phone and cédula
+	OTPPerTarget    ratelimit.Limit `yaml:"otp_per_target"`
+	ChangePerClient ratelimit.Limit `yaml:"change_per_client"`
+	ChangePerTarget ratelimit.Limit `yaml:"change_per_target"`
+	// WebhookPerIP limits the R4consulta/R4notifica webhooks failing authentication by
+	// source IP. Behind a load balancer it needs TrustedProxies, or all of them share the
+	// balancer's IP.
+	WebhookPerIP ratelimit.Limit `yaml:"webhook_per_ip"`
+}
+
+// Limits returns the limits by rule, for ratelimit.NewLimiter
+func (r RateLimitConfig) Limits() map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		ratelimit.OTPPerClient:    r.OTPPerClient,
+		ratelimit.OTPPerTarget:    r.OTPPerTarget,
+		ratelimit.ChangePerClient: r.ChangePerClient,
+		ratelimit.ChangePerTarget: r.ChangePerTarget,
+		ratelimit.WebhookPerIP:    r.WebhookPerIP,
+	}
+}
+
+type StoreConfig struct {
+	EntryPoint    string `yaml:"entry_point"`
+	CommerceToken string `yaml:"commerce_token"`
+	Secret        string `yaml:"secret"`
+}
+
+// Store returns the configuration of a store, which Load guarantees to exist
+func (c *Config) Store(name string) *StoreConfig {
+	return c.Stores[name]
+}
+
+// Load builds the configuration from, in increasing precedence: defaults, the YAML file
+// named by CONFIG_FILE, and environment variables. Every variable can also be read from
+// a file with the _FILE suffix (e.g. DB_PASSWORD_FILE) to support mounted secrets.
+// All problems are reported at once.
+func Load() (*Config, error) {
+	cfg := defaults()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg.ensureStores()
+
+	var errs []error
+	for _, b := range cfg.bindings() {
+		if err := b.apply(); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	errs = append(errs, cfg.validate()...)
 
-	if err := validate(cfg); err != nil {
-		return nil, err
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
 	}
 
 	return cfg, nil
 }
 
-func validate(cfg *Config) error {
-	if cfg.DBHost == "" {
-		return fmt.Errorf("DBHost is not configured")
+// WatchedFiles returns the files Load reads: CONFIG_FILE and every *_FILE secret
+func WatchedFiles() []string {
+	var files []string
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		files = append(files, path)
 	}
-	if cfg.DBPort == "" {
-		return fmt.Errorf("DBPort is not configured")
+
+	cfg := defaults()
+	cfg.ensureStores()
+	for _, b := range cfg.bindings() {
+		if path := os.Getenv(b.key + "_FILE"); path != "" {
+			files = append(files, path)
+		}
 	}
-	if cfg.DBUser == "" {
-		return fmt.Errorf("DBUser is not configured")
+
+	return files
+}
+
+func defaults() *Config {
+	return &Config{
+		Port:               "8080",
+		Timezone:           "America/Caracas",
+		ShutdownTimeout:    8 * time.Second,
+		ShutdownDelay:      time.Second,
+		HealthCheckTimeout: 2 * time.Second,
+
+		SecretRotationOverlap: 15 * time.Minute,
+		Database: DatabaseConfig{
+			MaxOpenConns:      20,
+			MaxIdleConns:      10,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnMaxIdleTime:   5 * time.Minute,
+			ConnectAttempts:   5,
+			ConnectBackoff:    time.Second,
+			ConnectMaxBackoff: 15 * time.Second,
+		},
+		R4: R4Config{
+			RequestTimeout:    20 * time.Second,
+			DebitPollAttempts: 7,
+			DebitPollInterval: 3 * time.Second,
+		},
+		EgressIP: EgressIPConfig{
+			ProviderURL: "https://api.ipify.org?format=json",
+			Timeout:     5 * time.Second,
+			CacheTTL:    10 * time.Minute,
+		},
+		GRPC: GRPCConfig{
+			WatchTimeout: 2 * time.Minute,
+		},
+		OutboundWebhooks: OutboundWebhooksConfig{
+			PollInterval:    2 * time.Second,
+			BatchSize:       20,
+			DeliveryTimeout: 10 * time.Second,
+			MaxAttempts:     8,
+			InitialBackoff:  30 * time.Second,
+			MaxBackoff:      time.Hour,
+		},
+		Outbox: OutboxConfig{
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			PublishTimeout: 10 * time.Second,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+		},
+		Broker: BrokerConfig{
+			Kind:  BrokerNone,
+			Topic: "r4.events",
+		},
+		LiveFeed: LiveFeedConfig{
+			Heartbeat:        15 * time.Second,
+			Buffer:           64,
+			ReplayLimit:      500,
+			ReconnectBackoff: 2 * time.Second,
+		},
+		Backoffice: BackofficeConfig{
+			SessionTTL: 12 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Store:           RateLimitMemory,
+			OTPPerClient:    ratelimit.Limit{Requests: 120, Per: time.Minute},
+			OTPPerTarget:    ratelimit.Limit{Requests: 3, Per: 10 * time.Minute},
+			ChangePerClient: ratelimit.Limit{Requests: 60, Per: time.Minute},
+			ChangePerTarget: ratelimit.Limit{Requests: 10, Per: time.Hour},
+			WebhookPerIP:    ratelimit.Limit{Requests: 60, Per: time.Minute},
+		},
+		TracesExporter: "none",
 	}
-	if cfg.DBPassword == "" {
-		return fmt.Errorf("DBPassword is not configured")
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
 	}
-	if cfg.DBName == "" {
-		return fmt.Errorf("DBName is not configured")
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
 	}
 
-	if cfg.R4BoneEntryPoint == "" {
-		return fmt.Errorf("R4BoneEntryPoint is not configured")
+	return nil
+}
+
+func (c *Config) ensureStores() {
+	if c.Stores == nil {
+		c.Stores = make(map[string]*StoreConfig, len(StoreNames))
 	}
-	if cfg.R4BoneCommerceToken == "" {
-		return fmt.Errorf("R4BoneCommerceToken is not configured")
+	for _, name := range StoreNames {
+		if c.Stores[name] == nil {
+			c.Stores[name] = &StoreConfig{}
+		}
 	}
-	if cfg.BoneSecret == "" {
-		return fmt.Errorf("BoneSecret is not configured")
+}
+
+// binding maps an environment variable onto a configuration field
+type binding struct {
+	key string
+	set func(value string) error
+}
+
+// apply reads KEY or KEY_FILE, leaving the field untouched when neither is set
+func (b binding) apply() error {
+	value, hasValue := os.LookupEnv(b.key)
+	file, hasFile := os.LookupEnv(b.key + "_FILE")
+
+	switch {
+	case hasValue && hasFile:
+		return fmt.Errorf("%s and %s_FILE are both set", b.key, b.key)
+	case hasFile:
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", b.key, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	case !hasValue || value == "":
+		return nil
+	}
+
+	if err := b.set(value); err != nil {
+		return fmt.Errorf("%s: %w", b.key, err)
+	}
+	return nil
+}
+
+func stringVar(key string, target *string) binding {
+	return binding{key: key, set: func(v string) error {
+		*target = v
+		return nil
+	}}
+}
+
+func durationVar(key string, target *time.Duration) binding {
+	return binding{key: key, set: func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("not a valid duration: %w", err)
+		}
+		*target = d
+		return nil
+	}}
+}
+
+func intVar(key string, target *int) binding {
+	return binding{key: key, set: func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("not a valid integer: %w", err)
+		}
+		*target = n
+		return nil
+	}}
+}
+
+func floatVar(key string, target *float64) binding {
+	return binding{key: key, set: func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("not a valid number: %w", err)
+		}
+		*target = f
+		return nil
+	}}
+}
+
+func boolVar(key string, target *bool) binding {
+	return binding{key: key, set: func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("not a valid boolean: %w", err)
+		}
+		*target = b
+		return nil
+	}}
+}
+
+// limitVar reads a rate limit written as requests/period
+func limitVar(key string, target *ratelimit.Limit) binding {
+	return binding{key: key, set: func(v string) error {
+		return target.UnmarshalText([]byte(v))
+	}}
+}
+
+// listVar reads a comma-separated list
+func listVar(key string, target *[]string) binding {
+	return binding{key: key, set: func(v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target = list
+		return nil
+	}}
+}
+
+func (c *Config) bindings() []binding {
+	bindings := []binding{
+		stringVar("PORT", &c.Port),
+		stringVar("TIMEZONE", &c.Timezone),
+		durationVar("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout),
+		durationVar("SHUTDOWN_DELAY", &c.ShutdownDelay),
+		durationVar("HEALTH_CHECK_TIMEOUT", &c.HealthCheckTimeout),
+		durationVar("SECRET_ROTATION_OVERLAP", &c.SecretRotationOverlap),
+
+		stringVar("DB_HOST", &c.Database.Host),
+		stringVar("DB_PORT", &c.Database.Port),
+		stringVar("DB_USER", &c.Database.User),
+		stringVar("DB_PASSWORD", &c.Database.Password),
+		stringVar("DB_NAME", &c.Database.Name),
+		stringVar("SSL_MODE", &c.Database.SSLMode),
+		boolVar("DB_AUTO_MIGRATE", &c.Database.AutoMigrate),
+		intVar("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns),
+		intVar("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns),
+		durationVar("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime),
+		durationVar("DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime),
+		intVar("DB_CONNECT_ATTEMPTS", &c.Database.ConnectAttempts),
+		durationVar("DB_CONNECT_BACKOFF", &c.Database.ConnectBackoff),
+		durationVar("DB_CONNECT_MAX_BACKOFF", &c.Database.ConnectMaxBackoff),
+
+		stringVar("DB_REPLICA_HOST", &c.Database.Replica.Host),
+		stringVar("DB_REPLICA_PORT", &c.Database.Replica.Port),
+		stringVar("DB_REPLICA_USER", &c.Database.Replica.User),
+		stringVar("DB_REPLICA_PASSWORD", &c.Database.Replica.Password),
+		stringVar("DB_REPLICA_NAME", &c.Database.Replica.Name),
+		stringVar("DB_REPLICA_SSL_MODE", &c.Database.Replica.SSLMode),
+
+		durationVar("R4_REQUEST_TIMEOUT", &c.R4.RequestTimeout),
+		intVar("R4_DEBIT_POLL_ATTEMPTS", &c.R4.DebitPollAttempts),
+		durationVar("R4_DEBIT_POLL_INTERVAL", &c.R4.DebitPollInterval),
+
+		stringVar("BANK_CATALOG_FILE", &c.BankCatalogFile),
+		stringVar("METRICS_TOKEN", &c.MetricsToken),
+		stringVar("ADMIN_TOKEN", &c.AdminToken),
+		stringVar("EGRESS_IP_PROVIDER_URL", &c.EgressIP.ProviderURL),
+		durationVar("EGRESS_IP_TIMEOUT", &c.EgressIP.Timeout),
+		durationVar("EGRESS_IP_CACHE_TTL", &c.EgressIP.CacheTTL),
+		listVar("R4_REGISTERED_IPS", &c.EgressIP.RegisteredIPs),
+		boolVar("EGRESS_IP_CHECK_ON_STARTUP", &c.EgressIP.CheckOnStartup),
+		stringVar("GRPC_PORT", &c.GRPC.Port),
+		durationVar("GRPC_WATCH_TIMEOUT", &c.GRPC.WatchTimeout),
+		boolVar("OUTBOUND_WEBHOOKS_DISABLED", &c.OutboundWebhooks.Disabled),
+		durationVar("OUTBOUND_WEBHOOKS_POLL_INTERVAL", &c.OutboundWebhooks.PollInterval),
+		intVar("OUTBOUND_WEBHOOKS_BATCH_SIZE", &c.OutboundWebhooks.BatchSize),
+		durationVar("OUTBOUND_WEBHOOKS_DELIVERY_TIMEOUT", &c.OutboundWebhooks.DeliveryTimeout),
+		intVar("OUTBOUND_WEBHOOKS_MAX_ATTEMPTS", &c.OutboundWebhooks.MaxAttempts),
+		durationVar("OUTBOUND_WEBHOOKS_INITIAL_BACKOFF", &c.OutboundWebhooks.InitialBackoff),
+		durationVar("OUTBOUND_WEBHOOKS_MAX_BACKOFF", &c.OutboundWebhooks.MaxBackoff),
+		durationVar("OUTBOX_POLL_INTERVAL", &c.Outbox.PollInterval),
+		intVar("OUTBOX_BATCH_SIZE", &c.Outbox.BatchSize),
+		durationVar("OUTBOX_PUBLISH_TIMEOUT", &c.Outbox.PublishTimeout),
+		durationVar("OUTBOX_INITIAL_BACKOFF", &c.Outbox.InitialBackoff),
+		durationVar("OUTBOX_MAX_BACKOFF", &c.Outbox.MaxBackoff),
+		stringVar("BROKER_KIND", &c.Broker.Kind),
+		stringVar("BROKER_URL", &c.Broker.URL),
+		listVar("BROKER_KAFKA_BROKERS", &c.Broker.Brokers),
+		stringVar("BROKER_TOPIC", &c.Broker.Topic),
+		boolVar("LIVE_FEED_DISABLED", &c.LiveFeed.Disabled),
+		durationVar("LIVE_FEED_HEARTBEAT", &c.LiveFeed.Heartbeat),
+		intVar("LIVE_FEED_BUFFER", &c.LiveFeed.Buffer),
+		intVar("LIVE_FEED_REPLAY_LIMIT", &c.LiveFeed.ReplayLimit),
+		durationVar("LIVE_FEED_RECONNECT_BACKOFF", &c.LiveFeed.ReconnectBackoff),
+		boolVar("BACKOFFICE_DISABLED", &c.Backoffice.Disabled),
+		durationVar("BACKOFFICE_SESSION_TTL", &c.Backoffice.SessionTTL),
+		boolVar("BACKOFFICE_INSECURE_COOKIE", &c.Backoffice.InsecureCookie),
+		floatVar("BACKOFFICE_CHANGE_APPROVAL_ABOVE", &c.Backoffice.ChangeApprovalAbove),
+		listVar("TRUSTED_PROXIES", &c.TrustedProxies),
+		boolVar("RATE_LIMIT_DISABLED", &c.RateLimit.Disabled),
+		stringVar("RATE_LIMIT_STORE", &c.RateLimit.Store),
+		limitVar("RATE_LIMIT_OTP_PER_CLIENT", &c.RateLimit.OTPPerClient),
+		limitVar("RATE_LIMIT_OTP_PER_TARGET", &c.RateLimit.OTPPerTarget),
+		limitVar("RATE_LIMIT_CHANGE_PER_CLIENT", &c.RateLimit.ChangePerClient),
+		limitVar("RATE_LIMIT_CHANGE_PER_TARGET", &c.RateLimit.ChangePerTarget),
+		limitVar("RATE_LIMIT_WEBHOOK_PER_IP", &c.RateLimit.WebhookPerIP),
+		stringVar("OTEL_TRACES_EXPORTER", &c.TracesExporter),
 	}
 
-	if cfg.R4APPAEntryPoint == "" {
-		return fmt.Errorf("R4APPAEntryPoint is not configured")
+	// R4_BONE_ENTRY_POINT, R4_BONE_COMMERCE_TOKEN, BONE_SECRET, ...
+	for _, name := range StoreNames {
+		store, prefix := c.Stores[name], strings.ToUpper(name)
+		bindings = append(bindings,
+			stringVar("R4_"+prefix+"_ENTRY_POINT", &store.EntryPoint),
+			stringVar("R4_"+prefix+"_COMMERCE_TOKEN", &store.CommerceToken),
+			stringVar(prefix+"_SECRET", &store.Secret),
+		)
 	}
-	if cfg.R4APPACommerceToken == "" {
-		return fmt.Errorf("R4APPACommerceToken is not configured")
+
+	return bindings
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	required := func(value, name string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is not configured", name))
+		}
 	}
-	if cfg.APPASecret == "" {
-		return fmt.Errorf("APPASecret is not configured")
+	positive := func(d time.Duration, name string) {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be greater than zero", name))
+		}
 	}
 
-	return nil
+	required(c.Database.Host, "database.host (DB_HOST)")
+	required(c.Database.Port, "database.port (DB_PORT)")
+	required(c.Database.User, "database.user (DB_USER)")
+	required(c.Database.Password, "database.password (DB_PASSWORD)")
+	required(c.Database.Name, "database.name (DB_NAME)")
+
+	names := make([]string, 0, len(c.Stores))
+	for name := range c.Stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		store := c.Stores[name]
+		if !isStoreName(name) {
+			errs = append(errs, fmt.Errorf("stores.%s is not a known store", name))
+			continue
+		}
+		prefix := strings.ToUpper(name)
+		required(store.EntryPoint, fmt.Sprintf("stores.%s.entry_point (R4_%s_ENTRY_POINT)", name, prefix))
+		required(store.CommerceToken, fmt.Sprintf("stores.%s.commerce_token (R4_%s_COMMERCE_TOKEN)", name, prefix))
+		required(store.Secret, fmt.Sprintf("stores.%s.secret (%s_SECRET)", name, prefix))
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database.max_open_conns and database.max_idle_conns cannot be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns cannot exceed database.max_open_conns"))
+	}
+	if c.Database.ConnectAttempts < 1 {
+		errs = append(errs, errors.New("database.connect_attempts must be at least 1"))
+	}
+	positive(c.Database.ConnectBackoff, "database.connect_backoff")
+	positive(c.Database.ConnectMaxBackoff, "database.connect_max_backoff")
+
+	positive(c.EgressIP.Timeout, "egress_ip.timeout")
+	for _, ip := range c.EgressIP.RegisteredIPs {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("egress_ip.registered_ips: %q is not an IP address", ip))
+		}
+	}
+
+	positive(c.ShutdownTimeout, "shutdown_timeout")
+	positive(c.HealthCheckTimeout, "health_check_timeout")
+	positive(c.R4.RequestTimeout, "r4.request_timeout")
+	positive(c.R4.DebitPollInterval, "r4.debit_poll_interval")
+	if c.GRPC.Enabled() {
+		positive(c.GRPC.WatchTimeout, "grpc.watch_timeout")
+		if c.GRPC.Port == c.Port {
+			errs = append(errs, errors.New("grpc.port must differ from port"))
+		}
+	}
+	positive(c.OutboundWebhooks.PollInterval, "outbound_webhooks.poll_interval")
+	positive(c.OutboundWebhooks.DeliveryTimeout, "outbound_webhooks.delivery_timeout")
+	positive(c.OutboundWebhooks.InitialBackoff, "outbound_webhooks.initial_backoff")
+	if c.OutboundWebhooks.MaxBackoff < c.OutboundWebhooks.InitialBackoff {
+		errs = append(errs, errors.New("outbound_webhooks.max_backoff cannot be less than outbound_webhooks.initial_backoff"))
+	}
+	if c.OutboundWebhooks.BatchSize < 1 || c.OutboundWebhooks.MaxAttempts < 1 {
+		errs = append(errs, errors.New("outbound_webhooks.batch_size and outbound_webhooks.max_attempts must be at least 1"))
+	}
+	positive(c.Outbox.PollInterval, "outbox.poll_interval")
+	positive(c.Outbox.PublishTimeout, "outbox.publish_timeout")
+	positive(c.Outbox.InitialBackoff, "outbox.initial_backoff")
+	if c.Outbox.MaxBackoff < c.Outbox.InitialBackoff {
+		errs = append(errs, errors.New("outbox.max_backoff cannot be less than outbox.initial_backoff"))
+	}
+	if c.Outbox.BatchSize < 1 {
+		errs = append(errs, errors.New("outbox.batch_size must be at least 1"))
+	}
+	switch c.Broker.Kind {
+	case "", BrokerNone, BrokerMemory:
+	case BrokerNATS, BrokerRabbitMQ:
+		required(c.Broker.URL, "broker.url (BROKER_URL)")
+		required(c.Broker.Topic, "broker.topic (BROKER_TOPIC)")
+	case BrokerKafka:
+		if len(c.Broker.Brokers) == 0 {
+			errs = append(errs, errors.New("broker.brokers (BROKER_KAFKA_BROKERS) is not configured"))
+		}
+		required(c.Broker.Topic, "broker.topic (BROKER_TOPIC)")
+	default:
+		errs = append(errs, fmt.Errorf("broker.kind %q must be none, memory, nats, kafka or rabbitmq", c.Broker.Kind))
+	}
+	positive(c.LiveFeed.Heartbeat, "live_feed.heartbeat")
+	positive(c.LiveFeed.ReconnectBackoff, "live_feed.reconnect_backoff")
+	if c.LiveFeed.Buffer < 1 || c.LiveFeed.ReplayLimit < 1 {
+		errs = append(errs, errors.New("live_feed.buffer and live_feed.replay_limit must be at least 1"))
+	}
+	positive(c.Backoffice.SessionTTL, "backoffice.session_ttl")
+	if c.Backoffice.ChangeApprovalAbove < 0 {
+		errs = append(errs, errors.New("backoffice.change_approval_above cannot be negative"))
+	}
+	if c.Backoffice.Disabled && c.Backoffice.ChangeApprovalAbove > 0 {
+		// Nobody could approve the payouts held
+		errs = append(errs, errors.New("backoffice.change_approval_above needs the back office enabled"))
+	}
+	switch c.RateLimit.Store {
+	case RateLimitMemory, RateLimitPostgres:
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit.store %q must be memory or postgres", c.RateLimit.Store))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("trusted_proxies: %q is not an IP address or CIDR", proxy))
+			}
+		}
+	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("shutdown_delay cannot be negative"))
+	}
+	if c.ShutdownDelay >= c.ShutdownTimeout {
+		errs = append(errs, errors.New("shutdown_delay must be less than shutdown_timeout, which includes it"))
+	}
+	if c.SecretRotationOverlap < 0 {
+		errs = append(errs, errors.New("secret_rotation_overlap cannot be negative"))
+	}
+	if c.R4.DebitPollAttempts < 1 {
+		errs = append(errs, errors.New("r4.debit_poll_attempts must be at least 1"))
+	}
+
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("timezone: %w", err))
+	}
+
+	switch c.TracesExporter {
+	case "", "none", "otlp", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("traces_exporter %q must be none, otlp or stdout", c.TracesExporter))
+	}
+
+	return errs
+}
+
+func isStoreName(name string) bool {
+	for _, n := range StoreNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequired sets the variables validate requires, so each test only sets what it checks
+func setRequired(t *testing.T) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"DB_HOST":     "localhost",
+		"DB_PORT":     "5432",
+		"DB_USER":     "r4",
+		"DB_PASSWORD": "r4",
+		"DB_NAME":     "r4",
+	} {
+		t.Setenv(key, value)
+	}
+	for _, name := range StoreNames {
+		prefix := strings.ToUpper(name)
+		t.Setenv("R4_"+prefix+"_ENTRY_POINT", "https://r4.example.com")
+		t.Setenv("R4_"+prefix+"_COMMERCE_TOKEN", "token-"+name)
+		t.Setenv(prefix+"_SECRET", "secret-"+name)
+	}
+}
+
+// writeFile writes content to a file in the test's directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setRequired(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", `
+port: "9000"
+r4:
+  request_timeout: 5s
+database:
+  host: yaml-db
+stores:
+  bone:
+    entry_point: https://bone.example.com
+rate_limit:
+  otp_per_target: 5/1h
+`))
+	t.Setenv("PORT", "9100")
+	t.Setenv("DB_HOST", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9100" {
+		t.Errorf("Port = %q, want the environment's 9100", cfg.Port)
+	}
+	if cfg.R4.RequestTimeout != 5*time.Second || cfg.RateLimit.OTPPerTarget.String() != "5/1h0m0s" {
+		t.Errorf("R4.RequestTimeout = %v, OTPPerTarget = %v, want the file's", cfg.R4.RequestTimeout, cfg.RateLimit.OTPPerTarget)
+	}
+	if cfg.Database.Host != "yaml-db" {
+		t.Errorf("Database.Host = %q, want the file's, an empty variable does not override it", cfg.Database.Host)
+	}
+	if cfg.Store(StoreBone).EntryPoint != "https://r4.example.com" || cfg.Store(StoreAppa).CommerceToken != "token-appa" {
+		t.Errorf("stores = %+v %+v, want the environment's", cfg.Store(StoreBone), cfg.Store(StoreAppa))
+	}
+	if cfg.R4.DebitPollAttempts != 7 || cfg.ShutdownTimeout != 8*time.Second {
+		t.Errorf("DebitPollAttempts = %d, ShutdownTimeout = %v, want the defaults", cfg.R4.DebitPollAttempts, cfg.ShutdownTimeout)
+	}
+}
+
+func TestLoadReadsVariablesFromFiles(t *testing.T) {
+	setRequired(t)
+	os.Unsetenv("DB_PASSWORD")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("Database.Password = %q, want the file's without its newline", cfg.Database.Password)
+	}
+
+	t.Setenv("DB_PASSWORD", "other")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD and DB_PASSWORD_FILE are both set") {
+		t.Errorf("Load with DB_PASSWORD and DB_PASSWORD_FILE = %v, want both reported", err)
+	}
+
+	os.Unsetenv("DB_PASSWORD")
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+		t.Errorf("Load with a missing DB_PASSWORD_FILE = %v, want it reported", err)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	setRequired(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "database:\n  hots: db.example.com\n"))
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "hots") {
+		t.Errorf("Load with a misspelled field = %v, want it rejected", err)
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+	t.Setenv("RATE_LIMIT_STORE", "redis")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,load-balancer")
+	t.Setenv("TIMEZONE", "Mars/Olympus_Mons")
+	t.Setenv("DB_HOST", "")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load without a database or stores succeeded")
+	}
+	for _, want := range []string{
+		"SHUTDOWN_TIMEOUT: not a valid duration",
+		"database.host (DB_HOST) is not configured",
+		"stores.appa.secret (APPA_SECRET) is not configured",
+		"stores.bone.entry_point (R4_BONE_ENTRY_POINT) is not configured",
+		`rate_limit.store "redis" must be memory or postgres`,
+		`trusted_proxies: "load-balancer" is not an IP address or CIDR`,
+		"timezone:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+}
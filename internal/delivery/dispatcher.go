@@ -0,0 +1,239 @@
+// Package delivery sends the queued event webhooks to their subscribers
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/webhooks"
+)
+
+// maxErrorBody bounds how much of a subscriber's error answer is kept in the delivery log
+const maxErrorBody = 512
+
+// Config controls how often deliveries are attempted
+type Config struct {
+	// PollInterval is how often due deliveries are looked for
+	PollInterval time.Duration
+	// BatchSize is how many deliveries are sent at once
+	BatchSize int
+	// Timeout bounds each request to a subscriber
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failure, doubled after each one up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Dispatcher sends due deliveries. Several instances can run against the same
+// database, each delivery is claimed by one of them at a time.
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	cfg    Config
+	logger *zap.Logger
+}
+
+// claimed is a delivery leased by this instance, with where and how to send it
+type claimed struct {
+	ID        int64
+	EventID   string
+	EventType string
+	Payload   []byte
+	Attempts  int
+	URL       string
+	Secret    string
+}
+
+// NewDispatcher creates a Dispatcher
+func NewDispatcher(db *gorm.DB, cfg Config, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run sends due deliveries every PollInterval until ctx is done. Requests already
+// sent when ctx is done are allowed to finish.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep going while full batches come back, a backlog should not wait for the next tick
+		for ctx.Err() == nil {
+			n, err := d.DispatchDue(context.WithoutCancel(ctx))
+			if err != nil {
+				d.logger.Error("could not dispatch event deliveries", zap.Error(err))
+			}
+			if err != nil || n < d.cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries and returns how many were attempted
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	batch, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+
+	return len(batch), nil
+}
+
+// claim leases a batch of due deliveries by moving their next attempt past the request
+// timeout, so other instances skip them and a crash only delays them
+func (d *Dispatcher) claim(ctx context.Context) ([]claimed, error) {
+	lease := 2 * d.cfg.Timeout
+	var batch []claimed
+	err := d.db.WithContext(ctx).Raw(`
+		UPDATE event_deliveries d SET next_attempt_at = NOW() + make_interval(secs => ?)
+		FROM event_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT due.id FROM event_deliveries due
+			JOIN event_subscriptions sub ON sub.id = due.subscription_id
+			WHERE due.status = ? AND due.next_attempt_at <= NOW() AND sub.disabled_at IS NULL
+			ORDER BY due.next_attempt_at
+			LIMIT ?
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret`,
+		lease.Seconds(), dbModels.DeliveryPending, d.cfg.BatchSize,
+	).Scan(&batch).Error
+	if err != nil {
+		return nil, fmt.Errorf("claiming event deliveries: %w", err)
+	}
+	return batch, nil
+}
+
+// attempt sends a delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery claimed) {
+	start := time.Now()
+	statusCode, sendErr := d.send(ctx, delivery)
+	duration := time.Since(start)
+	metrics.EventDeliveryDuration.WithLabelValues(delivery.EventType).Observe(duration.Seconds())
+
+	attempt := delivery.Attempts + 1
+	row := dbModels.EventDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    attempt,
+		DurationMS: int(duration.Milliseconds()),
+	}
+	if statusCode != 0 {
+		row.StatusCode = &statusCode
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		row.Error = &message
+	}
+
+	updates := map[string]any{
+		"attempts":         attempt,
+		"last_status_code": row.StatusCode,
+		"last_error":       row.Error,
+	}
+	outcome := "delivered"
+	switch {
+	case sendErr == nil:
+		updates["status"] = dbModels.DeliveryDelivered
+		updates["delivered_at"] = gorm.Expr("NOW()")
+	case attempt >= d.cfg.MaxAttempts:
+		outcome = "failed"
+		updates["status"] = dbModels.DeliveryFailed
+	default:
+		outcome = "retrying"
+		updates["next_attempt_at"] = gorm.Expr("NOW() + make_interval(secs => ?)", d.backoff(attempt).Seconds())
+	}
+	metrics.EventDeliveries.WithLabelValues(delivery.EventType, outcome).Inc()
+
+	logger := d.logger.With(
+		zap.Int64("delivery_id", delivery.ID),
+		zap.String("event", delivery.EventType),
+		zap.String("event_id", delivery.EventID),
+		zap.Int("attempt", attempt),
+	)
+	if sendErr != nil {
+		logger.Warn("event delivery failed", zap.Error(sendErr), zap.String("outcome", outcome))
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		return tx.Model(&dbModels.EventDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+	})
+	if err != nil {
+		// The lease expires and the delivery is sent again, subscribers dedupe on the event ID
+		logger.Error("could not record event delivery attempt", zap.Error(err))
+	}
+}
+
+// send posts the event and returns the subscriber's status code, with an error unless it is 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery claimed) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bone-appetit-r4-service")
+	req.Header.Set(webhooks.EventHeader, delivery.EventType)
+	req.Header.Set(webhooks.EventIDHeader, delivery.EventID)
+	req.Header.Set(webhooks.DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(delivery.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait after the given failed attempt: exponential, capped at
+// MaxBackoff, with jitter so subscribers coming back are not hit all at once
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, d.cfg.MaxBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
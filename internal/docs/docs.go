@@ -0,0 +1,43 @@
+// Package docs embeds the OpenAPI document of the service and the Swagger UI serving it
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The document is maintained by hand in openapi.yaml, openapi_test.go keeps it in
+// sync with the routes and the request and response models.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Spec returns the OpenAPI document as JSON
+func Spec() ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("parsing openapi.yaml: %w", err)
+	}
+	return json.Marshal(doc)
+}
+
+// swaggerInitializer replaces the one shipped with the UI, which loads the petstore example
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// SwaggerInitializer returns the script pointing the Swagger UI at /openapi.json
+func SwaggerInitializer() []byte {
+	return []byte(swaggerInitializer)
+}
@@ -0,0 +1,211 @@
+package docs_test
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/internal/docs"
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/handlers"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/routers"
+	"bone_appetit_r4_service/pkg/health"
+	"bone_appetit_r4_service/pkg/ipfy"
+	"bone_appetit_r4_service/pkg/middleware"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	data, err := docs.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		t.Fatalf("loading the document: %v", err)
+	}
+	return doc
+}
+
+func TestSpecIsValid(t *testing.T) {
+	if err := loadSpec(t).Validate(context.Background()); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+}
+
+// ginParam matches the :name and *name segments of gin routes
+var ginParam = regexp.MustCompile(`[:*](\w+)`)
+
+// routes registers every router the service mounts, with tokens set so optional routes are
+// included. The /backoffice pages are a UI rather than part of the API and are left out.
+func routes(t *testing.T) []string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	docsHandler, err := handlers.NewDocsHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := middleware.NewWebhookAuthMiddleware("test", "secret", "token")
+	routers.NewHealthRouter(&handlers.HealthHandler{}).SetRouter(router)
+	routers.NewMetricsRouter("token").SetRouter(router)
+	routers.NewAdminRouter(&handlers.AdminHandler{}, "token").SetRouter(router)
+	routers.NewDocsRouter(docsHandler).SetRouter(router)
+	routers.NewR4Routes(&handlers.R4Handler{}, &handlers.BankHandler{}, &handlers.PaymentHandler{}).SetRouter(router, auth)
+	for _, store := range config.StoreNames {
+		routers.NewV1Routes(store, &handlers.R4Handler{}, &handlers.BankHandler{}, &handlers.PaymentHandler{}).SetRouter(router, auth)
+		routers.NewFeedRoutes(store, &handlers.FeedHandler{}, nil).SetRouter(router)
+	}
+	routers.NewR4AppaRoutes(&handlers.R4Handler{}, &handlers.BankHandler{}, &handlers.PaymentHandler{}).SetRouter(router, auth)
+	routers.NewWebhookRouter(&handlers.WebhookHandler{}).SetRouter(router, auth)
+	routers.NewWebhookAppaRouter(&handlers.WebhookHandler{}).SetRouter(router, auth)
+
+	var got []string
+	for _, route := range router.Routes() {
+		got = append(got, route.Method+" "+ginParam.ReplaceAllString(route.Path, "{$1}"))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestSpecDocumentsEveryRoute(t *testing.T) {
+	doc := loadSpec(t)
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			// The /v1 routes of every store are documented once with a {store} parameter
+			if rest, ok := strings.CutPrefix(path, "/v1/{store}/"); ok {
+				for _, store := range config.StoreNames {
+					documented[method+" /v1/"+store+"/"+rest] = true
+				}
+				continue
+			}
+			documented[method+" "+path] = true
+		}
+	}
+
+	for _, route := range routes(t) {
+		if !documented[route] {
+			t.Errorf("%s is served but not documented", route)
+		}
+		delete(documented, route)
+	}
+	for route := range documented {
+		t.Errorf("%s is documented but not served", route)
+	}
+}
+
+func TestSchemasMatchModels(t *testing.T) {
+	doc := loadSpec(t)
+
+	schemas := map[string]any{
+		"Envelope":                       models.Envelope{},
+		"APIError":                       models.APIError{},
+		"OTPRequest":                     models.OTPRequest{},
+		"ValidateOTPRequest":             models.ValidateOTPRequest{},
+		"ChangePaidRequest":              models.ChangePaidRequest{},
+		"BCVTasaUSDResponse":             models.BCVTasaUSDResponse{},
+		"ValidateDebitInmediateResponse": models.ValidateDebitInmediateResponse{},
+		"ChangePaidResponse":             models.ChangePaidResponse{},
+		"GetOperationResponse":           r4bank.GetOperationResponse{},
+		"BankListResponse":               models.BankListResponse{},
+		"Bank":                           models.Bank{},
+		"PaymentListResponse":            models.PaymentListResponse{},
+		"Payment":                        models.Payment{},
+		"R4ConsultaRequest":              models.R4ConsultaRequest{},
+		"R4NotificaRequest":              models.R4NotificaRequest{},
+		"HealthReport":                   health.Report{},
+		"EgressIP":                       ipfy.Result{},
+		"APIKey":                         models.APIKey{},
+		"CreateAPIKeyRequest":            models.CreateAPIKeyRequest{},
+		"APIKeyListResponse":             models.APIKeyListResponse{},
+		"Subscription":                   models.Subscription{},
+		"CreateSubscriptionRequest":      models.CreateSubscriptionRequest{},
+		"SubscriptionListResponse":       models.SubscriptionListResponse{},
+		"Delivery":                       models.Delivery{},
+		"DeliveryListResponse":           models.DeliveryListResponse{},
+		"DeliveryAttempt":                models.DeliveryAttempt{},
+		"Event":                          events.Event{},
+		"PaymentEvent":                   events.Payment{},
+		"DebitEvent":                     events.Debit{},
+		"ChangeEvent":                    events.Change{},
+	}
+
+	for name, model := range schemas {
+		ref, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		schema := ref.Value
+
+		fields, required := jsonFields(reflect.TypeOf(model))
+		for _, field := range fields {
+			if _, ok := schema.Properties[field]; !ok {
+				t.Errorf("%s.%s is not documented", name, field)
+			}
+		}
+		for property := range schema.Properties {
+			if !contains(fields, property) {
+				t.Errorf("%s.%s is documented but not in the model", name, property)
+			}
+		}
+		// Only requests declare what is required, responses list every field they always send
+		if len(required) > 0 && !sameSet(required, schema.Required) {
+			t.Errorf("%s requires %v, the document says %v", name, required, schema.Required)
+		}
+	}
+}
+
+// jsonFields returns the JSON names of the fields of typ and those bound as required
+func jsonFields(typ reflect.Type) (fields, required []string) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			embedded, embeddedRequired := jsonFields(field.Type)
+			fields, required = append(fields, embedded...), append(required, embeddedRequired...)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+		if strings.Contains(","+field.Tag.Get("binding")+",", ",required,") {
+			required = append(required, name)
+		}
+	}
+	return fields, required
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, item := range a {
+		if !contains(b, item) {
+			return false
+		}
+	}
+	return true
+}
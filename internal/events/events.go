@@ -0,0 +1,108 @@
+// Package events defines the domain events the service tells downstream systems about
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Event types
+const (
+	PaymentReceived = "payment.received"
+	DebitAccepted   = "debit.accepted"
+	DebitRejected   = "debit.rejected"
+	ChangeSent      = "change.sent"
+)
+
+// Types lists every event type the service emits
+var Types = []string{PaymentReceived, DebitAccepted, DebitRejected, ChangeSent}
+
+// Event is something that happened in a store. It is sent as JSON as is, Data
+// holds one of the payloads below depending on Type.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Store      string    `json:"store"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+}
+
+// New creates an event with a new ID
+func New(eventType, store string, data any) Event {
+	return Event{
+		ID:         newID(),
+		Type:       eventType,
+		Store:      store,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// NewOnce creates an event whose ID is derived from key, so the same fact observed
+// twice, e.g. by two polls of a debit, is the same event and stored once
+func NewOnce(eventType, store, key string, data any) Event {
+	event := New(eventType, store, data)
+	sum := sha256.Sum256([]byte(store + "/" + eventType + "/" + key))
+	event.ID = "evt_" + hex.EncodeToString(sum[:16])
+	return event
+}
+
+// Decode reads an event encoded as JSON, keeping its data as is
+func Decode(payload []byte) (Event, error) {
+	var data json.RawMessage
+	event := Event{Data: &data}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// Publisher hands events over to whatever delivers them
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Discard is a Publisher dropping every event
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(context.Context, Event) error { return nil }
+
+// Payment is the data of payment.received: a pago móvil notified by R4
+type Payment struct {
+	Reference     string    `json:"reference"`
+	Amount        float64   `json:"amount"`
+	SenderPhone   string    `json:"senderPhone"`
+	IssuingBank   string    `json:"issuingBank"`
+	CommercePhone string    `json:"commercePhone"`
+	Date          time.Time `json:"date"`
+}
+
+// Debit is the data of debit.accepted and debit.rejected
+type Debit struct {
+	OperationID string  `json:"operationId"`
+	Code        string  `json:"code"`
+	Message     string  `json:"message"`
+	Reference   string  `json:"reference"`
+	Amount      float64 `json:"amount"`
+	Bank        string  `json:"bank"`
+}
+
+// Change is the data of change.sent: a vuelto paid to a customer
+type Change struct {
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Bank      string  `json:"bank"`
+	Phone     string  `json:"phone"`
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,351 @@
+// Package feed pushes the payments and debit outcomes of a store to the cashiers watching
+// it live. Every instance LISTENs for the events written to the outbox, so a cashier sees
+// a payment whichever instance R4 notified.
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/events"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/metrics"
+)
+
+// Channel is where the outbox trigger notifies the ID of every new event
+const Channel = "outbox_events"
+
+// pingInterval is how often the listening connection is checked, a connection dropped
+// silently by a proxy or firewall is otherwise only noticed on the next notification
+const pingInterval = time.Minute
+
+// replayWindow is how many IDs below the last event seen are read again. An outbox ID
+// is taken when the event is written but only visible once its transaction commits, so
+// an event committing late shows up below IDs already sent.
+const replayWindow = 100
+
+// Types lists the events sent to the feed
+var Types = []string{events.PaymentReceived, events.DebitAccepted, events.DebitRejected}
+
+// ErrClosed is returned when subscribing to a hub that stopped
+var ErrClosed = errors.New("feed closed")
+
+// Item is an event sent to the feed. Seq is its outbox ID, which feeds send as the
+// event ID so clients can resume after it.
+type Item struct {
+	Seq  int64
+	Type string
+	// Body is the event encoded as JSON
+	Body []byte
+
+	store  string
+	branch string
+}
+
+// Filter selects the events of a subscriber. A branch is identified by the commerce
+// phone its customers pay to, it only receives the payments made to that phone.
+type Filter struct {
+	Store  string
+	Branch string
+}
+
+func (f Filter) match(item Item) bool {
+	if item.store != f.Store {
+		return false
+	}
+	return f.Branch == "" || item.branch == f.Branch
+}
+
+// Config controls the live feed
+type Config struct {
+	// Buffer is how many events a subscriber can fall behind before it is dropped
+	Buffer int
+	// ReplayLimit bounds how many missed events are sent to a feed resuming
+	ReplayLimit int
+	// ReconnectBackoff is the wait before listening again after the connection broke,
+	// doubled after every failed attempt up to a minute
+	ReconnectBackoff time.Duration
+}
+
+// Hub fans the outbox events out to the subscribers of this instance
+type Hub struct {
+	db     *gorm.DB
+	dsn    string
+	cfg    Config
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	// last is the newest outbox ID dispatched, to catch up after a reconnection
+	last int64
+	// first is the newest outbox ID when the hub started, older events are only replayed
+	first int64
+	// sent holds the IDs dispatched within replayWindow of last, so catching up does not
+	// send them twice
+	sent map[int64]struct{}
+}
+
+// NewHub creates a Hub reading the outbox from db. It LISTENs on a connection of its
+// own to dsn, outside the pool of db.
+func NewHub(db *gorm.DB, dsn string, cfg Config, logger *zap.Logger) *Hub {
+	return &Hub{db: db, dsn: dsn, cfg: cfg, logger: logger, subs: make(map[*Subscription]struct{}), sent: make(map[int64]struct{})}
+}
+
+// Subscription receives the events matching its filter on C. C is closed when the
+// hub stops or when the subscriber fell too far behind, it should then resume with
+// the last Seq it received.
+type Subscription struct {
+	C      <-chan Item
+	c      chan Item
+	filter Filter
+	hub    *Hub
+}
+
+// Subscribe starts receiving the events matching filter
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	c := make(chan Item, h.cfg.Buffer)
+	sub := &Subscription{C: c, c: c, filter: filter, hub: h}
+	h.subs[sub] = struct{}{}
+	metrics.FeedSubscribers.WithLabelValues(filter.Store).Inc()
+	return sub, nil
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove closes sub, h.mu must be held
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.c)
+	metrics.FeedSubscribers.WithLabelValues(sub.filter.Store).Dec()
+}
+
+// Replay returns the events matching filter written after seq, oldest first. The events
+// within replayWindow below seq are returned again, as one of them may have committed
+// after seq was sent, so clients drop the Seq they already have. Only the newest
+// ReplayLimit are returned to a feed that was away for long.
+func (h *Hub) Replay(ctx context.Context, filter Filter, seq int64) ([]Item, error) {
+	query := h.db.WithContext(ctx).
+		Where("store = ? AND event_type IN ? AND id > ?", filter.Store, Types, max(seq-replayWindow, 0))
+	if filter.Branch != "" {
+		query = query.Where("event_type = ? AND payload->'data'->>'commercePhone' = ?", events.PaymentReceived, filter.Branch)
+	}
+
+	var rows []dbModels.OutboxEvent
+	if err := query.Order("id DESC").Limit(h.cfg.ReplayLimit).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("reading missed events: %w", err)
+	}
+
+	items := make([]Item, 0, len(rows))
+	for _, row := range slices.Backward(rows) {
+		item, err := toItem(row)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Run listens for new outbox events until ctx is done, reconnecting when the
+// connection breaks. The subscriptions are closed when it returns.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.close()
+
+	if err := h.db.WithContext(ctx).Model(&dbModels.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&h.last).Error; err != nil {
+		h.logger.Warn("could not read the newest outbox event", zap.Error(err))
+	}
+	h.first = h.last
+
+	listener := pq.NewListener(h.dsn, h.cfg.ReconnectBackoff, max(h.cfg.ReconnectBackoff, time.Minute), h.logEvent)
+	defer listener.Close()
+	// Listen blocks until connected, closing the listener releases it on shutdown
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	if err := listener.Listen(Channel); err != nil {
+		if ctx.Err() == nil {
+			h.logger.Error("live feed could not listen for outbox events", zap.Error(err))
+		}
+		return
+	}
+	// Events written before LISTEN took effect were not notified
+	h.catchUp(ctx)
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			go func() { _ = listener.Ping() }()
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			// nil is sent after reconnecting, events written meanwhile were not notified
+			if notification == nil {
+				h.catchUp(ctx)
+				continue
+			}
+			seq, err := strconv.ParseInt(notification.Extra, 10, 64)
+			if err != nil {
+				h.logger.Warn("invalid outbox notification", zap.String("payload", notification.Extra))
+				continue
+			}
+			if err := h.dispatchSeq(ctx, seq); err != nil {
+				h.logger.Error("could not send outbox event to the live feed", zap.Error(err), zap.Int64("seq", seq))
+			}
+		}
+	}
+}
+
+// logEvent reports the connection problems of the listener, which reconnects by itself
+func (h *Hub) logEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		h.logger.Warn("live feed stopped listening, reconnecting", zap.Error(err))
+	case pq.ListenerEventConnectionAttemptFailed:
+		h.logger.Warn("live feed could not reconnect", zap.Error(err))
+	case pq.ListenerEventReconnected:
+		h.logger.Info("live feed listening again")
+	}
+}
+
+// catchUp dispatches the events written after the last one dispatched, and those
+// committed late within replayWindow below it, a page of ReplayLimit at a time
+func (h *Hub) catchUp(ctx context.Context) {
+	h.mu.Lock()
+	after := max(h.last-replayWindow, h.first)
+	h.mu.Unlock()
+
+	for {
+		var rows []dbModels.OutboxEvent
+		err := h.db.WithContext(ctx).
+			Where("id > ? AND event_type IN ?", after, Types).
+			Order("id").
+			Limit(h.cfg.ReplayLimit).
+			Find(&rows).Error
+		if err != nil {
+			h.logger.Error("could not catch up with the outbox", zap.Error(err))
+			return
+		}
+		for _, row := range rows {
+			h.dispatchRow(row)
+		}
+		if len(rows) < h.cfg.ReplayLimit {
+			return
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+func (h *Hub) dispatchSeq(ctx context.Context, seq int64) error {
+	var row dbModels.OutboxEvent
+	err := h.db.WithContext(ctx).Where("id = ?", seq).Take(&row).Error
+	if err != nil {
+		return err
+	}
+	h.dispatchRow(row)
+	return nil
+}
+
+// dispatchRow sends row to the matching subscribers. A subscriber whose buffer is full
+// is dropped rather than holding the others back, it resumes from its last event.
+func (h *Hub) dispatchRow(row dbModels.OutboxEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.sent[row.ID]; ok {
+		return
+	}
+	h.markSent(row.ID)
+
+	if !slices.Contains(Types, row.EventType) {
+		return
+	}
+	item, err := toItem(row)
+	if err != nil {
+		h.logger.Error("could not decode outbox event", zap.Error(err), zap.Int64("seq", row.ID))
+		return
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.match(item) {
+			continue
+		}
+		select {
+		case sub.c <- item:
+		default:
+			h.logger.Warn("live feed subscriber too slow, dropping it", zap.String("store", sub.filter.Store))
+			h.remove(sub)
+		}
+	}
+}
+
+// markSent records id as dispatched, forgetting the IDs too old to be caught up again.
+// h.mu must be held.
+func (h *Hub) markSent(id int64) {
+	h.sent[id] = struct{}{}
+	if id <= h.last {
+		return
+	}
+	h.last = id
+	if len(h.sent) <= 2*replayWindow {
+		return
+	}
+	for seq := range h.sent {
+		if seq <= h.last-replayWindow {
+			delete(h.sent, seq)
+		}
+	}
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+func toItem(row dbModels.OutboxEvent) (Item, error) {
+	item := Item{Seq: row.ID, Type: row.EventType, Body: row.Payload, store: row.Store}
+	if row.EventType != events.PaymentReceived {
+		return item, nil
+	}
+
+	var payment struct {
+		Data events.Payment `json:"data"`
+	}
+	if err := json.Unmarshal(row.Payload, &payment); err != nil {
+		return Item{}, fmt.Errorf("decoding event %s: %w", row.EventID, err)
+	}
+	item.branch = payment.Data.CommercePhone
+	return item, nil
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/ipfy"
+	"bone_appetit_r4_service/pkg/logs"
+)
+
+type AdminHandler struct {
+	egress        *ipfy.Resolver
+	apiKeys       services.APIKeyService
+	subscriptions services.SubscriptionService
+}
+
+func NewAdminHandler(egress *ipfy.Resolver, apiKeys services.APIKeyService, subscriptions services.SubscriptionService) *AdminHandler {
+	return &AdminHandler{egress: egress, apiKeys: apiKeys, subscriptions: subscriptions}
+}
+
+// HandleEgressIP reports the public IP the service reaches R4 from and whether it is registered.
+// ?refresh=true bypasses the cache.
+func (h *AdminHandler) HandleEgressIP(c *gin.Context) {
+	ctx := c.Request.Context()
+	lookup := h.egress.Lookup
+	if c.Query("refresh") == "true" {
+		lookup = h.egress.Refresh
+	}
+
+	result, err := lookup(ctx)
+	if err != nil {
+		logs.FromContext(ctx).Warn("egress IP lookup failed", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleCreateAPIKey issues an API key for a store. The key is only returned in this response.
+func (h *AdminHandler) HandleCreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidPayload(c, Legacy, err)
+		return
+	}
+
+	key, err := h.apiKeys.Create(c, req.Name, req.Store)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("API key created", zap.Int("api_key_id", key.ID), zap.String("store", key.Store), zap.String("name", key.Name))
+	c.JSON(http.StatusCreated, key)
+}
+
+// HandleListAPIKeys lists the API keys, revoked ones included
+func (h *AdminHandler) HandleListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeys.List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIKeyListResponse{Keys: keys})
+}
+
+// HandleRevokeAPIKey revokes an API key, calls using it are rejected from then on
+func (h *AdminHandler) HandleRevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key ID must be a number"})
+		return
+	}
+
+	if err := h.apiKeys.Revoke(c, id); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("API key revoked", zap.Int("api_key_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+// HandleCreateSubscription subscribes an endpoint to the events of a store. The signing
+// secret is only returned in this response.
+func (h *AdminHandler) HandleCreateSubscription(c *gin.Context) {
+	var req models.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidPayload(c, Legacy, err)
+		return
+	}
+
+	subscription, err := h.subscriptions.Create(c, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("subscription created", zap.Int("subscription_id", subscription.ID), zap.String("store", subscription.Store), zap.String("url", subscription.URL))
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// HandleListSubscriptions lists the subscriptions, of one store with ?store=
+func (h *AdminHandler) HandleListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.subscriptions.List(c, c.Query("store"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SubscriptionListResponse{Subscriptions: subscriptions})
+}
+
+// HandleDisableSubscription stops sending events to a subscription
+func (h *AdminHandler) HandleDisableSubscription(c *gin.Context) {
+	h.setSubscriptionDisabled(c, true)
+}
+
+// HandleEnableSubscription resumes sending events to a subscription, pending deliveries included
+func (h *AdminHandler) HandleEnableSubscription(c *gin.Context) {
+	h.setSubscriptionDisabled(c, false)
+}
+
+func (h *AdminHandler) setSubscriptionDisabled(c *gin.Context, disabled bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subscription ID must be a number"})
+		return
+	}
+
+	if err := h.subscriptions.SetDisabled(c, id, disabled); err != nil {
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("subscription updated", zap.Int("subscription_id", id), zap.Bool("disabled", disabled))
+	c.Status(http.StatusNoContent)
+}
+
+// HandleListDeliveries lists the latest deliveries of a subscription, of one status with ?status=
+func (h *AdminHandler) HandleListDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subscription ID must be a number"})
+		return
+	}
+
+	deliveries, err := h.subscriptions.Deliveries(c, id, c.Query("status"))
+	if err != nil {
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DeliveryListResponse{Deliveries: deliveries})
+}
+
+// HandleGetDelivery returns a delivery with its payload and the log of its attempts
+func (h *AdminHandler) HandleGetDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delivery ID must be a number"})
+		return
+	}
+
+	delivery, err := h.subscriptions.Delivery(c, id)
+	if err != nil {
+		if errors.Is(err, services.ErrDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// HandleRetryDelivery sends a delivery again as soon as possible, failed ones included
+func (h *AdminHandler) HandleRetryDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delivery ID must be a number"})
+		return
+	}
+
+	if err := h.subscriptions.Retry(c, id); err != nil {
+		if errors.Is(err, services.ErrDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("delivery retry requested", zap.Int64("delivery_id", id))
+	c.Status(http.StatusAccepted)
+}
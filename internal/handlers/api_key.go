@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/logs"
+)
+
+// APIKeyHeader carries the API key of the caller
+const APIKeyHeader = "X-API-Key"
+
+// apiClientKey is where RequireAPIKey stores the caller's key in the gin context
+const apiClientKey = "api_client"
+
+// RequireAPIKey authenticates the requests with an API key issued for store, sent in the
+// X-API-Key header. Browsers' EventSource cannot set headers, so the api_key query
+// parameter is accepted too.
+func RequireAPIKey(apiKeys services.APIKeyService, store string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			key = c.Query("api_key")
+		}
+		if key == "" {
+			V1.Fail(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "missing "+APIKeyHeader+" header", nil)
+			return
+		}
+
+		apiKey, err := apiKeys.Authenticate(c.Request.Context(), strings.TrimSpace(key))
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			V1.Fail(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "invalid API key", nil)
+			return
+		}
+		if err != nil {
+			logs.FromContext(c.Request.Context()).Error("could not verify API key", zap.Error(err))
+			V1.Fail(c, http.StatusInternalServerError, models.ErrorCodeInternal, "could not verify the API key", nil)
+			return
+		}
+		if apiKey.Store != store {
+			V1.Fail(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "the API key was not issued for "+store, nil)
+			return
+		}
+
+		c.Set(apiClientKey, apiKey)
+		c.Next()
+	}
+}
+
+// APIClient returns the API key the request was authenticated with by RequireAPIKey
+func APIClient(c *gin.Context) *models.APIKey {
+	value, _ := c.Get(apiClientKey)
+	key, _ := value.(*models.APIKey)
+	return key
+}
@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/backoffice"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/logs"
+)
+
+const (
+	// BackofficeCookie holds the session token of a signed-in back-office user
+	BackofficeCookie = "backoffice_session"
+	// backofficeSessionKey is where RequireSession stores the session in the gin context
+	backofficeSessionKey = "backoffice_session"
+	backofficePath       = "/backoffice"
+)
+
+// errMissingReference is returned when a payout is resolved as paid without its reference
+var errMissingReference = errors.New("paid payout without reference")
+
+// payoutStatuses are the statuses the payouts page filters on, pending first
+var payoutStatuses = []string{
+	dbModels.PayoutPending, dbModels.PayoutPaying, dbModels.PayoutPaid, dbModels.PayoutFailed, dbModels.PayoutRejected,
+}
+
+// BackofficeServices are the services the back office works with, the same ones
+// behind the API
+type BackofficeServices struct {
+	Users      services.BackofficeUserService
+	Payments   services.PaymentService
+	Operations services.OperationService
+	Payouts    services.ChangePayoutService
+	// R4 holds the R4 service of each store, keyed by store name
+	R4 map[string]services.R4Service
+}
+
+type BackofficeHandler struct {
+	pages          *backoffice.Pages
+	svc            BackofficeServices
+	stores         []string
+	insecureCookie bool
+}
+
+// NewBackofficeHandler creates the handler serving the back-office pages of stores.
+// insecureCookie lets the session cookie travel over plain HTTP, for local development.
+func NewBackofficeHandler(pages *backoffice.Pages, svc BackofficeServices, stores []string, insecureCookie bool) *BackofficeHandler {
+	return &BackofficeHandler{pages: pages, svc: svc, stores: stores, insecureCookie: insecureCookie}
+}
+
+// backofficePage is what every page is rendered with, Data holds the page's own content
+type backofficePage struct {
+	Title   string
+	Nav     string
+	Session *models.BackofficeSession
+	Stores  []string
+	Store   string
+	Notice  string
+	Error   string
+	Data    any
+}
+
+type paymentsPage struct {
+	Query     models.PaymentQuery
+	Payments  []models.Payment
+	ExportURL string
+	NextURL   string
+	// Back is the query the page was opened with, to return to it after linking an order
+	Back string
+}
+
+type operationsPage struct {
+	Query      models.OperationQuery
+	Operations []models.Operation
+}
+
+type payoutsPage struct {
+	Status    string
+	Statuses  []string
+	Payouts   []models.ChangePayout
+	CanDecide bool
+}
+
+// notices and failures are the outcomes the actions redirect with, shown on the next
+// page. Only their keys travel in the URL, so links cannot put text on the pages.
+var (
+	notices = map[string]string{
+		"linked":    "Pago vinculado al pedido.",
+		"unlinked":  "Pago desvinculado del pedido.",
+		"refreshed": "Estado consultado en R4.",
+		"approved":  "Vuelto aprobado y pagado.",
+		"rejected":  "Vuelto rechazado.",
+		"resolved":  "Vuelto resuelto.",
+	}
+	failures = map[string]string{
+		"payment_not_found": "El pago no existe.",
+		"invalid_order":     "El pedido debe ser un número.",
+		"order_linked":      "El pago ya está vinculado a otro pedido, desvincúlalo primero.",
+		"link_failed":       "No se pudo vincular el pago.",
+		"r4_unavailable":    "R4 no respondió, intenta de nuevo en unos minutos.",
+		"payout_not_found":  "El vuelto no existe.",
+		"payout_decided":    "El vuelto ya fue decidido por otra persona.",
+		"payout_failed":     "No se pudo pagar el vuelto, quedó como fallido.",
+		"payout_not_stuck":  "Solo se resuelven a mano los vueltos que llevan más de 10 minutos pagándose.",
+		"payout_reference":  "Indica la referencia del vuelto en el estado de cuenta para marcarlo como pagado.",
+	}
+)
+
+// SecurityHeaders keeps the pages from being framed or loading anything from elsewhere
+func (h *BackofficeHandler) SecurityHeaders(c *gin.Context) {
+	c.Header("Content-Security-Policy", "default-src 'self'; form-action 'self'; frame-ancestors 'none'")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "same-origin")
+	c.Header("Cache-Control", "no-store")
+	c.Next()
+}
+
+// RequireSession sends the users without a session to the login page. Forms posted
+// must carry the CSRF token of the session.
+func (h *BackofficeHandler) RequireSession(c *gin.Context) {
+	token, err := c.Cookie(BackofficeCookie)
+	if err != nil || token == "" {
+		c.Redirect(http.StatusSeeOther, backofficePath+"/login")
+		c.Abort()
+		return
+	}
+
+	session, err := h.svc.Users.Session(c.Request.Context(), token)
+	if errors.Is(err, services.ErrSessionExpired) {
+		h.clearCookie(c)
+		c.Redirect(http.StatusSeeOther, backofficePath+"/login")
+		c.Abort()
+		return
+	}
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not load back-office session", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if c.Request.Method == http.MethodPost {
+		csrfToken := c.PostForm("csrf_token")
+		if subtle.ConstantTimeCompare([]byte(csrfToken), []byte(session.CSRFToken)) != 1 {
+			c.String(http.StatusForbidden, "El formulario expiró, vuelve a cargar la página.")
+			c.Abort()
+			return
+		}
+	}
+
+	c.Set(backofficeSessionKey, session)
+	c.Next()
+}
+
+// HandleLoginPage shows the login form
+func (h *BackofficeHandler) HandleLoginPage(c *gin.Context) {
+	h.render(c, http.StatusOK, "login", backofficePage{Title: "Iniciar sesión"})
+}
+
+// HandleLogin signs a user in and sends them to the payments
+func (h *BackofficeHandler) HandleLogin(c *gin.Context) {
+	email := c.PostForm("email")
+	session, err := h.svc.Users.Login(c.Request.Context(), email, c.PostForm("password"))
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		logs.FromContext(c.Request.Context()).Warn("back-office login failed", zap.String("email", email))
+		h.render(c, http.StatusUnauthorized, "login", backofficePage{Title: "Iniciar sesión", Error: "Correo o contraseña incorrectos.", Data: email})
+		return
+	}
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not sign in back-office user", zap.Error(err))
+		h.render(c, http.StatusInternalServerError, "login", backofficePage{Title: "Iniciar sesión", Error: "No se pudo iniciar sesión, intenta de nuevo.", Data: email})
+		return
+	}
+
+	logs.FromContext(c.Request.Context()).Info("back-office user signed in", zap.Int("user_id", session.User.ID), zap.String("email", session.User.Email))
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     BackofficeCookie,
+		Value:    session.Token,
+		Path:     backofficePath,
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   !h.insecureCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+	c.Redirect(http.StatusSeeOther, backofficePath+"/payments")
+}
+
+// HandleLogout closes the session
+func (h *BackofficeHandler) HandleLogout(c *gin.Context) {
+	session := backofficeSession(c)
+	if err := h.svc.Users.Logout(c.Request.Context(), session.Token); err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not close back-office session", zap.Error(err))
+	}
+	h.clearCookie(c)
+	c.Redirect(http.StatusSeeOther, backofficePath+"/login")
+}
+
+// HandleHome sends the users to the payments, the page they use the most
+func (h *BackofficeHandler) HandleHome(c *gin.Context) {
+	c.Redirect(http.StatusSeeOther, backofficePath+"/payments")
+}
+
+// HandlePayments searches the payments received by a store
+func (h *BackofficeHandler) HandlePayments(c *gin.Context) {
+	page := h.page(c, "Pagos", "payments")
+	data := paymentsPage{Back: c.Request.URL.RawQuery}
+	page.Data = &data
+	if err := c.ShouldBindQuery(&data.Query); err != nil {
+		page.Error = "Los filtros no son válidos, revisa las fechas."
+		h.render(c, http.StatusBadRequest, "payments", page)
+		return
+	}
+
+	resp, err := h.svc.Payments.FindPayments(c.Request.Context(), page.Store, &data.Query)
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not search payments", zap.Error(err))
+		page.Error = "No se pudieron buscar los pagos."
+		h.render(c, http.StatusInternalServerError, "payments", page)
+		return
+	}
+	data.Payments = resp.Payments
+
+	query := c.Request.URL.Query()
+	query.Set("store", page.Store)
+	query.Del("offset")
+	query.Del("limit")
+	data.ExportURL = backofficePath + "/payments/export?" + query.Encode()
+	if len(resp.Payments) == resp.Limit {
+		query.Set("offset", strconv.Itoa(resp.Offset+resp.Limit))
+		query.Set("limit", strconv.Itoa(resp.Limit))
+		data.NextURL = backofficePath + "/payments?" + query.Encode()
+	}
+
+	h.render(c, http.StatusOK, "payments", page)
+}
+
+// HandleExportPayments downloads the payments matching the search as CSV
+func (h *BackofficeHandler) HandleExportPayments(c *gin.Context) {
+	store := h.store(c.Query("store"))
+	var query models.PaymentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, "Los filtros no son válidos.")
+		return
+	}
+
+	session := backofficeSession(c)
+	logs.FromContext(c.Request.Context()).Info("back-office payments export", zap.String("store", store), zap.String("email", session.User.Email))
+
+	filename := fmt.Sprintf("%s-payments-%s.csv", store, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := h.svc.Payments.ExportPayments(c.Request.Context(), store, &query, c.Writer); err != nil {
+		// Headers may already be flushed, abort so the client sees a truncated download
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// HandleLinkOrder links a payment to the order it paid, an empty order unlinks it
+func (h *BackofficeHandler) HandleLinkOrder(c *gin.Context) {
+	store := h.store(c.Param("store"))
+	back, _ := url.ParseQuery(c.PostForm("back"))
+	back.Set("store", store)
+	redirect := func(outcome, key string) {
+		back.Del("notice")
+		back.Del("error")
+		back.Set(outcome, key)
+		c.Redirect(http.StatusSeeOther, backofficePath+"/payments?"+back.Encode())
+	}
+
+	paymentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		redirect("error", "payment_not_found")
+		return
+	}
+	var orderID *int
+	if value := strings.TrimSpace(c.PostForm("order_id")); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			redirect("error", "invalid_order")
+			return
+		}
+		orderID = &id
+	}
+
+	err = h.svc.Payments.LinkOrder(c.Request.Context(), store, paymentID, orderID)
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound):
+		redirect("error", "payment_not_found")
+		return
+	case errors.Is(err, services.ErrOrderAlreadyLinked):
+		redirect("error", "order_linked")
+		return
+	case err != nil:
+		logs.FromContext(c.Request.Context()).Error("could not link payment to order", zap.Error(err), zap.Int("payment_id", paymentID))
+		redirect("error", "link_failed")
+		return
+	}
+
+	session := backofficeSession(c)
+	logs.FromContext(c.Request.Context()).Info("payment linked to order", zap.String("store", store), zap.Int("payment_id", paymentID), zap.Any("order_id", orderID), zap.String("email", session.User.Email))
+	if orderID == nil {
+		redirect("notice", "unlinked")
+		return
+	}
+	redirect("notice", "linked")
+}
+
+// HandleOperations searches the immediate debits of a store
+func (h *BackofficeHandler) HandleOperations(c *gin.Context) {
+	page := h.page(c, "Débitos", "operations")
+	data := operationsPage{}
+	page.Data = &data
+	if err := c.ShouldBindQuery(&data.Query); err != nil {
+		page.Error = "Los filtros no son válidos."
+		h.render(c, http.StatusBadRequest, "operations", page)
+		return
+	}
+
+	operations, err := h.svc.Operations.List(c.Request.Context(), page.Store, &data.Query)
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not list debit operations", zap.Error(err))
+		page.Error = "No se pudieron buscar los débitos."
+		h.render(c, http.StatusInternalServerError, "operations", page)
+		return
+	}
+	data.Operations = operations
+
+	h.render(c, http.StatusOK, "operations", page)
+}
+
+// HandleOperation shows a debit with every status R4 reported for it
+func (h *BackofficeHandler) HandleOperation(c *gin.Context) {
+	page := h.page(c, "Débito", "operations")
+	page.Store = h.store(c.Param("store"))
+
+	detail, err := h.svc.Operations.Get(c.Request.Context(), page.Store, c.Param("id"))
+	if errors.Is(err, services.ErrOperationNotFound) {
+		page.Error = "El débito no existe."
+		h.render(c, http.StatusNotFound, "operation", page)
+		return
+	}
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not read debit operation", zap.Error(err))
+		page.Error = "No se pudo leer el débito."
+		h.render(c, http.StatusInternalServerError, "operation", page)
+		return
+	}
+	page.Data = detail
+
+	h.render(c, http.StatusOK, "operation", page)
+}
+
+// HandleRefreshOperation asks R4 for the status of a debit, which records it if it changed
+func (h *BackofficeHandler) HandleRefreshOperation(c *gin.Context) {
+	store := h.store(c.Param("store"))
+	operationID := c.Param("id")
+	target := backofficePath + "/operations/" + url.PathEscape(store) + "/" + url.PathEscape(operationID)
+
+	if _, err := h.svc.R4[store].GetOperationByID(c.Request.Context(), operationID); err != nil {
+		c.Redirect(http.StatusSeeOther, target+"?error=r4_unavailable")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, target+"?notice=refreshed")
+}
+
+// HandlePayouts lists the change payouts of a store, the pending ones by default
+func (h *BackofficeHandler) HandlePayouts(c *gin.Context) {
+	page := h.page(c, "Vueltos", "payouts")
+	session := backofficeSession(c)
+	data := payoutsPage{
+		Status:    c.DefaultQuery("status", dbModels.PayoutPending),
+		Statuses:  payoutStatuses,
+		CanDecide: session.User.Role == dbModels.RoleFinance,
+	}
+	if !slices.Contains(payoutStatuses, data.Status) {
+		data.Status = ""
+	}
+	page.Data = &data
+
+	payouts, err := h.svc.Payouts.List(c.Request.Context(), page.Store, data.Status)
+	if err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not list change payouts", zap.Error(err))
+		page.Error = "No se pudieron leer los vueltos."
+		h.render(c, http.StatusInternalServerError, "payouts", page)
+		return
+	}
+	data.Payouts = payouts
+
+	h.render(c, http.StatusOK, "payouts", page)
+}
+
+// HandleApprovePayout pays a change payout held for approval
+func (h *BackofficeHandler) HandleApprovePayout(c *gin.Context) {
+	h.decidePayout(c, "approved", func(store string, id int64, user string) error {
+		_, err := h.svc.R4[store].ApproveChange(c.Request.Context(), id, user)
+		return err
+	})
+}
+
+// HandleRejectPayout refuses a change payout held for approval, it is never paid
+func (h *BackofficeHandler) HandleRejectPayout(c *gin.Context) {
+	h.decidePayout(c, "rejected", func(store string, id int64, user string) error {
+		return h.svc.Payouts.Reject(c.Request.Context(), store, id, user)
+	})
+}
+
+// HandleResolvePayout records by hand the outcome of a payout left paying, as found in
+// the bank statement: paid with its reference, or failed
+func (h *BackofficeHandler) HandleResolvePayout(c *gin.Context) {
+	h.decidePayout(c, "resolved", func(store string, id int64, user string) error {
+		reference := strings.TrimSpace(c.PostForm("reference"))
+		switch c.PostForm("outcome") {
+		case dbModels.PayoutPaid:
+			if reference == "" {
+				return errMissingReference
+			}
+		case dbModels.PayoutFailed:
+			reference = ""
+		default:
+			return errMissingReference
+		}
+		return h.svc.Payouts.Resolve(c.Request.Context(), store, id, user, reference)
+	})
+}
+
+// decidePayout runs decide on the payout of the request if the user is from finance
+func (h *BackofficeHandler) decidePayout(c *gin.Context, notice string, decide func(store string, id int64, user string) error) {
+	store := h.store(c.Param("store"))
+	session := backofficeSession(c)
+	target := backofficePath + "/payouts?store=" + url.QueryEscape(store) + "&"
+
+	if session.User.Role != dbModels.RoleFinance {
+		c.String(http.StatusForbidden, "Solo el equipo de finanzas puede aprobar o rechazar vueltos.")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, target+"error=payout_not_found")
+		return
+	}
+
+	err = decide(store, id, session.User.Email)
+	logger := logs.FromContext(c.Request.Context()).With(zap.String("store", store), zap.Int64("payout_id", id), zap.String("email", session.User.Email))
+	switch {
+	case errors.Is(err, services.ErrPayoutNotFound):
+		c.Redirect(http.StatusSeeOther, target+"error=payout_not_found")
+	case errors.Is(err, services.ErrPayoutNotPending):
+		c.Redirect(http.StatusSeeOther, target+"error=payout_decided")
+	case errors.Is(err, services.ErrPayoutNotStuck):
+		c.Redirect(http.StatusSeeOther, target+"status=paying&error=payout_not_stuck")
+	case errors.Is(err, errMissingReference):
+		c.Redirect(http.StatusSeeOther, target+"status=paying&error=payout_reference")
+	case err != nil:
+		logger.Error("change payout decision failed", zap.Error(err), zap.String("decision", notice))
+		c.Redirect(http.StatusSeeOther, target+"status=all&error=payout_failed")
+	default:
+		logger.Info("change payout decided", zap.String("decision", notice))
+		c.Redirect(http.StatusSeeOther, target+"notice="+notice)
+	}
+}
+
+// page starts the page of the request, for the store it asks for
+func (h *BackofficeHandler) page(c *gin.Context, title, nav string) backofficePage {
+	page := backofficePage{
+		Title:   title,
+		Nav:     nav,
+		Session: backofficeSession(c),
+		Stores:  h.stores,
+		Store:   h.store(c.Query("store")),
+		Notice:  notices[c.Query("notice")],
+		Error:   failures[c.Query("error")],
+	}
+	return page
+}
+
+// store returns name if it is a known store, the first store otherwise
+func (h *BackofficeHandler) store(name string) string {
+	if slices.Contains(h.stores, name) {
+		return name
+	}
+	return h.stores[0]
+}
+
+func (h *BackofficeHandler) render(c *gin.Context, status int, name string, page backofficePage) {
+	// Rendered first so a template error does not leave half a page
+	var buf bytes.Buffer
+	if err := h.pages.Render(&buf, name, page); err != nil {
+		logs.FromContext(c.Request.Context()).Error("could not render back-office page", zap.Error(err), zap.String("page", name))
+		c.String(http.StatusInternalServerError, "No se pudo mostrar la página.")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+func (h *BackofficeHandler) clearCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     BackofficeCookie,
+		Value:    "",
+		Path:     backofficePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   !h.insecureCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// backofficeSession returns the session loaded by RequireSession
+func backofficeSession(c *gin.Context) *models.BackofficeSession {
+	value, _ := c.Get(backofficeSessionKey)
+	session, _ := value.(*models.BackofficeSession)
+	return session
+}
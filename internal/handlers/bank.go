@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/banks"
+)
+
+type BankHandler struct {
+	catalog *banks.Catalog
+	respond Responder
+}
+
+func NewBankHandler(catalog *banks.Catalog, respond Responder) *BankHandler {
+	return &BankHandler{catalog: catalog, respond: respond}
+}
+
+// HandleListBanks lists the banks of the catalog.
+// With ?immediate_debit=true only banks supporting OTP and immediate debit are returned.
+func (h *BankHandler) HandleListBanks(c *gin.Context) {
+	onlyDebit := c.Query("immediate_debit") == "true"
+
+	list := h.catalog.List()
+	resp := models.BankListResponse{
+		Version: h.catalog.Version,
+		Banks:   make([]models.Bank, 0, len(list)),
+	}
+	for _, b := range list {
+		if onlyDebit && !b.ImmediateDebit {
+			continue
+		}
+		resp.Banks = append(resp.Banks, models.Bank{
+			Code:           b.Code,
+			ShortName:      b.ShortName,
+			Name:           b.Name,
+			ImmediateDebit: b.ImmediateDebit,
+		})
+	}
+
+	h.respond.OK(c, http.StatusOK, resp)
+}
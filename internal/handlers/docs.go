@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+
+	"bone_appetit_r4_service/internal/docs"
+)
+
+type DocsHandler struct {
+	spec []byte
+	ui   http.Handler
+}
+
+// NewDocsHandler creates the handler serving the OpenAPI document and the Swagger UI
+func NewDocsHandler() (*DocsHandler, error) {
+	spec, err := docs.Spec()
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{
+		spec: spec,
+		ui:   http.StripPrefix("/docs", http.FileServer(http.FS(swaggerFiles.FS))),
+	}, nil
+}
+
+// HandleSpec serves the OpenAPI document
+func (h *DocsHandler) HandleSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// HandleUI serves the Swagger UI assets, /docs/ opens the UI on /openapi.json
+func (h *DocsHandler) HandleUI(c *gin.Context) {
+	path := strings.TrimPrefix(c.Param("path"), "/")
+	if path == "swagger-initializer.js" {
+		c.Data(http.StatusOK, "application/javascript", docs.SwaggerInitializer())
+		return
+	}
+
+	if path != "" {
+		if _, err := fs.Stat(swaggerFiles.FS, path); err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	}
+	h.ui.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/feed"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
+type FeedHandler struct {
+	hub       *feed.Hub
+	storeName string
+	heartbeat time.Duration
+}
+
+// NewFeedHandler creates a handler streaming the live feed of a single store
+func NewFeedHandler(hub *feed.Hub, storeName string, heartbeat time.Duration) *FeedHandler {
+	return &FeedHandler{hub: hub, storeName: storeName, heartbeat: heartbeat}
+}
+
+// HandleFeed streams the payments and debit outcomes of the store as server-sent events.
+// A client reconnecting with Last-Event-ID first receives the events it missed.
+func (h *FeedHandler) HandleFeed(c *gin.Context) {
+	filter := feed.Filter{Store: h.storeName, Branch: validation.NormalizePhone(c.Query("branch"))}
+
+	// EventSource sends Last-Event-ID on its own, other clients may use the query
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	var after int64
+	if lastEventID != "" {
+		var err error
+		if after, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || after < 0 {
+			V1.Fail(c, http.StatusBadRequest, models.ErrorCodeInvalidPayload, "invalid Last-Event-ID", nil)
+			return
+		}
+	}
+
+	// Subscribe before replaying so nothing written in between is missed
+	sub, err := h.hub.Subscribe(filter)
+	if err != nil {
+		V1.Fail(c, http.StatusServiceUnavailable, models.ErrorCodeInternal, err.Error(), nil)
+		return
+	}
+	defer sub.Close()
+
+	var missed []feed.Item
+	if lastEventID != "" {
+		if missed, err = h.hub.Replay(c, filter, after); err != nil {
+			logs.FromContext(c.Request.Context()).Error("could not replay the live feed", zap.Error(err))
+			V1.Fail(c, http.StatusInternalServerError, models.ErrorCodeInternal, "could not read the missed events", nil)
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	replayed := make(map[int64]bool, len(missed))
+	for _, item := range missed {
+		if !h.send(c, item) {
+			return
+		}
+		replayed[item.Seq] = true
+	}
+	c.Writer.Flush()
+
+	// Comments keep proxies and load balancers from closing an idle stream
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case item, ok := <-sub.C:
+			if !ok {
+				// Too slow or shutting down, the client resumes from the last event
+				return
+			}
+			if replayed[item.Seq] {
+				continue
+			}
+			if !h.send(c, item) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// send writes item as an event, reporting whether the client is still there
+func (h *FeedHandler) send(c *gin.Context, item feed.Item) bool {
+	_, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", item.Seq, item.Type, item.Body)
+	return err == nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/pkg/health"
+)
+
+type HealthHandler struct {
+	readiness *health.Readiness
+	checker   *health.Checker
+}
+
+func NewHealthHandler(readiness *health.Readiness, checker *health.Checker) *HealthHandler {
+	return &HealthHandler{readiness: readiness, checker: checker}
+}
+
+// HandleHealthz is the legacy probe, it only reflects whether the instance is draining
+func (h *HealthHandler) HandleHealthz(c *gin.Context) {
+	if !h.readiness.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "SHUTTING_DOWN",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+	})
+}
+
+// HandleLive reports that the process is running, without checking dependencies
+func (h *HealthHandler) HandleLive(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// HandleReady reports whether the instance can serve traffic, with a breakdown per dependency
+func (h *HealthHandler) HandleReady(c *gin.Context) {
+	report := h.checker.Ready(c)
+
+	status := http.StatusOK
+	if report.Status == health.StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+)
+
+type PaymentHandler struct {
+	service   services.PaymentService
+	storeName string
+	respond   Responder
+}
+
+// NewPaymentHandler creates a handler serving the payments of a single store
+func NewPaymentHandler(service services.PaymentService, storeName string, respond Responder) *PaymentHandler {
+	return &PaymentHandler{service: service, storeName: storeName, respond: respond}
+}
+
+// HandleFindPayments lists the mobile payments received by the store
+func (h *PaymentHandler) HandleFindPayments(c *gin.Context) {
+	var query models.PaymentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		invalidPayload(c, h.respond, err)
+		return
+	}
+
+	resp, err := h.service.FindPayments(c, h.storeName, &query)
+	if err != nil {
+		h.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error(), nil)
+		return
+	}
+
+	h.respond.OK(c, http.StatusOK, resp)
+}
+
+// HandleExportPayments downloads the mobile payments received by the store as CSV
+func (h *PaymentHandler) HandleExportPayments(c *gin.Context) {
+	var query models.PaymentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		invalidPayload(c, h.respond, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-payments-%s.csv", h.storeName, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := h.service.ExportPayments(c, h.storeName, &query, c.Writer); err != nil {
+		// Headers may already be flushed, abort so the client sees a truncated download
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
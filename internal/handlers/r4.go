@@ -3,97 +3,145 @@ package handlers
 import (
 	"bone_appetit_r4_service/internal/models"
 	"bone_appetit_r4_service/internal/services"
-	"fmt"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/ratelimit"
+	"bone_appetit_r4_service/pkg/validation"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type R4Handler struct {
 	r4Service services.R4Service
+	store     string
+	limiter   *ratelimit.Limiter
+	respond   Responder
 }
 
-func NewR4Handler(r4Service services.R4Service) *R4Handler {
-	return &R4Handler{r4Service: r4Service}
+// NewR4Handler creates the R4 handlers of store. A nil limiter does not limit anything.
+func NewR4Handler(r4Service services.R4Service, store string, limiter *ratelimit.Limiter, respond Responder) *R4Handler {
+	return &R4Handler{r4Service: r4Service, store: store, limiter: limiter, respond: respond}
 }
 
 // GetBCVTasa handles requests to get the BCV exchange rate for USD
 func (p *R4Handler) GetBCVTasa(c *gin.Context) {
 	tasa, err := p.r4Service.GetBCVTasaUSD(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		p.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeR4, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasa)
+	p.respond.OK(c, http.StatusOK, tasa)
 }
 
 // HandleGenerateOTP handles requests to generate an OTP
 func (p *R4Handler) HandleGenerateOTP(c *gin.Context) {
 	var req models.OTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logs.FromContext(c.Request.Context()).Warn("invalid request payload", zap.Error(err))
+		invalidPayload(c, p.respond, err)
+		return
+	}
+	req.Normalize()
+	if p.limited(c, ratelimit.OTPPerClient, ratelimit.OTPPerTarget, req.Phone, req.DNI) {
 		return
 	}
 
 	if err := p.r4Service.GenerateOTP(c, &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		p.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeR4, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "OTP generated successfully"})
+	p.respond.OK(c, http.StatusOK, gin.H{"message": "OTP generated successfully"})
 }
 
 // HandleValidateImmediateDebit handles requests to validate an immediate debit transaction using OTP
 func (p *R4Handler) HandleValidateImmediateDebit(c *gin.Context) {
 	var req models.ValidateOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logs.FromContext(c.Request.Context()).Warn("invalid request payload", zap.Error(err))
+		invalidPayload(c, p.respond, err)
 		return
 	}
+	req.Normalize()
 
 	resp, err := p.r4Service.ValidateImmediateDebit(c, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		p.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeR4, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	p.respond.OK(c, http.StatusOK, resp)
 }
 
 // HandleChangePaid handles requests to change paid in Bolivares
 func (p *R4Handler) HandleChangePaid(c *gin.Context) {
 	var req models.ChangePaidRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logs.FromContext(c.Request.Context()).Warn("invalid request payload", zap.Error(err))
+		invalidPayload(c, p.respond, err)
+		return
+	}
+	req.Normalize()
+	if p.limited(c, ratelimit.ChangePerClient, ratelimit.ChangePerTarget, req.Phone, req.DNI) {
 		return
 	}
 
 	resp, err := p.r4Service.ChangePaid(c, &req)
 	if err != nil {
-		fmt.Printf("Error processing ChangePaid request: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logs.FromContext(c.Request.Context()).Error("could not process change payout", zap.Error(err))
+		p.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeR4, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	// Held for approval, the payout is neither paid nor refused yet
+	if resp.Status == models.ChangeStatusPending {
+		p.respond.OK(c, http.StatusAccepted, resp)
+		return
+	}
+	p.respond.OK(c, http.StatusOK, resp)
 }
 
 func (p *R4Handler) HandleGetOperationByID(c *gin.Context) {
 	operationID := c.Param("id")
 	if operationID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Operation ID is required"})
+		p.respond.Fail(c, http.StatusBadRequest, models.ErrorCodeInvalidPayload, "Operation ID is required", nil)
 		return
 	}
 
 	resp, err := p.r4Service.GetOperationByID(c, operationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		p.respond.Fail(c, http.StatusInternalServerError, models.ErrorCodeR4, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	p.respond.OK(c, http.StatusOK, resp)
+}
+
+// limited takes a token for the caller, its API key or else the store, and for the
+// customer's phone and cédula, answering 429 when one of them is over its limit
+func (p *R4Handler) limited(c *gin.Context, clientRule, targetRule, phone, dni string) bool {
+	client := "store:" + p.store
+	if key := APIClient(c); key != nil {
+		client = "key:" + strconv.Itoa(key.ID)
+	}
+
+	result := p.limiter.Allow(c.Request.Context(),
+		ratelimit.Key{Rule: clientRule, Value: client},
+		ratelimit.Key{Rule: targetRule, Value: "phone:" + phone},
+		ratelimit.Key{Rule: targetRule, Value: "dni:" + dni},
+	)
+	if result.Allowed {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(result.RetryAfterSeconds()))
+	p.respond.Fail(c, http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many requests, retry later", nil)
+	return true
+}
+
+// invalidPayload responds with 400 and, for validation errors, the message of each invalid field
+func invalidPayload(c *gin.Context, respond Responder, err error) {
+	respond.Fail(c, http.StatusBadRequest, models.ErrorCodeInvalidPayload, "Invalid request payload", validation.FieldErrors(err))
 }
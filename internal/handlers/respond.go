@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/middleware"
+)
+
+// Responder writes the result of a handler. The same handlers serve the deprecated
+// unversioned routes through Legacy and the /v1 routes through V1.
+type Responder interface {
+	OK(c *gin.Context, status int, data any)
+	Fail(c *gin.Context, status int, code, message string, fields map[string]string)
+}
+
+var (
+	// Legacy writes the bodies the unversioned routes always returned
+	Legacy Responder = legacyResponder{}
+	// V1 wraps every body in models.Envelope
+	V1 Responder = v1Responder{}
+)
+
+type legacyResponder struct{}
+
+func (legacyResponder) OK(c *gin.Context, status int, data any) {
+	c.JSON(status, data)
+}
+
+func (legacyResponder) Fail(c *gin.Context, status int, _, message string, fields map[string]string) {
+	resp := gin.H{"error": message}
+	if fields != nil {
+		resp["fields"] = fields
+	}
+	c.AbortWithStatusJSON(status, resp)
+}
+
+type v1Responder struct{}
+
+func (v1Responder) OK(c *gin.Context, status int, data any) {
+	c.JSON(status, models.Envelope{Data: data, RequestID: middleware.GetRequestID(c)})
+}
+
+func (v1Responder) Fail(c *gin.Context, status int, code, message string, fields map[string]string) {
+	c.AbortWithStatusJSON(status, models.Envelope{
+		Error:     &models.APIError{Code: code, Message: message, Fields: fields},
+		RequestID: middleware.GetRequestID(c),
+	})
+}
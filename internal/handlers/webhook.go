@@ -3,37 +3,69 @@ package handlers
 import (
 	"bone_appetit_r4_service/internal/models"
 	"bone_appetit_r4_service/internal/services"
-	"fmt"
+	"bone_appetit_r4_service/pkg/lifecycle"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/ratelimit"
+	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 type WebhookHandler struct {
 	service services.WebhookService
+	workers *lifecycle.Workers
+	limiter *ratelimit.Limiter
 }
 
-func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+func NewWebhookHandler(service services.WebhookService, workers *lifecycle.Workers, limiter *ratelimit.Limiter) *WebhookHandler {
 	return &WebhookHandler{
 		service: service,
+		workers: workers,
+		limiter: limiter,
 	}
 }
 
+// Unauthorized answers the webhooks failing authentication, with 429 once their source
+// IP failed too often. Webhooks signed by R4 are never limited: behind a load balancer
+// that is not a trusted proxy every caller shares its IP, and a flood of forged ones
+// must not keep the genuine ones out.
+func (h *WebhookHandler) Unauthorized(c *gin.Context) {
+	result := h.limiter.Allow(c.Request.Context(), ratelimit.Key{Rule: ratelimit.WebhookPerIP, Value: "ip:" + c.ClientIP()})
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(result.RetryAfterSeconds()))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"status": false})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"abono": false})
+}
+
 // HandlerBoneR4Consulta is the handler for the R4Consulta webhook
 func (h *WebhookHandler) HandlerBoneR4Consulta(c *gin.Context) {
 	var request models.R4ConsultaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
+		logs.FromContext(c.Request.Context()).Warn("invalid webhook payload", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("bone", "consulta", "invalid_payload").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"status": false})
 		return
 	}
 
-	// Process asynchronously
-	go func(request models.R4ConsultaRequest) {
-		if err := h.service.RegisterR4MobilePaymentPreview(&request, "bone"); err != nil {
-			fmt.Printf("Error registering R4 mobile payment preview: %v\n", err)
+	// Process asynchronously, shutdown waits for it to finish
+	spanCtx := trace.SpanContextFromContext(c)
+	logger := logs.FromContext(c.Request.Context())
+	h.workers.Go(func(ctx context.Context) {
+		ctx = logs.WithContext(trace.ContextWithSpanContext(ctx, spanCtx), logger)
+		if err := h.service.RegisterR4MobilePaymentPreview(ctx, &request, "bone"); err != nil {
+			metrics.WebhooksReceived.WithLabelValues("bone", "consulta", "error").Inc()
+			logs.FromContext(ctx).Error("could not register R4 mobile payment preview", zap.Error(err))
+			return
 		}
-	}(request)
+		metrics.WebhooksReceived.WithLabelValues("bone", "consulta", "registered").Inc()
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": true,
@@ -44,17 +76,20 @@ func (h *WebhookHandler) HandlerBoneR4Consulta(c *gin.Context) {
 func (h *WebhookHandler) HandlerBoneR4Notifica(c *gin.Context) {
 	var request models.R4NotificaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
+		logs.FromContext(c.Request.Context()).Warn("invalid webhook payload", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("bone", "notifica", "invalid_payload").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"status": false})
 		return
 	}
 
-	err := h.service.RegisterR4MobilePayment(&request, "bone")
+	err := h.service.RegisterR4MobilePayment(c, &request, "bone")
 	if err != nil {
-		fmt.Printf("Error registering R4 mobile payment: %v\n", err)
+		logs.FromContext(c.Request.Context()).Error("could not register R4 mobile payment", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("bone", "notifica", "error").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{"status": false})
 		return
 	}
+	metrics.WebhooksReceived.WithLabelValues("bone", "notifica", "registered").Inc()
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": true,
@@ -65,17 +100,24 @@ func (h *WebhookHandler) HandlerBoneR4Notifica(c *gin.Context) {
 func (h *WebhookHandler) HandlerAppaR4Consulta(c *gin.Context) {
 	var request models.R4ConsultaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
+		logs.FromContext(c.Request.Context()).Warn("invalid webhook payload", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("appa", "consulta", "invalid_payload").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"status": false})
 		return
 	}
 
-	// Process asynchronously
-	go func(request models.R4ConsultaRequest) {
-		if err := h.service.RegisterR4MobilePaymentPreview(&request, "appa"); err != nil {
-			fmt.Printf("Error registering R4 mobile payment preview: %v\n", err)
+	// Process asynchronously, shutdown waits for it to finish
+	spanCtx := trace.SpanContextFromContext(c)
+	logger := logs.FromContext(c.Request.Context())
+	h.workers.Go(func(ctx context.Context) {
+		ctx = logs.WithContext(trace.ContextWithSpanContext(ctx, spanCtx), logger)
+		if err := h.service.RegisterR4MobilePaymentPreview(ctx, &request, "appa"); err != nil {
+			metrics.WebhooksReceived.WithLabelValues("appa", "consulta", "error").Inc()
+			logs.FromContext(ctx).Error("could not register R4 mobile payment preview", zap.Error(err))
+			return
 		}
-	}(request)
+		metrics.WebhooksReceived.WithLabelValues("appa", "consulta", "registered").Inc()
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": true,
@@ -86,17 +128,20 @@ func (h *WebhookHandler) HandlerAppaR4Consulta(c *gin.Context) {
 func (h *WebhookHandler) HandlerAppaR4Notifica(c *gin.Context) {
 	var request models.R4NotificaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
+		logs.FromContext(c.Request.Context()).Warn("invalid webhook payload", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("appa", "notifica", "invalid_payload").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"status": false})
 		return
 	}
 
-	err := h.service.RegisterR4MobilePayment(&request, "appa")
+	err := h.service.RegisterR4MobilePayment(c, &request, "appa")
 	if err != nil {
-		fmt.Printf("Error registering R4 mobile payment: %v\n", err)
+		logs.FromContext(c.Request.Context()).Error("could not register R4 mobile payment", zap.Error(err))
+		metrics.WebhooksReceived.WithLabelValues("appa", "notifica", "error").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{"status": false})
 		return
 	}
+	metrics.WebhooksReceived.WithLabelValues("appa", "notifica", "registered").Inc()
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": true,
@@ -0,0 +1,24 @@
+package models
+
+// Envelope is the body of every /v1 response: Data on success, Error otherwise
+type Envelope struct {
+	Data      any       `json:"data,omitempty"`
+	Error     *APIError `json:"error,omitempty"`
+	RequestID string    `json:"requestId"`
+}
+
+// APIError describes why a /v1 request failed. Clients branch on Code, Message is for humans.
+type APIError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Error codes of the /v1 API
+const (
+	ErrorCodeInvalidPayload = "invalid_payload"
+	ErrorCodeUnauthorized   = "unauthorized"
+	ErrorCodeR4             = "r4_error"
+	ErrorCodeInternal       = "internal_error"
+	ErrorCodeRateLimited    = "rate_limited"
+)
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey identifies an internal client. The key itself is only shown when it is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Store      string     `json:"store"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name" binding:"required,max=255"`
+	Store string `json:"store" binding:"required,oneof=bone appa"`
+}
+
+// CreatedAPIKey is a new API key along with the key, which cannot be recovered later
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// BackofficeUser is a member of the support or finance staff signing in to the back office
+type BackofficeUser struct {
+	ID          int        `json:"id"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+}
+
+// BackofficeSession is a signed-in user. Token goes in the session cookie and CSRFToken
+// in every form posted during the session.
+type BackofficeSession struct {
+	Token     string
+	CSRFToken string
+	ExpiresAt time.Time
+	User      BackofficeUser
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// OperationQuery filters the immediate debits of a store
+type OperationQuery struct {
+	Phone     string `form:"phone"`
+	DNI       string `form:"dni"`
+	Reference string `form:"reference"`
+	Code      string `form:"code"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// Operation is an immediate debit requested through the service with its last known status
+type Operation struct {
+	ID        string    `json:"id"`
+	Store     string    `json:"store"`
+	Bank      string    `json:"bank"`
+	Amount    float64   `json:"amount"`
+	Phone     string    `json:"phone"`
+	DNI       string    `json:"dni"`
+	Name      string    `json:"name"`
+	Code      string    `json:"code"`
+	Reference string    `json:"reference"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// OperationStatus is a status R4 reported for an operation
+type OperationStatus struct {
+	Code      string    `json:"code"`
+	Reference string    `json:"reference"`
+	Success   bool      `json:"success"`
+	At        time.Time `json:"at"`
+}
+
+// OperationDetail is an operation with every status it went through, oldest first
+type OperationDetail struct {
+	Operation
+	History []OperationStatus `json:"history"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// PaymentQuery filters the mobile payments received by a store
+type PaymentQuery struct {
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Reference   string `form:"reference"`
+	SenderPhone string `form:"sender_phone"`
+	Bank        string `form:"bank"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+	Offset      int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// Payment represents a mobile payment notified by R4, enriched with the issuing bank name
+type Payment struct {
+	ID              int       `json:"id"`
+	Reference       string    `json:"reference"`
+	Amount          float64   `json:"amount"`
+	SenderPhone     string    `json:"senderPhone"`
+	CommercePhone   string    `json:"commercePhone"`
+	IssuingBank     string    `json:"issuingBank"`
+	IssuingBankName string    `json:"issuingBankName"`
+	OrderID         *int      `json:"orderId"`
+	Date            time.Time `json:"date"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type PaymentListResponse struct {
+	Payments []Payment `json:"payments"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
+// BankListResponse lists the banks of the catalog
+type BankListResponse struct {
+	Version string `json:"version"`
+	Banks   []Bank `json:"banks"`
+}
+
+type Bank struct {
+	Code           string `json:"code"`
+	ShortName      string `json:"shortName"`
+	Name           string `json:"name"`
+	ImmediateDebit bool   `json:"immediateDebit"`
+}
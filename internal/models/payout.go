@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ChangePayout is a change payout (MBvuelto) paid or waiting for approval
+type ChangePayout struct {
+	ID        int64      `json:"id"`
+	Store     string     `json:"store"`
+	Bank      string     `json:"bank"`
+	Amount    float64    `json:"amount"`
+	Phone     string     `json:"phone"`
+	DNI       string     `json:"dni"`
+	Concept   string     `json:"concept"`
+	Status    string     `json:"status"`
+	Reference string     `json:"reference"`
+	Error     *string    `json:"error"`
+	DecidedBy *string    `json:"decidedBy"`
+	DecidedAt *time.Time `json:"decidedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"bone_appetit_r4_service/pkg/banks"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
 // BCVTasaUSDResponse represent the response from the BCV API
 type BCVTasaUSDResponse struct {
 	Date string  `json:"date"`
@@ -7,32 +12,63 @@ type BCVTasaUSDResponse struct {
 }
 
 type OTPRequest struct {
-	Bank   string  `json:"bank"`
-	Amount float64 `json:"amount"`
-	Phone  string  `json:"phone"`
-	DNI    string  `json:"dni"`
+	Bank   string  `json:"bank" binding:"required,vebankdebit"`
+	Amount float64 `json:"amount" binding:"required,veamount"`
+	Phone  string  `json:"phone" binding:"required,vephone"`
+	DNI    string  `json:"dni" binding:"required,vedni"`
+}
+
+// Normalize rewrites the fields in the format expected by R4
+func (r *OTPRequest) Normalize() {
+	r.Bank = banks.Normalize(r.Bank)
+	r.Phone = validation.NormalizePhone(r.Phone)
+	r.DNI = validation.NormalizeDNI(r.DNI)
 }
 
 type ValidateOTPRequest struct {
-	Bank    string  `json:"bank"`
-	Amount  float64 `json:"amount"`
-	Phone   string  `json:"phone"`
-	DNI     string  `json:"dni"`
-	Name    string  `json:"name"`
-	OTP     string  `json:"otp"`
-	Concept string  `json:"concept"`
+	Bank    string  `json:"bank" binding:"required,vebankdebit"`
+	Amount  float64 `json:"amount" binding:"required,veamount"`
+	Phone   string  `json:"phone" binding:"required,vephone"`
+	DNI     string  `json:"dni" binding:"required,vedni"`
+	Name    string  `json:"name" binding:"required,max=80"`
+	OTP     string  `json:"otp" binding:"required,numeric,min=4,max=8"`
+	Concept string  `json:"concept" binding:"max=140"`
+}
+
+// Normalize rewrites the fields in the format expected by R4
+func (r *ValidateOTPRequest) Normalize() {
+	r.Bank = banks.Normalize(r.Bank)
+	r.Phone = validation.NormalizePhone(r.Phone)
+	r.DNI = validation.NormalizeDNI(r.DNI)
 }
 
 type ChangePaidRequest struct {
-	Bank    string  `json:"bank"`
-	Amount  float64 `json:"amount"`
-	Phone   string  `json:"phone"`
-	DNI     string  `json:"dni"`
-	Concept string  `json:"concept"`
+	Bank    string  `json:"bank" binding:"required,vebank"`
+	Amount  float64 `json:"amount" binding:"required,veamount"`
+	Phone   string  `json:"phone" binding:"required,vephone"`
+	DNI     string  `json:"dni" binding:"required,vedni"`
+	Concept string  `json:"concept" binding:"max=140"`
+}
+
+// Normalize rewrites the fields in the format expected by R4
+func (r *ChangePaidRequest) Normalize() {
+	r.Bank = banks.Normalize(r.Bank)
+	r.Phone = validation.NormalizePhone(r.Phone)
+	r.DNI = validation.NormalizeDNI(r.DNI)
 }
 
+// Change payout outcomes returned by change-paid
+const (
+	ChangeStatusPaid    = "paid"
+	ChangeStatusPending = "pending"
+)
+
+// ChangePaidResponse is a payout either paid, with its reference, or pending approval in
+// the back office because of its amount
 type ChangePaidResponse struct {
 	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	PayoutID  int64  `json:"payoutId,omitempty"`
 }
 
 type ValidateDebitInmediateResponse struct {
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Subscription is a downstream endpoint receiving the events of a store.
+// The signing secret is only shown when it is created.
+type Subscription struct {
+	ID          int        `json:"id"`
+	Store       string     `json:"store"`
+	URL         string     `json:"url"`
+	Events      []string   `json:"events"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DisabledAt  *time.Time `json:"disabledAt"`
+}
+
+// CreateSubscriptionRequest subscribes url to events, or to every event when empty
+type CreateSubscriptionRequest struct {
+	Store       string   `json:"store" binding:"required,oneof=bone appa"`
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Events      []string `json:"events" binding:"omitempty,dive,oneof=payment.received debit.accepted debit.rejected change.sent"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// CreatedSubscription is a new subscription along with its signing secret
+type CreatedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+type SubscriptionListResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Delivery is one event sent, or still to send, to one subscription
+type Delivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int        `json:"subscriptionId"`
+	EventID        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastStatusCode *int       `json:"lastStatusCode"`
+	LastError      *string    `json:"lastError"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+}
+
+type DeliveryListResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// DeliveryAttempt is what a subscriber answered to one attempt
+type DeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"statusCode"`
+	Error      *string   `json:"error"`
+	DurationMS int       `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// DeliveryDetail is a delivery with its payload and the log of its attempts
+type DeliveryDetail struct {
+	Delivery
+	Payload json.RawMessage   `json:"payload"`
+	Log     []DeliveryAttempt `json:"log"`
+}
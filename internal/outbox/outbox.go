@@ -0,0 +1,75 @@
+// Package outbox stores domain events in the database along with the changes they
+// describe, and relays them to the message broker and the webhook subscriptions.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/pkg/broker"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+)
+
+// Outbox is an events.Publisher storing each event on its own, for changes that are
+// not in the database, such as R4 operations
+type Outbox struct {
+	db *gorm.DB
+}
+
+// New creates an Outbox
+func New(db *gorm.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// Publish stores event to be relayed
+func (o *Outbox) Publish(ctx context.Context, event events.Event) error {
+	return Write(o.db.WithContext(ctx), event)
+}
+
+// Write stores event in tx. It is only relayed if tx commits. An event whose ID is
+// already stored is skipped, see events.NewOnce.
+func Write(tx *gorm.DB, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event %s: %w", event.Type, err)
+	}
+
+	return tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).Create(&dbModels.OutboxEvent{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Store:     event.Store,
+		Payload:   payload,
+	}).Error
+}
+
+// BrokerSink publishes events to a message broker under the key <store>.<type>,
+// e.g. bone.payment.received
+func BrokerSink(publisher broker.Publisher) events.Publisher {
+	return brokerSink{publisher: publisher}
+}
+
+type brokerSink struct {
+	publisher broker.Publisher
+}
+
+func (b brokerSink) Publish(ctx context.Context, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event %s: %w", event.Type, err)
+	}
+
+	return b.publisher.Publish(ctx, broker.Message{
+		ID:   event.ID,
+		Key:  event.Store + "." + event.Type,
+		Body: body,
+		Headers: map[string]string{
+			"event-type": event.Type,
+			"store":      event.Store,
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/pkg/broker"
+)
+
+func TestBrokerSinkRelaysTheStoredEvent(t *testing.T) {
+	stored, err := json.Marshal(events.New(events.DebitAccepted, "appa", events.Debit{OperationID: "op-1", Code: "ACCP", Amount: 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The relay reads the event back from the outbox without knowing its data type
+	event, err := events.Decode(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memory := broker.NewMemory()
+	if err := BrokerSink(memory).Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := memory.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("published %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.ID != event.ID || msg.Key != "appa.debit.accepted" || msg.Headers["event-type"] != events.DebitAccepted {
+		t.Fatalf("message = %+v, want the event keyed appa.debit.accepted", msg)
+	}
+	if string(msg.Body) != string(stored) {
+		t.Fatalf("body = %s, want the stored event %s", msg.Body, stored)
+	}
+	if event.OccurredAt.IsZero() || time.Since(event.OccurredAt) > time.Minute {
+		t.Fatalf("occurredAt = %v", event.OccurredAt)
+	}
+}
@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bone_appetit_r4_service/internal/events"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/metrics"
+)
+
+// RelayConfig controls how often the outbox is relayed
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// PublishTimeout bounds handing one event to one sink
+	PublishTimeout time.Duration
+	// InitialBackoff is the wait after an event first fails, doubled after each failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Relay hands the outbox events to every sink, oldest first, and marks them published
+// once they all accepted it. An event is retried until then, so sinks see it at least
+// once and must deduplicate on its ID. Several instances can relay the same outbox.
+type Relay struct {
+	db     *gorm.DB
+	sinks  []events.Publisher
+	cfg    RelayConfig
+	logger *zap.Logger
+}
+
+// NewRelay creates a Relay publishing to sinks
+func NewRelay(db *gorm.DB, cfg RelayConfig, logger *zap.Logger, sinks ...events.Publisher) *Relay {
+	return &Relay{db: db, sinks: sinks, cfg: cfg, logger: logger}
+}
+
+// Run relays the outbox every PollInterval until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep going while full batches come back, a backlog should not wait for the next tick
+		for ctx.Err() == nil {
+			n, err := r.RelayDue(context.WithoutCancel(ctx))
+			if err != nil {
+				r.logger.Error("could not relay the outbox", zap.Error(err))
+			}
+			if err != nil || n < r.cfg.BatchSize {
+				break
+			}
+		}
+		r.updateBacklog(ctx)
+	}
+}
+
+// RelayDue relays one batch of due events and returns how many were published. It stops
+// at the first event a sink rejects, the rest of the batch is likely to fail the same way.
+func (r *Relay) RelayDue(ctx context.Context) (int, error) {
+	published := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The rows stay locked until the batch is done so other instances skip them
+		var batch []dbModels.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND next_attempt_at <= NOW()").
+			Order("id").
+			Limit(r.cfg.BatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return fmt.Errorf("reading the outbox: %w", err)
+		}
+
+		for _, row := range batch {
+			if err := r.publish(ctx, row); err != nil {
+				metrics.OutboxEvents.WithLabelValues(row.EventType, "error").Inc()
+				r.logger.Warn("could not relay outbox event", zap.Error(err),
+					zap.String("event", row.EventType), zap.String("event_id", row.EventID), zap.Int("attempt", row.Attempts+1))
+				return r.postpone(tx, row, err)
+			}
+
+			metrics.OutboxEvents.WithLabelValues(row.EventType, "published").Inc()
+			published++
+			err := tx.Model(&row).Updates(map[string]any{
+				"published_at": gorm.Expr("NOW()"),
+				"attempts":     row.Attempts + 1,
+				"last_error":   nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return published, err
+}
+
+// publish hands an event to every sink
+func (r *Relay) publish(ctx context.Context, row dbModels.OutboxEvent) error {
+	event, err := events.Decode(row.Payload)
+	if err != nil {
+		return fmt.Errorf("decoding event: %w", err)
+	}
+
+	for _, sink := range r.sinks {
+		ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+		err := sink.Publish(ctx, event)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postpone records a failed attempt and schedules the next one with exponential backoff
+func (r *Relay) postpone(tx *gorm.DB, row dbModels.OutboxEvent, cause error) error {
+	wait := r.cfg.InitialBackoff
+	for i := 0; i < row.Attempts && wait < r.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, r.cfg.MaxBackoff)
+
+	return tx.Model(&row).Updates(map[string]any{
+		"attempts":        row.Attempts + 1,
+		"last_error":      cause.Error(),
+		"next_attempt_at": gorm.Expr("NOW() + make_interval(secs => ?)", wait.Seconds()),
+	}).Error
+}
+
+func (r *Relay) updateBacklog(ctx context.Context) {
+	var backlog int64
+	if err := r.db.WithContext(ctx).Model(&dbModels.OutboxEvent{}).Where("published_at IS NULL").Count(&backlog).Error; err != nil {
+		return
+	}
+	metrics.OutboxBacklog.Set(float64(backlog))
+}
@@ -0,0 +1,156 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/middleware"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// debounce groups the burst of events a secret mount update produces into one reload
+const debounce = 500 * time.Millisecond
+
+// Store is what must be updated when the credentials of a store change
+type Store struct {
+	Client *r4bank.RestClient
+	Auth   *middleware.WebhookAuthMiddleware
+}
+
+// Reloader re-reads the configuration on SIGHUP or when a watched file changes and
+// applies the new store credentials. Other settings still require a restart.
+type Reloader struct {
+	logger *zap.Logger
+	stores map[string]Store
+
+	mu      sync.Mutex
+	current map[string]config.StoreConfig
+}
+
+// New creates a Reloader starting from the credentials in cfg
+func New(cfg *config.Config, stores map[string]Store, logger *zap.Logger) *Reloader {
+	r := &Reloader{
+		logger:  logger,
+		stores:  stores,
+		current: make(map[string]config.StoreConfig, len(stores)),
+	}
+	for name := range stores {
+		r.current[name] = *cfg.Store(name)
+	}
+	return r
+}
+
+// Reload loads the configuration again and rotates the credentials that changed
+func (r *Reloader) Reload() error {
+	cfg, err := config.Load()
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("error").Inc()
+		r.logger.Error("could not reload configuration, keeping the current credentials", zap.Error(err))
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rotated := 0
+	for name, target := range r.stores {
+		next := *cfg.Store(name)
+		if next == r.current[name] {
+			continue
+		}
+
+		if target.Client != nil {
+			target.Client.UpdateCredentials(next.EntryPoint, next.CommerceToken)
+		}
+		target.Auth.Rotate(next.Secret, next.CommerceToken, cfg.SecretRotationOverlap)
+		r.current[name] = next
+		rotated++
+
+		r.logger.Info("store credentials rotated",
+			zap.String("store", name),
+			zap.Duration("previous_secret_accepted_for", cfg.SecretRotationOverlap))
+	}
+
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	r.logger.Info("configuration reloaded", zap.Int("stores_rotated", rotated))
+	return nil
+}
+
+// Start reloads on SIGHUP and on changes to the config and secret files until ctx is
+// done. SIGHUP is handled once Start returns, before it the default action kills the
+// process.
+func (r *Reloader) Start(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go r.run(ctx, hup)
+}
+
+func (r *Reloader) run(ctx context.Context, hup chan os.Signal) {
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	watcher, err := r.watch(config.WatchedFiles())
+	if err != nil {
+		r.logger.Warn("could not watch configuration files, reload with SIGHUP", zap.Error(err))
+	} else if watcher != nil {
+		defer watcher.Close()
+		events = watcher.Events
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading configuration")
+			_ = r.Reload()
+		case ev := <-events:
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				pending = time.After(debounce)
+			}
+		case <-pending:
+			pending = nil
+			r.logger.Info("configuration files changed, reloading")
+			_ = r.Reload()
+		}
+	}
+}
+
+// watch follows the directories of files, since Kubernetes and Docker replace
+// mounted secrets by swapping symlinks rather than writing the file
+func (r *Reloader) watch(files []string) (*fsnotify.Watcher, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		dirs[dir] = true
+	}
+
+	return watcher, nil
+}
@@ -0,0 +1,104 @@
+package reload
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/internal/config"
+	"bone_appetit_r4_service/pkg/middleware"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// setStores sets the configuration Load requires, with the bone credentials given
+func setStores(t *testing.T, entryPoint, token, secret string) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"DB_HOST":                 "localhost",
+		"DB_PORT":                 "5432",
+		"DB_USER":                 "r4",
+		"DB_PASSWORD":             "r4",
+		"DB_NAME":                 "r4",
+		"R4_APPA_ENTRY_POINT":     "https://r4.example.com",
+		"R4_APPA_COMMERCE_TOKEN":  "token-appa",
+		"APPA_SECRET":             "secret-appa",
+		"R4_BONE_ENTRY_POINT":     entryPoint,
+		"R4_BONE_COMMERCE_TOKEN":  token,
+		"BONE_SECRET":             secret,
+		"SECRET_ROTATION_OVERLAP": "1m",
+	} {
+		t.Setenv(key, value)
+	}
+}
+
+// r4Server records the commerce token of the calls it receives
+func r4Server(t *testing.T) (*httptest.Server, *string) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Commerce")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":"00","fechavalor":"2024-01-02","tipocambio":36.5}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &token
+}
+
+func TestReloadRotatesClientAndSecretTogether(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	before, beforeToken := r4Server(t)
+	after, afterToken := r4Server(t)
+	setStores(t, before.URL, "token-1", "secret-1")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bone := cfg.Store(config.StoreBone)
+	client := r4bank.NewClient(config.StoreBone, bone.EntryPoint, bone.CommerceToken, time.Second, zap.NewNop())
+	auth := middleware.NewWebhookAuthMiddleware(config.StoreBone, bone.Secret, bone.CommerceToken)
+	appa := cfg.Store(config.StoreAppa)
+	reloader := New(cfg, map[string]Store{
+		config.StoreBone: {Client: client, Auth: auth},
+		config.StoreAppa: {Auth: middleware.NewWebhookAuthMiddleware(config.StoreAppa, appa.Secret, appa.CommerceToken)},
+	}, zap.NewNop())
+
+	router := gin.New()
+	router.POST("/R4notifica", auth.Auth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	accepts := func(secret, token string) bool {
+		req := httptest.NewRequest(http.MethodPost, "/R4notifica", nil)
+		req.Header.Set("Authorization", r4bank.GenerateAuthToken(token, secret))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code == http.StatusOK
+	}
+
+	setStores(t, after.URL, "token-2", "secret-2")
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.BCVRate(context.Background(), r4bank.BCVRateRequest{Currency: "USD", Date: "2024-01-02"}); err != nil {
+		t.Fatal(err)
+	}
+	if *afterToken != "token-2" || *beforeToken != "" {
+		t.Fatalf("R4 called with token %q at the new entry point and %q at the old one, want only the new token at the new one", *afterToken, *beforeToken)
+	}
+	if !accepts("secret-2", "token-2") || !accepts("secret-1", "token-1") {
+		t.Fatal("webhooks signed with the new or, within the overlap, the previous secret were rejected")
+	}
+
+	// A configuration that does not load keeps the credentials in use
+	setStores(t, after.URL, "token-3", "")
+	if err := reloader.Reload(); err == nil || !strings.Contains(err.Error(), "BONE_SECRET") {
+		t.Fatalf("Reload with a missing secret = %v, want it reported", err)
+	}
+	if !accepts("secret-2", "token-2") || client.CredentialsError() != nil {
+		t.Fatal("a failed reload changed the credentials")
+	}
+}
@@ -0,0 +1,38 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/handlers"
+	"bone_appetit_r4_service/pkg/middleware"
+)
+
+type AdminRouter struct {
+	adminHandler *handlers.AdminHandler
+	token        string
+}
+
+// NewAdminRouter creates the /admin routes, protected with a bearer token
+func NewAdminRouter(adminHandler *handlers.AdminHandler, token string) *AdminRouter {
+	return &AdminRouter{adminHandler: adminHandler, token: token}
+}
+
+// SetRouter sets up the admin routes. They are not mounted without a token.
+func (a *AdminRouter) SetRouter(router *gin.Engine) {
+	if a.token == "" {
+		return
+	}
+
+	admin := router.Group("/admin", middleware.BearerToken(a.token))
+	admin.GET("/egress-ip", a.adminHandler.HandleEgressIP)
+	admin.POST("/api-keys", a.adminHandler.HandleCreateAPIKey)
+	admin.GET("/api-keys", a.adminHandler.HandleListAPIKeys)
+	admin.DELETE("/api-keys/:id", a.adminHandler.HandleRevokeAPIKey)
+	admin.POST("/subscriptions", a.adminHandler.HandleCreateSubscription)
+	admin.GET("/subscriptions", a.adminHandler.HandleListSubscriptions)
+	admin.POST("/subscriptions/:id/disable", a.adminHandler.HandleDisableSubscription)
+	admin.POST("/subscriptions/:id/enable", a.adminHandler.HandleEnableSubscription)
+	admin.GET("/subscriptions/:id/deliveries", a.adminHandler.HandleListDeliveries)
+	admin.GET("/deliveries/:id", a.adminHandler.HandleGetDelivery)
+	admin.POST("/deliveries/:id/retry", a.adminHandler.HandleRetryDelivery)
+}
@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/backoffice"
+	"bone_appetit_r4_service/internal/handlers"
+)
+
+type BackofficeRouter struct {
+	backofficeHandler *handlers.BackofficeHandler
+}
+
+// NewBackofficeRouter creates the /backoffice pages, signed in with the back-office users
+func NewBackofficeRouter(backofficeHandler *handlers.BackofficeHandler) *BackofficeRouter {
+	return &BackofficeRouter{backofficeHandler: backofficeHandler}
+}
+
+// SetRouter sets up the back-office pages
+func (b *BackofficeRouter) SetRouter(router *gin.Engine) {
+	h := b.backofficeHandler
+	group := router.Group("/backoffice", h.SecurityHeaders)
+	group.StaticFS("/static", http.FS(backoffice.Static()))
+	group.GET("/login", h.HandleLoginPage)
+	group.POST("/login", h.HandleLogin)
+
+	pages := group.Group("", h.RequireSession)
+	pages.GET("", h.HandleHome)
+	pages.POST("/logout", h.HandleLogout)
+	pages.GET("/payments", h.HandlePayments)
+	pages.GET("/payments/export", h.HandleExportPayments)
+	pages.POST("/payments/:store/:id/order", h.HandleLinkOrder)
+	pages.GET("/operations", h.HandleOperations)
+	pages.GET("/operations/:store/:id", h.HandleOperation)
+	pages.POST("/operations/:store/:id/refresh", h.HandleRefreshOperation)
+	pages.GET("/payouts", h.HandlePayouts)
+	pages.POST("/payouts/:store/:id/approve", h.HandleApprovePayout)
+	pages.POST("/payouts/:store/:id/reject", h.HandleRejectPayout)
+	pages.POST("/payouts/:store/:id/resolve", h.HandleResolvePayout)
+}
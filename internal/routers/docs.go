@@ -0,0 +1,21 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/handlers"
+)
+
+type DocsRouter struct {
+	docsHandler *handlers.DocsHandler
+}
+
+func NewDocsRouter(docsHandler *handlers.DocsHandler) *DocsRouter {
+	return &DocsRouter{docsHandler: docsHandler}
+}
+
+// SetRouter sets up the unauthenticated API documentation routes
+func (d *DocsRouter) SetRouter(router *gin.Engine) {
+	router.GET("/openapi.json", d.docsHandler.HandleSpec)
+	router.GET("/docs/*path", d.docsHandler.HandleUI)
+}
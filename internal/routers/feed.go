@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/handlers"
+	"bone_appetit_r4_service/internal/services"
+)
+
+type FeedRoutes struct {
+	store       string
+	feedHandler *handlers.FeedHandler
+	apiKeys     services.APIKeyService
+}
+
+// NewFeedRoutes creates the /v1/<store>/feed route, authenticated with the store's API keys
+func NewFeedRoutes(store string, feedHandler *handlers.FeedHandler, apiKeys services.APIKeyService) *FeedRoutes {
+	return &FeedRoutes{store: store, feedHandler: feedHandler, apiKeys: apiKeys}
+}
+
+// SetRouter sets up the live feed route of the store
+func (f *FeedRoutes) SetRouter(router *gin.Engine) {
+	router.GET("/v1/"+f.store+"/feed", handlers.RequireAPIKey(f.apiKeys, f.store), f.feedHandler.HandleFeed)
+}
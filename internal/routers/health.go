@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"bone_appetit_r4_service/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthRouter struct {
+	healthHandler *handlers.HealthHandler
+}
+
+func NewHealthRouter(healthHandler *handlers.HealthHandler) *HealthRouter {
+	return &HealthRouter{healthHandler: healthHandler}
+}
+
+// SetRouter sets up the unauthenticated probe routes
+func (h *HealthRouter) SetRouter(router *gin.Engine) {
+	router.GET("/healthz", h.healthHandler.HandleHealthz)
+	router.GET("/livez", h.healthHandler.HandleLive)
+	router.GET("/readyz", h.healthHandler.HandleReady)
+}
@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"bone_appetit_r4_service/pkg/middleware"
+)
+
+type MetricsRouter struct {
+	token string
+}
+
+// NewMetricsRouter creates the /metrics route, protected with a bearer token when token is not empty
+func NewMetricsRouter(token string) *MetricsRouter {
+	return &MetricsRouter{token: token}
+}
+
+// SetRouter sets up the Prometheus scrape route
+func (m *MetricsRouter) SetRouter(router *gin.Engine) {
+	handlers := []gin.HandlerFunc{gin.WrapH(promhttp.Handler())}
+	if m.token != "" {
+		handlers = append([]gin.HandlerFunc{middleware.BearerToken(m.token)}, handlers...)
+	}
+	router.GET("/metrics", handlers...)
+}
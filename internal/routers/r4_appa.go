@@ -8,19 +8,32 @@ import (
 )
 
 type r4AppaRoutes struct {
-	r4Handler *handlers.R4Handler
+	r4Handler      *handlers.R4Handler
+	bankHandler    *handlers.BankHandler
+	paymentHandler *handlers.PaymentHandler
 }
 
-func NewR4AppaRoutes(r4Handler *handlers.R4Handler) *r4AppaRoutes {
-	return &r4AppaRoutes{r4Handler: r4Handler}
+func NewR4AppaRoutes(
+	r4Handler *handlers.R4Handler,
+	bankHandler *handlers.BankHandler,
+	paymentHandler *handlers.PaymentHandler,
+) *r4AppaRoutes {
+	return &r4AppaRoutes{
+		r4Handler:      r4Handler,
+		bankHandler:    bankHandler,
+		paymentHandler: paymentHandler,
+	}
 }
 
-// SetRouter sets up the R4-related routes
+// SetRouter sets up the R4-related routes, deprecated in favor of /v1
 func (p *r4AppaRoutes) SetRouter(router *gin.Engine, auth *middleware.WebhookAuthMiddleware) {
-	group := router.Group("/r4/appa", auth.Auth())
+	group := router.Group("/r4/appa", middleware.Deprecated(LegacyDeprecatedAt, "/r4/appa", "/v1/appa"), auth.Auth())
 	group.GET("/bcv-tasa", p.r4Handler.GetBCVTasa)
 	group.POST("/generate-otp", p.r4Handler.HandleGenerateOTP)
 	group.POST("/validate-immediate-debit", p.r4Handler.HandleValidateImmediateDebit)
 	group.POST("/change-paid", p.r4Handler.HandleChangePaid)
 	group.GET("/get-operation/:id", p.r4Handler.HandleGetOperationByID)
+	group.GET("/banks", p.bankHandler.HandleListBanks)
+	group.GET("/payments", p.paymentHandler.HandleFindPayments)
+	group.GET("/payments/export", p.paymentHandler.HandleExportPayments)
 }
@@ -0,0 +1,54 @@
+package routers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/internal/handlers"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/middleware"
+)
+
+// LegacyDeprecatedAt is when the unversioned store routes were superseded by /v1
+var LegacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+type V1Routes struct {
+	store          string
+	r4Handler      *handlers.R4Handler
+	bankHandler    *handlers.BankHandler
+	paymentHandler *handlers.PaymentHandler
+}
+
+// NewV1Routes creates the /v1/<store> routes. The handlers must respond with handlers.V1.
+func NewV1Routes(
+	store string,
+	r4Handler *handlers.R4Handler,
+	bankHandler *handlers.BankHandler,
+	paymentHandler *handlers.PaymentHandler,
+) *V1Routes {
+	return &V1Routes{
+		store:          store,
+		r4Handler:      r4Handler,
+		bankHandler:    bankHandler,
+		paymentHandler: paymentHandler,
+	}
+}
+
+// SetRouter sets up the versioned store routes, the same as the unversioned ones under /v1/<store>
+func (v *V1Routes) SetRouter(router *gin.Engine, auth *middleware.WebhookAuthMiddleware) {
+	group := router.Group("/v1/"+v.store, auth.AuthWith(v1Unauthorized))
+	group.GET("/bcv-tasa", v.r4Handler.GetBCVTasa)
+	group.POST("/generate-otp", v.r4Handler.HandleGenerateOTP)
+	group.POST("/validate-immediate-debit", v.r4Handler.HandleValidateImmediateDebit)
+	group.POST("/change-paid", v.r4Handler.HandleChangePaid)
+	group.GET("/get-operation/:id", v.r4Handler.HandleGetOperationByID)
+	group.GET("/banks", v.bankHandler.HandleListBanks)
+	group.GET("/payments", v.paymentHandler.HandleFindPayments)
+	group.GET("/payments/export", v.paymentHandler.HandleExportPayments)
+}
+
+func v1Unauthorized(c *gin.Context) {
+	handlers.V1.Fail(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "missing or invalid Authorization header", nil)
+}
@@ -17,7 +17,7 @@ func NewWebhookAppaRouter(webhookHandler *handlers.WebhookHandler) *WebhookAppaR
 
 // SetRouter sets up the webhook-related routes
 func (w *WebhookAppaRouter) SetRouter(router *gin.Engine, auth *middleware.WebhookAuthMiddleware) {
-	group := router.Group("/appa", auth.Auth())
+	group := router.Group("/appa", auth.AuthWith(w.webhookHandler.Unauthorized))
 	group.POST("/R4consulta", w.webhookHandler.HandlerAppaR4Consulta)
 	group.POST("/R4notifica", w.webhookHandler.HandlerAppaR4Notifica)
 }
@@ -17,7 +17,7 @@ func NewWebhookRouter(webhookHandler *handlers.WebhookHandler) *WebhookRouter {
 
 // SetRouter sets up the webhook-related routes
 func (w *WebhookRouter) SetRouter(router *gin.Engine, auth *middleware.WebhookAuthMiddleware) {
-	group := router.Group("/", auth.Auth())
+	group := router.Group("/", auth.AuthWith(w.webhookHandler.Unauthorized))
 	group.POST("/R4consulta", w.webhookHandler.HandlerBoneR4Consulta)
 	group.POST("/R4notifica", w.webhookHandler.HandlerBoneR4Notifica)
 }
@@ -0,0 +1,23 @@
+package rpc
+
+import (
+	"context"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"bone_appetit_r4_service/pkg/health"
+)
+
+// healthServer answers the standard gRPC health check with the instance readiness,
+// so it stops reporting SERVING as soon as shutdown starts
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	readiness *health.Readiness
+}
+
+func (h *healthServer) Check(_ context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !h.readiness.IsReady() {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/middleware"
+)
+
+// APIKeyMetadata is the metadata key carrying the caller's API key
+const APIKeyMetadata = "x-api-key"
+
+// requestIDMetadata carries the request ID like the X-Request-ID header does over HTTP
+const requestIDMetadata = "x-request-id"
+
+// protectedPrefix selects the methods requiring an API key, health and reflection do not
+const protectedPrefix = "/r4.v1."
+
+type clientKey struct{}
+
+// client returns the API key the call was authenticated with
+func client(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(clientKey{}).(*models.APIKey)
+	return key
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var resp any
+	err := s.serve(ctx, info.FullMethod, grpc.SetHeader, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	setHeader := func(_ context.Context, md metadata.MD) error { return ss.SetHeader(md) }
+	return s.serve(ss.Context(), info.FullMethod, setHeader, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// serverStream replaces the context of a stream with the one prepared by serve
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// serve runs a call the way the HTTP middlewares run a request: with a request ID and a
+// logger tagged with it, panic recovery, metrics, an access log and, for the R4 and
+// payment services, API key authentication
+func (s *Server) serve(ctx context.Context, method string, setHeader func(context.Context, metadata.MD) error, call func(context.Context) error) (err error) {
+	start := time.Now()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := middleware.RequestIDOrNew(first(md, requestIDMetadata))
+	_ = setHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+	fields := []zap.Field{zap.String("request_id", requestID)}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", spanCtx.TraceID().String()))
+	}
+	logger := s.logger.With(fields...)
+	ctx = logs.WithContext(ctx, logger)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("panic recovered", zap.Any("panic", r), zap.Stack("stack"))
+			err = status.Error(codes.Internal, "internal error")
+		}
+
+		code := status.Code(err)
+		metrics.GRPCRequests.WithLabelValues(method, code.String()).Inc()
+		metrics.GRPCRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+		callFields := []zap.Field{
+			zap.String("method", method),
+			zap.String("code", code.String()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if key := client(ctx); key != nil {
+			callFields = append(callFields, zap.Int("api_key_id", key.ID), zap.String("store", key.Store))
+		}
+		if err != nil {
+			callFields = append(callFields, zap.Error(err))
+		}
+		switch code {
+		case codes.OK:
+			logger.Info("grpc call", callFields...)
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			logger.Error("grpc call", callFields...)
+		default:
+			logger.Warn("grpc call", callFields...)
+		}
+	}()
+
+	if strings.HasPrefix(method, protectedPrefix) {
+		ctx, err = s.authenticate(ctx, first(md, APIKeyMetadata))
+		if err != nil {
+			return err
+		}
+	}
+
+	return call(ctx)
+}
+
+// authenticate resolves the API key and stores it in the context
+func (s *Server) authenticate(ctx context.Context, key string) (context.Context, error) {
+	if key == "" {
+		return ctx, status.Error(codes.Unauthenticated, "missing "+APIKeyMetadata+" metadata")
+	}
+
+	apiKey, err := s.apiKeys.Authenticate(ctx, key)
+	if errors.Is(err, services.ErrInvalidAPIKey) {
+		return ctx, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if err != nil {
+		logs.FromContext(ctx).Error("could not verify API key", zap.Error(err))
+		return ctx, status.Error(codes.Unavailable, "could not verify the API key")
+	}
+	if _, ok := s.r4[apiKey.Store]; !ok {
+		return ctx, status.Errorf(codes.PermissionDenied, "store %s is not served", apiKey.Store)
+	}
+
+	return context.WithValue(ctx, clientKey{}, apiKey), nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
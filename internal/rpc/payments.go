@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"bone_appetit_r4_service/internal/models"
+	r4v1 "bone_appetit_r4_service/pkg/pb/r4/v1"
+)
+
+// FindPayments lists the mobile payments received by the caller's store
+func (s *Server) FindPayments(ctx context.Context, in *r4v1.FindPaymentsRequest) (*r4v1.FindPaymentsResponse, error) {
+	query := models.PaymentQuery{
+		From:        in.From,
+		To:          in.To,
+		Reference:   in.Reference,
+		SenderPhone: in.SenderPhone,
+		Bank:        in.Bank,
+		Limit:       int(in.Limit),
+		Offset:      int(in.Offset),
+	}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.payments.FindPayments(ctx, client(ctx).Store, &query)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	out := &r4v1.FindPaymentsResponse{
+		Payments: make([]*r4v1.Payment, 0, len(resp.Payments)),
+		Limit:    int32(resp.Limit),
+		Offset:   int32(resp.Offset),
+	}
+	for _, p := range resp.Payments {
+		payment := &r4v1.Payment{
+			Id:              int64(p.ID),
+			Reference:       p.Reference,
+			Amount:          p.Amount,
+			SenderPhone:     p.SenderPhone,
+			CommercePhone:   p.CommercePhone,
+			IssuingBank:     p.IssuingBank,
+			IssuingBankName: p.IssuingBankName,
+			Date:            timestamppb.New(p.Date),
+			CreatedAt:       timestamppb.New(p.CreatedAt),
+		}
+		if p.OrderID != nil {
+			orderID := int64(*p.OrderID)
+			payment.OrderId = &orderID
+		}
+		out.Payments = append(out.Payments, payment)
+	}
+	return out, nil
+}
@@ -0,0 +1,218 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	r4v1 "bone_appetit_r4_service/pkg/pb/r4/v1"
+	"bone_appetit_r4_service/pkg/r4bank"
+	"bone_appetit_r4_service/pkg/ratelimit"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
+// codePending is the R4 code of a debit waiting for the bank
+const codePending = "AC00"
+
+// store returns the R4Service of the store the caller's API key belongs to
+func (s *Server) store(ctx context.Context) services.R4Service {
+	return s.r4[client(ctx).Store]
+}
+
+// GetBCVRate returns the BCV exchange rate for USD on the current date
+func (s *Server) GetBCVRate(ctx context.Context, _ *r4v1.GetBCVRateRequest) (*r4v1.GetBCVRateResponse, error) {
+	rate, err := s.store(ctx).GetBCVTasaUSD(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &r4v1.GetBCVRateResponse{Date: rate.Date, Rate: rate.Rate}, nil
+}
+
+// GenerateOTP sends the payer the OTP authorizing an immediate debit
+func (s *Server) GenerateOTP(ctx context.Context, in *r4v1.GenerateOTPRequest) (*r4v1.GenerateOTPResponse, error) {
+	req := models.OTPRequest{Bank: in.Bank, Amount: in.Amount, Phone: in.Phone, DNI: in.Dni}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+	req.Normalize()
+	if err := s.limit(ctx, ratelimit.OTPPerClient, ratelimit.OTPPerTarget, req.Phone, req.DNI); err != nil {
+		return nil, err
+	}
+
+	if err := s.store(ctx).GenerateOTP(ctx, &req); err != nil {
+		return nil, toStatus(err)
+	}
+	return &r4v1.GenerateOTPResponse{}, nil
+}
+
+// ValidateImmediateDebit debits the payer with their OTP and waits for the result
+func (s *Server) ValidateImmediateDebit(ctx context.Context, in *r4v1.ValidateImmediateDebitRequest) (*r4v1.ValidateImmediateDebitResponse, error) {
+	req := models.ValidateOTPRequest{
+		Bank:    in.Bank,
+		Amount:  in.Amount,
+		Phone:   in.Phone,
+		DNI:     in.Dni,
+		Name:    in.Name,
+		OTP:     in.Otp,
+		Concept: in.Concept,
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+	req.Normalize()
+
+	resp, err := s.store(ctx).ValidateImmediateDebit(ctx, &req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if resp == nil {
+		return nil, status.Error(codes.Internal, "R4 did not report the state of the debit")
+	}
+
+	return &r4v1.ValidateImmediateDebitResponse{
+		Id:        resp.ID,
+		Code:      resp.Code,
+		Reference: resp.Reference,
+		Message:   resp.Message,
+		Accepted:  resp.Status,
+	}, nil
+}
+
+// ChangePaid pays change in bolívares to a mobile payment account
+func (s *Server) ChangePaid(ctx context.Context, in *r4v1.ChangePaidRequest) (*r4v1.ChangePaidResponse, error) {
+	req := models.ChangePaidRequest{Bank: in.Bank, Amount: in.Amount, Phone: in.Phone, DNI: in.Dni, Concept: in.Concept}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+	req.Normalize()
+	if err := s.limit(ctx, ratelimit.ChangePerClient, ratelimit.ChangePerTarget, req.Phone, req.DNI); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.store(ctx).ChangePaid(ctx, &req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &r4v1.ChangePaidResponse{
+		Reference: resp.Reference,
+		Pending:   resp.Status == models.ChangeStatusPending,
+		PayoutId:  resp.PayoutID,
+	}, nil
+}
+
+// GetOperation returns the state of an immediate debit
+func (s *Server) GetOperation(ctx context.Context, in *r4v1.GetOperationRequest) (*r4v1.GetOperationResponse, error) {
+	op, err := s.operation(ctx, in.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &r4v1.GetOperationResponse{Operation: op}, nil
+}
+
+// WatchOperation sends the state of an immediate debit and every change until it leaves AC00
+func (s *Server) WatchOperation(in *r4v1.WatchOperationRequest, stream r4v1.R4Service_WatchOperationServer) error {
+	ctx, cancel := context.WithTimeout(stream.Context(), s.watch.Timeout)
+	defer cancel()
+
+	var last string
+	for {
+		op, err := s.operation(ctx, in.Id)
+		if err != nil {
+			return err
+		}
+		if op.Code != last {
+			if err := stream.Send(&r4v1.WatchOperationResponse{Operation: op}); err != nil {
+				return err
+			}
+			last = op.Code
+		}
+		if op.Code != codePending {
+			return nil
+		}
+
+		select {
+		case <-time.After(s.watch.Interval):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func (s *Server) operation(ctx context.Context, id string) (*r4v1.Operation, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "operation id is required")
+	}
+
+	op, err := s.store(ctx).GetOperationByID(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if op == nil {
+		return nil, status.Error(codes.Internal, "R4 did not report the state of the operation")
+	}
+	return toOperation(id, op), nil
+}
+
+func toOperation(id string, op *r4bank.GetOperationResponse) *r4v1.Operation {
+	return &r4v1.Operation{Id: id, Code: op.Code, Reference: op.Reference, Success: op.Success}
+}
+
+// validate checks a request with the rules of the HTTP API, reporting each invalid field
+func validate(req any) error {
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+
+	fields := validation.FieldErrors(err)
+	if fields == nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request")
+	details := &errdetails.BadRequest{}
+	for field, msg := range fields {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: field, Description: msg})
+	}
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// limit takes a token for the caller's API key and for the customer's phone and cédula,
+// returning ResourceExhausted with the delay in RetryInfo when one is over its limit
+func (s *Server) limit(ctx context.Context, clientRule, targetRule, phone, dni string) error {
+	result := s.limiter.Allow(ctx,
+		ratelimit.Key{Rule: clientRule, Value: "key:" + strconv.Itoa(client(ctx).ID)},
+		ratelimit.Key{Rule: targetRule, Value: "phone:" + phone},
+		ratelimit.Key{Rule: targetRule, Value: "dni:" + dni},
+	)
+	if result.Allowed {
+		return nil
+	}
+
+	st := status.New(codes.ResourceExhausted, "too many requests, retry later")
+	retry := &errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)}
+	if withDetails, err := st.WithDetails(retry); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// toStatus turns an error of the services into a gRPC status
+func toStatus(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
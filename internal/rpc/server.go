@@ -0,0 +1,75 @@
+// Package rpc serves the R4 and payment operations over gRPC for internal clients,
+// on top of the same services as the HTTP API. Callers authenticate with an API key
+// in the x-api-key metadata and act on behalf of the store the key belongs to.
+package rpc
+
+import (
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/health"
+	r4v1 "bone_appetit_r4_service/pkg/pb/r4/v1"
+	"bone_appetit_r4_service/pkg/ratelimit"
+)
+
+// Watch controls how WatchOperation follows a debit
+type Watch struct {
+	// Interval is the wait between two ConsultarOperaciones calls
+	Interval time.Duration
+	// Timeout ends the stream when the debit is still pending after it
+	Timeout time.Duration
+}
+
+// Server implements the gRPC services
+type Server struct {
+	r4v1.UnimplementedR4ServiceServer
+	r4v1.UnimplementedPaymentServiceServer
+
+	r4       map[string]services.R4Service
+	payments services.PaymentService
+	apiKeys  services.APIKeyService
+	limiter  *ratelimit.Limiter
+	watch    Watch
+	logger   *zap.Logger
+}
+
+// NewServer creates the gRPC services. r4 holds the R4Service of each store, keyed by store name.
+// A nil limiter does not limit anything.
+func NewServer(
+	r4 map[string]services.R4Service,
+	payments services.PaymentService,
+	apiKeys services.APIKeyService,
+	limiter *ratelimit.Limiter,
+	watch Watch,
+	logger *zap.Logger,
+) *Server {
+	return &Server{
+		r4:       r4,
+		payments: payments,
+		apiKeys:  apiKeys,
+		limiter:  limiter,
+		watch:    watch,
+		logger:   logger,
+	}
+}
+
+// GRPCServer creates a grpc.Server with the services, the standard health service
+// reporting readiness, and reflection for tools like grpcurl
+func (s *Server) GRPCServer(readiness *health.Readiness) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	r4v1.RegisterR4ServiceServer(server, s)
+	r4v1.RegisterPaymentServiceServer(server, s)
+	healthpb.RegisterHealthServer(server, &healthServer{readiness: readiness})
+	reflection.Register(server)
+	return server
+}
@@ -0,0 +1,242 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/services"
+	"bone_appetit_r4_service/pkg/health"
+	r4v1 "bone_appetit_r4_service/pkg/pb/r4/v1"
+	"bone_appetit_r4_service/pkg/r4bank"
+	"bone_appetit_r4_service/pkg/ratelimit"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
+// fakeR4 answers GetOperationByID with codes in order, repeating the last one
+type fakeR4 struct {
+	services.R4Service
+	rate       float64
+	operations []string
+	polls      int
+}
+
+func (f *fakeR4) GetBCVTasaUSD(context.Context) (*models.BCVTasaUSDResponse, error) {
+	return &models.BCVTasaUSDResponse{Date: "2026-10-19", Rate: f.rate}, nil
+}
+
+func (f *fakeR4) GetOperationByID(_ context.Context, _ string) (*r4bank.GetOperationResponse, error) {
+	code := f.operations[min(f.polls, len(f.operations)-1)]
+	f.polls++
+	return &r4bank.GetOperationResponse{Code: code, Success: code == "ACCP"}, nil
+}
+
+func (f *fakeR4) GenerateOTP(context.Context, *models.OTPRequest) error {
+	return nil
+}
+
+type fakeKeys struct {
+	services.APIKeyService
+	keys map[string]*models.APIKey
+}
+
+func (f *fakeKeys) Authenticate(_ context.Context, key string) (*models.APIKey, error) {
+	if apiKey, ok := f.keys[key]; ok {
+		return apiKey, nil
+	}
+	return nil, services.ErrInvalidAPIKey
+}
+
+type testEnv struct {
+	bone, appa *fakeR4
+	readiness  *health.Readiness
+	conn       *grpc.ClientConn
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	if err := validation.RegisterGinValidators(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &testEnv{
+		bone:      &fakeR4{rate: 100, operations: []string{"AC00"}},
+		appa:      &fakeR4{rate: 200, operations: []string{"AC00"}},
+		readiness: health.NewReadiness(),
+	}
+	keys := &fakeKeys{keys: map[string]*models.APIKey{
+		"bone-key": {ID: 1, Store: "bone"},
+		"appa-key": {ID: 2, Store: "appa"},
+	}}
+	server := NewServer(
+		map[string]services.R4Service{"bone": e.bone, "appa": e.appa},
+		nil,
+		keys,
+		ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+			ratelimit.OTPPerTarget: {Requests: 1, Per: time.Hour},
+		}),
+		Watch{Interval: time.Millisecond, Timeout: time.Second},
+		zap.NewNop(),
+	).GRPCServer(e.readiness)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	e.conn = conn
+	return e
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, key)
+}
+
+func TestRequiresAPIKey(t *testing.T) {
+	e := newTestEnv(t)
+	client := r4v1.NewR4ServiceClient(e.conn)
+
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"unknown": withKey("other-key"),
+	} {
+		if _, err := client.GetBCVRate(ctx, &r4v1.GetBCVRateRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s key: err = %v, want Unauthenticated", name, err)
+		}
+	}
+
+	// The health check is open to the orchestrator
+	e.readiness.SetReady()
+	resp, err := healthpb.NewHealthClient(e.conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health = %v, %v, want SERVING", resp, err)
+	}
+}
+
+func TestCallsActForTheStoreOfTheKey(t *testing.T) {
+	e := newTestEnv(t)
+	client := r4v1.NewR4ServiceClient(e.conn)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(withKey("appa-key"), "x-request-id", "req-42")
+	resp, err := client.GetBCVRate(ctx, &r4v1.GetBCVRateRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Rate != e.appa.rate {
+		t.Fatalf("rate = %v, want the appa rate %v", resp.Rate, e.appa.rate)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-42" {
+		t.Fatalf("x-request-id = %v, want the caller's", got)
+	}
+}
+
+func TestInvalidRequestReportsFields(t *testing.T) {
+	e := newTestEnv(t)
+	client := r4v1.NewR4ServiceClient(e.conn)
+
+	_, err := client.GenerateOTP(withKey("bone-key"), &r4v1.GenerateOTPRequest{Bank: "0102", Amount: 10, Phone: "123", Dni: "V12345678"})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("err = %v, want InvalidArgument", err)
+	}
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	if len(fields) != 1 || fields[0] != "phone" {
+		t.Fatalf("invalid fields = %v, want [phone]", fields)
+	}
+}
+
+func TestGenerateOTPIsRateLimitedPerPhone(t *testing.T) {
+	e := newTestEnv(t)
+	client := r4v1.NewR4ServiceClient(e.conn)
+	req := &r4v1.GenerateOTPRequest{Bank: "0102", Amount: 10, Phone: "04141234567", Dni: "V12345678"}
+
+	if _, err := client.GenerateOTP(withKey("bone-key"), req); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.GenerateOTP(withKey("appa-key"), req)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("second OTP to the phone: err = %v, want ResourceExhausted", err)
+	}
+	for _, detail := range st.Details() {
+		if retry, ok := detail.(*errdetails.RetryInfo); ok && retry.RetryDelay.AsDuration() > 59*time.Minute {
+			return
+		}
+	}
+	t.Fatalf("details = %v, want RetryInfo of about an hour", st.Details())
+}
+
+func TestWatchOperationStreamsChanges(t *testing.T) {
+	e := newTestEnv(t)
+	e.bone.operations = []string{"AC00", "AC00", "AC00", "ACCP"}
+	client := r4v1.NewR4ServiceClient(e.conn)
+
+	stream, err := client.WatchOperation(withKey("bone-key"), &r4v1.WatchOperationRequest{Id: "op-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, resp.Operation.Code)
+	}
+	if len(codes) != 2 || codes[0] != "AC00" || codes[1] != "ACCP" {
+		t.Fatalf("streamed codes = %v, want [AC00 ACCP]", codes)
+	}
+	if e.bone.polls != 4 {
+		t.Fatalf("polled %d times, want 4", e.bone.polls)
+	}
+}
+
+func TestWatchOperationGivesUp(t *testing.T) {
+	e := newTestEnv(t)
+	client := r4v1.NewR4ServiceClient(e.conn)
+
+	stream, err := client.WatchOperation(withKey("bone-key"), &r4v1.WatchOperationRequest{Id: "op-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("first state: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("err = %v, want DeadlineExceeded once the watch timeout passes", err)
+	}
+}
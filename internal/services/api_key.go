@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/models"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/logs"
+)
+
+var (
+	// ErrInvalidAPIKey is returned for keys that do not exist or were revoked
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when revoking a key that does not exist
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// apiKeyPrefix starts every key so they are recognizable in configs and secret scanners
+const apiKeyPrefix = "r4k_"
+
+// lastUsedResolution limits how often authenticating a key writes its last use
+const lastUsedResolution = time.Minute
+
+type APIKeyService interface {
+	Create(ctx context.Context, name, store string) (*models.CreatedAPIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(db *gorm.DB) APIKeyService {
+	return &apiKeyService{db: db}
+}
+
+// Create issues a key for store. Only its hash is stored, the key is returned once.
+func (s *apiKeyService) Create(ctx context.Context, name, store string) (*models.CreatedAPIKey, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating API key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	row := dbModels.APIKey{
+		Name:    name,
+		Store:   store,
+		Prefix:  key[:len(apiKeyPrefix)+8],
+		KeyHash: hashAPIKey(key),
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		logs.FromContext(ctx).Error("failed to create API key", zap.Error(err), zap.String("store", store))
+		return nil, err
+	}
+
+	return &models.CreatedAPIKey{APIKey: toAPIKey(row), Key: key}, nil
+}
+
+// List returns every key, revoked ones included, newest first
+func (s *apiKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	var rows []dbModels.APIKey
+	if err := s.db.WithContext(ctx).Order("id DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, toAPIKey(row))
+	}
+	return keys, nil
+}
+
+// Revoke stops a key from authenticating. Revoking it again is a no-op.
+func (s *apiKeyService) Revoke(ctx context.Context, id int) error {
+	result := s.db.WithContext(ctx).Model(&dbModels.APIKey{}).
+		Where("id = ?", id).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, NOW())"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the active key matching key, or ErrInvalidAPIKey
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	var row dbModels.APIKey
+	err := s.db.WithContext(ctx).
+		Where("key_hash = ? AND revoked_at IS NULL", hashAPIKey(key)).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Only informative, a failure must not reject the call
+	err = s.db.WithContext(ctx).Model(&dbModels.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => ?))", row.ID, lastUsedResolution.Seconds()).
+		Update("last_used_at", gorm.Expr("NOW()")).Error
+	if err != nil {
+		logs.FromContext(ctx).Warn("could not record API key use", zap.Error(err), zap.Int("api_key_id", row.ID))
+	}
+
+	apiKey := toAPIKey(row)
+	return &apiKey, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIKey(row dbModels.APIKey) models.APIKey {
+	return models.APIKey{
+		ID:         row.ID,
+		Name:       row.Name,
+		Store:      row.Store,
+		Prefix:     row.Prefix,
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt,
+		RevokedAt:  row.RevokedAt,
+	}
+}
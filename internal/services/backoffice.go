@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/models"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+)
+
+var (
+	// ErrInvalidCredentials is returned for unknown emails, wrong passwords and disabled users alike
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrSessionExpired is returned for session tokens that do not exist or expired
+	ErrSessionExpired = errors.New("session expired")
+	// ErrUserExists is returned when creating a user with an email already taken
+	ErrUserExists = errors.New("a user with that email already exists")
+)
+
+// MinPasswordLength is the shortest password accepted for back-office users
+const MinPasswordLength = 12
+
+// dummyPasswordHash is compared against when the email is unknown, so a login takes
+// as long whether the user exists or not
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+// BackofficeUserService signs the support and finance staff in to the back office
+type BackofficeUserService interface {
+	Create(ctx context.Context, email, name, role, password string) (*models.BackofficeUser, error)
+	Login(ctx context.Context, email, password string) (*models.BackofficeSession, error)
+	Session(ctx context.Context, token string) (*models.BackofficeSession, error)
+	Logout(ctx context.Context, token string) error
+}
+
+type backofficeUserService struct {
+	db         *gorm.DB
+	sessionTTL time.Duration
+}
+
+// NewBackofficeUserService creates a new BackofficeUserService whose sessions last sessionTTL
+func NewBackofficeUserService(db *gorm.DB, sessionTTL time.Duration) BackofficeUserService {
+	return &backofficeUserService{db: db, sessionTTL: sessionTTL}
+}
+
+// Create adds a user. Only the bcrypt hash of the password is stored.
+func (s *backofficeUserService) Create(ctx context.Context, email, name, role, password string) (*models.BackofficeUser, error) {
+	if role != dbModels.RoleSupport && role != dbModels.RoleFinance {
+		return nil, fmt.Errorf("unknown role %q, must be %s or %s", role, dbModels.RoleSupport, dbModels.RoleFinance)
+	}
+	if len(password) < MinPasswordLength {
+		return nil, fmt.Errorf("the password must have at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hashing password: %w", err)
+	}
+
+	row := dbModels.BackofficeUser{
+		Email:        strings.TrimSpace(email),
+		Name:         name,
+		Role:         role,
+		PasswordHash: string(hash),
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+
+	user := toBackofficeUser(row)
+	return &user, nil
+}
+
+// Login checks the password of the user with email and opens a session
+func (s *backofficeUserService) Login(ctx context.Context, email, password string) (*models.BackofficeSession, error) {
+	var row dbModels.BackofficeUser
+	err := s.db.WithContext(ctx).
+		Where("lower(email) = lower(?) AND disabled_at IS NULL", strings.TrimSpace(email)).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(row.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	session := dbModels.BackofficeSession{
+		TokenHash: hashAPIKey(token),
+		UserID:    row.ID,
+		CSRFToken: csrfToken,
+		ExpiresAt: time.Now().Add(s.sessionTTL),
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Logins are rare enough to clean the expired sessions up as they happen
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&dbModels.BackofficeSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("last_login_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BackofficeSession{
+		Token:     token,
+		CSRFToken: csrfToken,
+		ExpiresAt: session.ExpiresAt,
+		User:      toBackofficeUser(row),
+	}, nil
+}
+
+// Session returns the open session of token, or ErrSessionExpired
+func (s *backofficeUserService) Session(ctx context.Context, token string) (*models.BackofficeSession, error) {
+	var session dbModels.BackofficeSession
+	err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ?", hashAPIKey(token), time.Now()).
+		Take(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var row dbModels.BackofficeUser
+	err = s.db.WithContext(ctx).Where("id = ? AND disabled_at IS NULL", session.UserID).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BackofficeSession{
+		Token:     token,
+		CSRFToken: session.CSRFToken,
+		ExpiresAt: session.ExpiresAt,
+		User:      toBackofficeUser(row),
+	}, nil
+}
+
+// Logout closes the session of token. Closing it again is a no-op.
+func (s *backofficeUserService) Logout(ctx context.Context, token string) error {
+	return s.db.WithContext(ctx).Where("token_hash = ?", hashAPIKey(token)).Delete(&dbModels.BackofficeSession{}).Error
+}
+
+func randomToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func toBackofficeUser(row dbModels.BackofficeUser) models.BackofficeUser {
+	return models.BackofficeUser{
+		ID:          row.ID,
+		Email:       row.Email,
+		Name:        row.Name,
+		Role:        row.Role,
+		CreatedAt:   row.CreatedAt,
+		LastLoginAt: row.LastLoginAt,
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/outbox"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// ErrOperationNotFound is returned for immediate debits the service has no record of
+var ErrOperationNotFound = errors.New("operation not found")
+
+const defaultOperationsLimit = 100
+
+// OperationService keeps the immediate debits of the stores and the history of their
+// status, written as R4Service requests and polls them. A debit reaching its final code
+// emits debit.accepted or debit.rejected, however that code was seen.
+type OperationService interface {
+	// Started records a debit R4 accepted to process
+	Started(ctx context.Context, store string, req *models.ValidateOTPRequest, resp *r4bank.ValidateDebitInmediateResponse) error
+	// Observed records the status of a debit when it changed, with its event when it left
+	// AC00. Debits not started through the service are ignored.
+	Observed(ctx context.Context, store, operationID string, op *r4bank.GetOperationResponse) error
+	List(ctx context.Context, store string, query *models.OperationQuery) ([]models.Operation, error)
+	Get(ctx context.Context, store, operationID string) (*models.OperationDetail, error)
+}
+
+type operationService struct {
+	db *gorm.DB
+}
+
+// NewOperationService creates a new OperationService
+func NewOperationService(db *gorm.DB) OperationService {
+	return &operationService{db: db}
+}
+
+func (s *operationService) Started(ctx context.Context, store string, req *models.ValidateOTPRequest, resp *r4bank.ValidateDebitInmediateResponse) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := dbModels.DebitOperation{
+			Store:       store,
+			OperationID: resp.ID,
+			Bank:        req.Bank,
+			Amount:      req.Amount,
+			Phone:       req.Phone,
+			DNI:         req.DNI,
+			Name:        req.Name,
+			Code:        resp.Code,
+			Reference:   resp.Reference,
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&dbModels.DebitOperationStatus{
+			DebitOperationID: row.ID,
+			Code:             resp.Code,
+			Reference:        resp.Reference,
+		}).Error
+		if err != nil || resp.Code == debitPending {
+			return err
+		}
+		return writeDebitEvent(tx, row)
+	})
+}
+
+func (s *operationService) Observed(ctx context.Context, store, operationID string, op *r4bank.GetOperationResponse) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Polls of the same debit from several requests must not record a change twice
+		var row dbModels.DebitOperation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("store = ? AND operation_id = ?", store, operationID).
+			Take(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if row.Code == op.Code && row.Reference == op.Reference && row.Success == op.Success {
+			return nil
+		}
+
+		changedCode := row.Code != op.Code
+		err = tx.Model(&row).Updates(map[string]any{
+			"code":      op.Code,
+			"reference": op.Reference,
+			"success":   op.Success,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&dbModels.DebitOperationStatus{
+			DebitOperationID: row.ID,
+			Code:             op.Code,
+			Reference:        op.Reference,
+			Success:          op.Success,
+		}).Error
+		if err != nil || !changedCode || op.Code == debitPending {
+			return err
+		}
+		return writeDebitEvent(tx, row)
+	})
+}
+
+// writeDebitEvent stores the event of a debit that reached its final code. Its ID comes
+// from the operation and the code, so the debit has a single event for each outcome.
+func writeDebitEvent(tx *gorm.DB, row dbModels.DebitOperation) error {
+	eventType := events.DebitRejected
+	if row.Code == debitAccepted {
+		eventType = events.DebitAccepted
+	}
+	event := events.NewOnce(eventType, row.Store, row.OperationID+"/"+row.Code, events.Debit{
+		OperationID: row.OperationID,
+		Code:        row.Code,
+		Message:     debitMessage(row.Code),
+		Reference:   row.Reference,
+		Amount:      row.Amount,
+		Bank:        row.Bank,
+	})
+	if err := outbox.Write(tx, event); err != nil {
+		return fmt.Errorf("storing %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// List returns the debits of store matching query, newest first
+func (s *operationService) List(ctx context.Context, store string, query *models.OperationQuery) ([]models.Operation, error) {
+	tx := s.db.WithContext(ctx).Where("store = ?", store)
+	if query.Phone != "" {
+		tx = tx.Where("phone = ?", query.Phone)
+	}
+	if query.DNI != "" {
+		tx = tx.Where("dni = ?", query.DNI)
+	}
+	if query.Reference != "" {
+		tx = tx.Where("reference = ?", query.Reference)
+	}
+	if query.Code != "" {
+		tx = tx.Where("code = ?", query.Code)
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultOperationsLimit
+	}
+
+	var rows []dbModels.DebitOperation
+	if err := tx.Order("id DESC").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	operations := make([]models.Operation, 0, len(rows))
+	for _, row := range rows {
+		operations = append(operations, toOperation(row))
+	}
+	return operations, nil
+}
+
+// Get returns a debit of store with its status history
+func (s *operationService) Get(ctx context.Context, store, operationID string) (*models.OperationDetail, error) {
+	var row dbModels.DebitOperation
+	err := s.db.WithContext(ctx).Where("store = ? AND operation_id = ?", store, operationID).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []dbModels.DebitOperationStatus
+	if err := s.db.WithContext(ctx).Where("debit_operation_id = ?", row.ID).Order("id").Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+
+	detail := &models.OperationDetail{Operation: toOperation(row), History: make([]models.OperationStatus, 0, len(statuses))}
+	for _, status := range statuses {
+		detail.History = append(detail.History, models.OperationStatus{
+			Code:      status.Code,
+			Reference: status.Reference,
+			Success:   status.Success,
+			At:        status.CreatedAt,
+		})
+	}
+	return detail, nil
+}
+
+func toOperation(row dbModels.DebitOperation) models.Operation {
+	return models.Operation{
+		ID:        row.OperationID,
+		Store:     row.Store,
+		Bank:      row.Bank,
+		Amount:    row.Amount,
+		Phone:     row.Phone,
+		DNI:       row.DNI,
+		Name:      row.Name,
+		Code:      row.Code,
+		Reference: row.Reference,
+		Success:   row.Success,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
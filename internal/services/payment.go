@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/banks"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/validation"
+)
+
+const (
+	defaultPaymentsLimit = 100
+	exportBatchSize      = 500
+)
+
+var (
+	// ErrPaymentNotFound is returned when linking a payment that does not exist
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrOrderAlreadyLinked is returned when linking a payment already linked to another order
+	ErrOrderAlreadyLinked = errors.New("payment already linked to an order")
+)
+
+var paymentExportHeader = []string{
+	"id", "reference", "date", "amount", "sender_phone", "commerce_phone",
+	"issuing_bank", "issuing_bank_name", "order_id", "created_at",
+}
+
+type PaymentService interface {
+	FindPayments(ctx context.Context, storeName string, query *models.PaymentQuery) (*models.PaymentListResponse, error)
+	ExportPayments(ctx context.Context, storeName string, query *models.PaymentQuery, w io.Writer) error
+	// LinkOrder sets the order a payment was made for, a nil orderID unlinks it
+	LinkOrder(ctx context.Context, storeName string, paymentID int, orderID *int) error
+}
+
+type paymentService struct {
+	db      *gorm.DB
+	readDB  *gorm.DB
+	loc     *time.Location
+	catalog *banks.Catalog
+}
+
+// NewPaymentService creates a new PaymentService. Searches and exports run on readDB,
+// which may be a replica.
+func NewPaymentService(db, readDB *gorm.DB, loc *time.Location, catalog *banks.Catalog) PaymentService {
+	return &paymentService{db: db, readDB: readDB, loc: loc, catalog: catalog}
+}
+
+// FindPayments lists the mobile payments of a store matching the query
+func (s *paymentService) FindPayments(ctx context.Context, storeName string, query *models.PaymentQuery) (*models.PaymentListResponse, error) {
+	tx, err := s.filter(ctx, storeName, query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPaymentsLimit
+	}
+
+	var rows []dbModels.R4MobilePayment
+	if err := tx.Order("id DESC").Limit(limit).Offset(query.Offset).Find(&rows).Error; err != nil {
+		logs.FromContext(ctx).Error("failed to query mobile payments", zap.Error(err), zap.String("store", storeName))
+		return nil, err
+	}
+
+	payments := make([]models.Payment, 0, len(rows))
+	for i := range rows {
+		payments = append(payments, s.toPayment(&rows[i]))
+	}
+
+	return &models.PaymentListResponse{
+		Payments: payments,
+		Limit:    limit,
+		Offset:   query.Offset,
+	}, nil
+}
+
+// ExportPayments writes every payment matching the query to w as CSV
+func (s *paymentService) ExportPayments(ctx context.Context, storeName string, query *models.PaymentQuery, w io.Writer) error {
+	tx, err := s.filter(ctx, storeName, query)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(paymentExportHeader); err != nil {
+		return err
+	}
+
+	var batch []dbModels.R4MobilePayment
+	result := tx.Order("id").FindInBatches(&batch, exportBatchSize, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := writer.Write(s.toCSVRecord(&batch[i])); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if result.Error != nil {
+		logs.FromContext(ctx).Error("failed to export mobile payments", zap.Error(result.Error), zap.String("store", storeName))
+		return result.Error
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// filter builds the query over the payments table of the store
+func (s *paymentService) filter(ctx context.Context, storeName string, query *models.PaymentQuery) (*gorm.DB, error) {
+	tableName, err := mobilePaymentTable(storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.readDB.WithContext(ctx).Table(tableName)
+	if query.From != "" {
+		tx = tx.Where("date >= ?", query.From)
+	}
+	if query.To != "" {
+		tx = tx.Where("date <= ?", query.To)
+	}
+	if query.Reference != "" {
+		tx = tx.Where("reference = ?", query.Reference)
+	}
+	if query.SenderPhone != "" {
+		tx = tx.Where("sender_phone = ?", validation.NormalizePhone(query.SenderPhone))
+	}
+	if query.Bank != "" {
+		tx = tx.Where("issuing_bank = ?", banks.Normalize(query.Bank))
+	}
+
+	return tx, nil
+}
+
+// LinkOrder links a payment to orderID. Relinking a payment to another order must
+// unlink it first, so a payment is never moved between orders by mistake.
+func (s *paymentService) LinkOrder(ctx context.Context, storeName string, paymentID int, orderID *int) error {
+	tableName, err := mobilePaymentTable(storeName)
+	if err != nil {
+		return err
+	}
+
+	tx := s.db.WithContext(ctx).Table(tableName).Where("id = ?", paymentID)
+	if orderID != nil {
+		tx = tx.Where("order_id IS NULL OR order_id = ?", *orderID)
+	}
+	result := tx.Update("order_id", orderID)
+	if result.Error != nil {
+		logs.FromContext(ctx).Error("failed to link mobile payment", zap.Error(result.Error), zap.String("store", storeName), zap.Int("payment_id", paymentID))
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Table(tableName).Where("id = ?", paymentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPaymentNotFound
+	}
+	return ErrOrderAlreadyLinked
+}
+
+func (s *paymentService) toPayment(row *dbModels.R4MobilePayment) models.Payment {
+	return models.Payment{
+		ID:              row.ID,
+		Reference:       row.Reference,
+		Amount:          row.Amount,
+		SenderPhone:     row.SenderPhone,
+		CommercePhone:   row.CommercePhone,
+		IssuingBank:     row.IssuingBank,
+		IssuingBankName: s.catalog.ShortName(row.IssuingBank),
+		OrderID:         row.OrderID,
+		Date:            row.Date,
+		CreatedAt:       row.CreatedAt,
+	}
+}
+
+func (s *paymentService) toCSVRecord(row *dbModels.R4MobilePayment) []string {
+	orderID := ""
+	if row.OrderID != nil {
+		orderID = strconv.Itoa(*row.OrderID)
+	}
+
+	return []string{
+		strconv.Itoa(row.ID),
+		row.Reference,
+		row.Date.Format("2006-01-02"),
+		strconv.FormatFloat(row.Amount, 'f', 2, 64),
+		row.SenderPhone,
+		row.CommercePhone,
+		row.IssuingBank,
+		s.catalog.ShortName(row.IssuingBank),
+		orderID,
+		row.CreatedAt.In(s.loc).Format(time.RFC3339),
+	}
+}
+
+// mobilePaymentTable returns the table holding the mobile payments of a store
+func mobilePaymentTable(storeName string) (string, error) {
+	switch storeName {
+	case "bone":
+		return dbModels.R4MobilePayment{}.TableName(), nil
+	case "appa":
+		return dbModels.R4AppaMobilePayment{}.TableName(), nil
+	default:
+		return "", fmt.Errorf("unknown store name: %s", storeName)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bone_appetit_r4_service/internal/models"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+)
+
+var (
+	// ErrPayoutNotFound is returned for change payouts that do not exist
+	ErrPayoutNotFound = errors.New("change payout not found")
+	// ErrPayoutNotPending is returned when deciding on a payout already approved or rejected
+	ErrPayoutNotPending = errors.New("change payout is not pending")
+	// ErrPayoutNotStuck is returned when resolving a payout that is not left paying
+	ErrPayoutNotStuck = errors.New("change payout is not stuck paying")
+)
+
+const defaultPayoutsLimit = 100
+
+// PayoutStuckAfter is how long a payout has to be paying before it can be resolved by
+// hand, well past the R4 timeout so an approval still running is never raced
+const PayoutStuckAfter = 10 * time.Minute
+
+// ChangePayoutService records the change payouts of the stores. Payouts above the
+// approval threshold are held until a finance user approves or rejects them.
+type ChangePayoutService interface {
+	RequiresApproval(amount float64) bool
+	// Hold records a payout waiting for approval
+	Hold(ctx context.Context, store string, req *models.ChangePaidRequest) (*models.ChangePayout, error)
+	// Record records a payout paid right away, or that R4 refused when payErr is set
+	Record(ctx context.Context, store string, req *models.ChangePaidRequest, reference string, payErr error) (*models.ChangePayout, error)
+	// Claim moves a pending payout to paying, so it is paid only once however many
+	// users approve it at the same time
+	Claim(ctx context.Context, store string, id int64, user string) (*models.ChangePayout, error)
+	// Settle records the outcome of paying a claimed payout
+	Settle(ctx context.Context, id int64, reference string, payErr error) error
+	// Resolve records the outcome of a payout left paying for longer than PayoutStuckAfter,
+	// when the service stopped between paying and settling it. Whether R4 paid it is checked
+	// by hand: reference is the one in the bank statement, empty when it was not paid.
+	Resolve(ctx context.Context, store string, id int64, user, reference string) error
+	Reject(ctx context.Context, store string, id int64, user string) error
+	List(ctx context.Context, store, status string) ([]models.ChangePayout, error)
+	Get(ctx context.Context, store string, id int64) (*models.ChangePayout, error)
+}
+
+type changePayoutService struct {
+	db            *gorm.DB
+	approvalAbove float64
+}
+
+// NewChangePayoutService creates a new ChangePayoutService. Payouts above approvalAbove
+// need approval, none do when it is 0.
+func NewChangePayoutService(db *gorm.DB, approvalAbove float64) ChangePayoutService {
+	return &changePayoutService{db: db, approvalAbove: approvalAbove}
+}
+
+func (s *changePayoutService) RequiresApproval(amount float64) bool {
+	return s.approvalAbove > 0 && amount > s.approvalAbove
+}
+
+func (s *changePayoutService) Hold(ctx context.Context, store string, req *models.ChangePaidRequest) (*models.ChangePayout, error) {
+	row := newPayoutRow(store, req, dbModels.PayoutPending)
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	payout := toChangePayout(row)
+	return &payout, nil
+}
+
+func (s *changePayoutService) Record(ctx context.Context, store string, req *models.ChangePaidRequest, reference string, payErr error) (*models.ChangePayout, error) {
+	row := newPayoutRow(store, req, dbModels.PayoutPaid)
+	row.Reference = reference
+	if payErr != nil {
+		row.Status = dbModels.PayoutFailed
+		message := payErr.Error()
+		row.Error = &message
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	payout := toChangePayout(row)
+	return &payout, nil
+}
+
+func (s *changePayoutService) Claim(ctx context.Context, store string, id int64, user string) (*models.ChangePayout, error) {
+	return s.decide(ctx, store, id, user, dbModels.PayoutPaying)
+}
+
+func (s *changePayoutService) Settle(ctx context.Context, id int64, reference string, payErr error) error {
+	updates := map[string]any{"status": dbModels.PayoutPaid, "reference": reference}
+	if payErr != nil {
+		updates = map[string]any{"status": dbModels.PayoutFailed, "error": payErr.Error()}
+	}
+	return s.db.WithContext(ctx).Model(&dbModels.ChangePayout{}).
+		Where("id = ? AND status = ?", id, dbModels.PayoutPaying).
+		Updates(updates).Error
+}
+
+func (s *changePayoutService) Resolve(ctx context.Context, store string, id int64, user, reference string) error {
+	updates := map[string]any{"status": dbModels.PayoutPaid, "reference": reference}
+	if reference == "" {
+		updates = map[string]any{"status": dbModels.PayoutFailed, "error": "marcado como fallido por " + user}
+	}
+	result := s.db.WithContext(ctx).Model(&dbModels.ChangePayout{}).
+		Where("id = ? AND store = ? AND status = ? AND decided_at < ?", id, store, dbModels.PayoutPaying, time.Now().Add(-PayoutStuckAfter)).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(ctx, store, id); err != nil {
+			return err
+		}
+		return ErrPayoutNotStuck
+	}
+	return nil
+}
+
+func (s *changePayoutService) Reject(ctx context.Context, store string, id int64, user string) error {
+	_, err := s.decide(ctx, store, id, user, dbModels.PayoutRejected)
+	return err
+}
+
+// decide moves a pending payout of store to status on behalf of user
+func (s *changePayoutService) decide(ctx context.Context, store string, id int64, user, status string) (*models.ChangePayout, error) {
+	var row dbModels.ChangePayout
+	result := s.db.WithContext(ctx).Model(&row).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND store = ? AND status = ?", id, store, dbModels.PayoutPending).
+		Updates(map[string]any{"status": status, "decided_by": user, "decided_at": time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(ctx, store, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrPayoutNotPending
+	}
+	payout := toChangePayout(row)
+	return &payout, nil
+}
+
+// List returns the payouts of store, newest first, only those in status unless it is empty
+func (s *changePayoutService) List(ctx context.Context, store, status string) ([]models.ChangePayout, error) {
+	tx := s.db.WithContext(ctx).Where("store = ?", store)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	var rows []dbModels.ChangePayout
+	if err := tx.Order("id DESC").Limit(defaultPayoutsLimit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	payouts := make([]models.ChangePayout, 0, len(rows))
+	for _, row := range rows {
+		payouts = append(payouts, toChangePayout(row))
+	}
+	return payouts, nil
+}
+
+func (s *changePayoutService) Get(ctx context.Context, store string, id int64) (*models.ChangePayout, error) {
+	var row dbModels.ChangePayout
+	err := s.db.WithContext(ctx).Where("id = ? AND store = ?", id, store).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPayoutNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	payout := toChangePayout(row)
+	return &payout, nil
+}
+
+func newPayoutRow(store string, req *models.ChangePaidRequest, status string) dbModels.ChangePayout {
+	return dbModels.ChangePayout{
+		Store:   store,
+		Bank:    req.Bank,
+		Amount:  req.Amount,
+		Phone:   req.Phone,
+		DNI:     req.DNI,
+		Concept: req.Concept,
+		Status:  status,
+	}
+}
+
+// payoutRequest rebuilds the request a held payout was created from, to pay it
+func payoutRequest(payout *models.ChangePayout) *models.ChangePaidRequest {
+	return &models.ChangePaidRequest{
+		Bank:    payout.Bank,
+		Amount:  payout.Amount,
+		Phone:   payout.Phone,
+		DNI:     payout.DNI,
+		Concept: payout.Concept,
+	}
+}
+
+func toChangePayout(row dbModels.ChangePayout) models.ChangePayout {
+	return models.ChangePayout{
+		ID:        row.ID,
+		Store:     row.Store,
+		Bank:      row.Bank,
+		Amount:    row.Amount,
+		Phone:     row.Phone,
+		DNI:       row.DNI,
+		Concept:   row.Concept,
+		Status:    row.Status,
+		Reference: row.Reference,
+		Error:     row.Error,
+		DecidedBy: row.DecidedBy,
+		DecidedAt: row.DecidedAt,
+		CreatedAt: row.CreatedAt,
+	}
+}
@@ -2,15 +2,21 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"bone_appetit_r4_service/internal/events"
 	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
 	"bone_appetit_r4_service/pkg/r4bank"
+	"bone_appetit_r4_service/pkg/telemetry"
 )
 
 type R4Service interface {
@@ -19,11 +25,26 @@ type R4Service interface {
 	ValidateImmediateDebit(ctx context.Context, req *models.ValidateOTPRequest) (*models.ValidateDebitInmediateResponse, error)
 	ChangePaid(ctx context.Context, req *models.ChangePaidRequest) (*models.ChangePaidResponse, error)
 	GetOperationByID(ctx context.Context, operationID string) (*r4bank.GetOperationResponse, error)
+	// ApproveChange pays a change payout held for approval, on behalf of user
+	ApproveChange(ctx context.Context, payoutID int64, user string) (*models.ChangePayout, error)
 }
 
+// ErrApprovalDisabled is returned when approving payouts without a ChangePayoutService
+var ErrApprovalDisabled = errors.New("change payout approval is disabled")
+
 type r4Service struct {
-	r4Client *r4bank.RestClient
-	Logger   *zap.Logger
+	storeName  string
+	r4Client   r4bank.Client
+	polling    DebitPolling
+	publisher  events.Publisher
+	operations OperationService
+	payouts    ChangePayoutService
+}
+
+// DebitPolling controls how long ValidateImmediateDebit waits for a debit to leave AC00
+type DebitPolling struct {
+	Attempts int
+	Interval time.Duration
 }
 
 var _DebitInmediateSpecialResponse = map[string]string{
@@ -37,41 +58,48 @@ var _DebitInmediateSpecialResponse = map[string]string{
 
 const _debitInmetiateGenericError = "ocurrió un error al procesar la solicitud"
 
-// NewR4Service creates a new R4Service
-func NewR4Service(logger *zap.Logger, r4Client *r4bank.RestClient) R4Service {
+// Codes of an immediate debit waiting for the bank and accepted by it
+const (
+	debitPending  = "AC00"
+	debitAccepted = "ACCP"
+)
+
+// debitMessage is the message shown to the customer for the code of a debit
+func debitMessage(code string) string {
+	if msg, exist := _DebitInmediateSpecialResponse[code]; exist {
+		return msg
+	}
+	return _debitInmetiateGenericError
+}
+
+// NewR4Service creates a new R4Service. The debits are recorded in operations and the
+// change payouts in payouts, either may be nil to not record them.
+func NewR4Service(storeName string, r4Client r4bank.Client, polling DebitPolling, publisher events.Publisher, operations OperationService, payouts ChangePayoutService) R4Service {
 	return &r4Service{
-		r4Client: r4Client,
-		Logger:   logger,
+		storeName:  storeName,
+		r4Client:   r4Client,
+		polling:    polling,
+		publisher:  publisher,
+		operations: operations,
+		payouts:    payouts,
 	}
 }
 
 // GetBCVTasaUSD retrieves the BCV exchange rate for USD
 func (r *r4Service) GetBCVTasaUSD(ctx context.Context) (*models.BCVTasaUSDResponse, error) {
-	var (
-		dateValue = time.Now().Format("2006-01-02")
-		currency  = "USD"
-	)
-
-	hmacInput := dateValue + currency
-	payload := map[string]string{
-		"Moneda":     currency,
-		"Fechavalor": dateValue,
+	req := r4bank.BCVRateRequest{
+		Currency: "USD",
+		Date:     time.Now().Format("2006-01-02"),
 	}
 
-	resp, err := r.r4Client.Do(ctx, hmacInput, payload, "MBbcv")
+	r4Resp, err := r.r4Client.BCVRate(ctx, req)
 	if err != nil {
-		r.Logger.Error(err.Error(), zap.Any("payload", payload))
+		logs.FromContext(ctx).Error(err.Error(), zap.String("date", req.Date))
 		return nil, fmt.Errorf("error en request: %w", err)
 	}
 
-	var r4Resp r4bank.BCVResponse
-	if err := json.Unmarshal(resp, &r4Resp); err != nil {
-		r.Logger.Error(err.Error(), zap.Any("response", string(resp)))
-		return nil, fmt.Errorf("error decodificando respuesta: %w", err)
-	}
-
 	if r4Resp.Code != "00" {
-		r.Logger.Error("R4 API error", zap.String("code", r4Resp.Code), zap.Any("payload", payload))
+		logs.FromContext(ctx).Error("R4 API error", zap.String("code", r4Resp.Code), zap.String("date", req.Date))
 		return nil, errors.New("R4 API returned an error")
 	}
 
@@ -81,63 +109,118 @@ func (r *r4Service) GetBCVTasaUSD(ctx context.Context) (*models.BCVTasaUSDRespon
 	}, nil
 }
 
-// ChangePaid returns paid in Bolivares
+// ChangePaid returns paid in Bolivares. Payouts above the approval threshold are held
+// pending until a finance user approves them in the back office.
 func (r *r4Service) ChangePaid(ctx context.Context, req *models.ChangePaidRequest) (*models.ChangePaidResponse, error) {
-	hmacInput := req.Phone + fmt.Sprintf("%.2f", req.Amount) + req.Bank + req.DNI
-	payload := map[string]string{
-		"TelefonoDestino": req.Phone,
-		"Cedula":          req.DNI,
-		"Banco":           req.Bank,
-		"Monto":           fmt.Sprintf("%.2f", req.Amount),
-		"Concepto":        req.Concept,
+	if r.payouts != nil && r.payouts.RequiresApproval(req.Amount) {
+		payout, err := r.payouts.Hold(ctx, r.storeName, req)
+		if err != nil {
+			logs.FromContext(ctx).Error("failed to hold change payout", zap.Error(err), zap.Any("request", req))
+			return nil, fmt.Errorf("error guardando el vuelto: %w", err)
+		}
+		metrics.ChangePayouts.WithLabelValues(r.storeName, "held").Inc()
+		logs.FromContext(ctx).Info("change payout held for approval", zap.Int64("payout_id", payout.ID), zap.Float64("amount", req.Amount))
+		return &models.ChangePaidResponse{Status: models.ChangeStatusPending, PayoutID: payout.ID}, nil
+	}
+
+	reference, payErr := r.payChange(ctx, req)
+
+	resp := &models.ChangePaidResponse{Reference: reference, Status: models.ChangeStatusPaid}
+	if r.payouts != nil {
+		payout, err := r.payouts.Record(ctx, r.storeName, req, reference, payErr)
+		if err != nil {
+			// The money moved or not regardless, the caller must know the outcome
+			logs.FromContext(ctx).Error("failed to record change payout", zap.Error(err), zap.String("reference", reference))
+		} else {
+			resp.PayoutID = payout.ID
+		}
+	}
+	if payErr != nil {
+		return nil, payErr
+	}
+	return resp, nil
+}
+
+// ApproveChange claims a held payout, pays it and records the outcome
+func (r *r4Service) ApproveChange(ctx context.Context, payoutID int64, user string) (*models.ChangePayout, error) {
+	if r.payouts == nil {
+		return nil, ErrApprovalDisabled
 	}
 
-	resp, err := r.r4Client.Do(ctx, hmacInput, payload, "MBvuelto")
+	payout, err := r.payouts.Claim(ctx, r.storeName, payoutID, user)
 	if err != nil {
-		r.Logger.Error(err.Error(), zap.Any("payload", payload))
-		return nil, fmt.Errorf("error en request: %w", err)
+		return nil, err
+	}
+	logs.FromContext(ctx).Info("change payout approved", zap.Int64("payout_id", payoutID), zap.String("user", user))
+
+	// Once claimed the payout is paid and settled even if the user goes away, or it would
+	// be left paying with the money sent
+	ctx = context.WithoutCancel(ctx)
+	reference, payErr := r.payChange(ctx, payoutRequest(payout))
+	if err := r.payouts.Settle(ctx, payoutID, reference, payErr); err != nil {
+		logs.FromContext(ctx).Error("failed to settle change payout", zap.Error(err), zap.Int64("payout_id", payoutID), zap.String("reference", reference))
+		return nil, err
+	}
+	if payErr != nil {
+		return nil, payErr
+	}
+	return r.payouts.Get(ctx, r.storeName, payoutID)
+}
+
+// payChange sends the change through R4, returning its reference
+func (r *r4Service) payChange(ctx context.Context, req *models.ChangePaidRequest) (string, error) {
+	changeReq := r4bank.ChangeRequest{
+		Phone:   req.Phone,
+		DNI:     req.DNI,
+		Bank:    req.Bank,
+		Amount:  r4bank.Amount(req.Amount),
+		Concept: req.Concept,
 	}
 
-	var changeResp r4bank.ChangePaidResponse
-	if err := json.Unmarshal(resp, &changeResp); err != nil {
-		r.Logger.Error(err.Error(), zap.Any("response", string(resp)))
-		return nil, fmt.Errorf("error decodificando respuesta: %w", err)
+	changeResp, err := r.r4Client.Change(ctx, changeReq)
+	if err != nil {
+		metrics.ChangePayouts.WithLabelValues(r.storeName, "error").Inc()
+		logs.FromContext(ctx).Error(err.Error(), zap.Any("request", changeReq))
+		return "", fmt.Errorf("error en request: %w", err)
 	}
 
 	if changeResp.Code != "00" {
-		r.Logger.Error("R4 Change Paid API error", zap.String("code", changeResp.Code), zap.Any("payload", payload))
-		return nil, errors.New("R4 Change Paid API returned an error")
+		metrics.ChangePayouts.WithLabelValues(r.storeName, "rejected").Inc()
+		logs.FromContext(ctx).Error("R4 Change Paid API error", zap.String("code", changeResp.Code), zap.Any("request", changeReq))
+		return "", errors.New("R4 Change Paid API returned an error")
 	}
 
-	return &models.ChangePaidResponse{
-		Reference: fmt.Sprintf("%d", changeResp.Reference),
-	}, nil
+	metrics.ChangePayouts.WithLabelValues(r.storeName, "paid").Inc()
+	metrics.ChangePayoutAmount.WithLabelValues(r.storeName).Add(req.Amount)
+
+	reference := fmt.Sprintf("%d", changeResp.Reference)
+	publish(ctx, r.publisher, events.ChangeSent, r.storeName, events.Change{
+		Reference: reference,
+		Amount:    req.Amount,
+		Bank:      req.Bank,
+		Phone:     req.Phone,
+	})
+
+	return reference, nil
 }
 
 // GenerateOTP generates a one-time password (OTP) for secure transactions
 func (r *r4Service) GenerateOTP(ctx context.Context, req *models.OTPRequest) error {
-	hmacInput := req.Bank + fmt.Sprintf("%.2f", req.Amount) + req.Phone + req.DNI
-	payload := map[string]string{
-		"Banco":    req.Bank,
-		"Monto":    fmt.Sprintf("%.2f", req.Amount),
-		"Telefono": req.Phone,
-		"Cedula":   req.DNI,
+	otpReq := r4bank.OTPRequest{
+		Bank:   req.Bank,
+		Amount: r4bank.Amount(req.Amount),
+		Phone:  req.Phone,
+		DNI:    req.DNI,
 	}
 
-	resp, err := r.r4Client.Do(ctx, hmacInput, payload, "GenerarOtp")
+	otpResp, err := r.r4Client.GenerateOTP(ctx, otpReq)
 	if err != nil {
-		r.Logger.Error(err.Error(), zap.Any("payload", payload))
+		logs.FromContext(ctx).Error(err.Error(), zap.Any("request", otpReq))
 		return fmt.Errorf("error en request: %w", err)
 	}
 
-	var otpResp r4bank.OTPResponse
-	if err := json.Unmarshal(resp, &otpResp); err != nil {
-		r.Logger.Error(err.Error(), zap.Any("response", string(resp)))
-		return fmt.Errorf("error decodificando respuesta: %w", err)
-	}
-
 	if otpResp.Code != "202" {
-		r.Logger.Error("R4 OTP API error", zap.String("code", otpResp.Message), zap.Any("payload", payload))
+		logs.FromContext(ctx).Error("R4 OTP API error", zap.String("code", otpResp.Message), zap.Any("request", otpReq))
 		return errors.New("R4 OTP API returned an error")
 	}
 
@@ -151,59 +234,50 @@ type ValidateDebitInmediateResponse struct {
 
 // ValidateImmediateDebit validates an immediate debit transaction using the provided OTP
 func (r *r4Service) ValidateImmediateDebit(ctx context.Context, req *models.ValidateOTPRequest) (*models.ValidateDebitInmediateResponse, error) {
-
-	hmacInput := req.Bank + req.DNI + req.Phone + fmt.Sprintf("%.2f", req.Amount) + req.OTP
-	payload := map[string]string{
-		"Banco":    req.Bank,
-		"Monto":    fmt.Sprintf("%.2f", req.Amount),
-		"Telefono": req.Phone,
-		"Cedula":   req.DNI,
-		"Nombre":   req.Name,
-		"OTP":      req.OTP,
-		"Concepto": req.Concept,
+	debitReq := r4bank.ImmediateDebitRequest{
+		Bank:    req.Bank,
+		Amount:  r4bank.Amount(req.Amount),
+		Phone:   req.Phone,
+		DNI:     req.DNI,
+		Name:    req.Name,
+		OTP:     req.OTP,
+		Concept: req.Concept,
 	}
 
-	resp, err := r.r4Client.Do(ctx, hmacInput, payload, "DebitoInmediato")
+	validateResp, err := r.r4Client.ImmediateDebit(ctx, debitReq)
 	if err != nil {
-		r.Logger.Error(err.Error(), zap.Any("payload", payload))
+		logs.FromContext(ctx).Error(err.Error(), zap.Any("request", debitReq))
 		return nil, err
 	}
-
-	var validateResp r4bank.ValidateDebitInmediateResponse
-	if err := json.Unmarshal(resp, &validateResp); err != nil {
-		r.Logger.Error(err.Error(), zap.Any("response", string(resp)))
-		return nil, err
+	if r.operations != nil {
+		if err := r.operations.Started(ctx, r.storeName, req, validateResp); err != nil {
+			logs.FromContext(ctx).Error("failed to record debit operation", zap.Error(err), zap.String("id", validateResp.ID))
+		}
 	}
 
 	var operationResp *r4bank.GetOperationResponse
 	intent := 0
-	for intent < 7 {
-		if validateResp.Code != "ACCP" {
-			time.Sleep(3 * time.Second)
-		}
-
-		operationResp, err = r.GetOperationByID(ctx, validateResp.ID)
+	for intent < r.polling.Attempts {
+		operationResp, err = r.pollOperation(ctx, validateResp.ID, intent+1, validateResp.Code != debitAccepted)
 		if err != nil {
-			r.Logger.Error(err.Error(), zap.Any("payload", payload), zap.Any("validateResp", validateResp.ID))
+			logs.FromContext(ctx).Error(err.Error(), zap.Any("request", debitReq), zap.Any("validateResp", validateResp.ID))
 			return nil, err
 		}
 
 		if operationResp == nil {
-			r.Logger.Error("nil response from GetOperationByID", zap.Any("payload", payload))
+			logs.FromContext(ctx).Error("nil response from GetOperationByID", zap.Any("request", debitReq))
 			return nil, nil
 		}
 
-		if operationResp.Code != "AC00" {
+		intent++
+		if operationResp.Code != debitPending {
 			break
 		}
-
-		intent++
 	}
+	metrics.DebitPollingIterations.WithLabelValues(r.storeName).Observe(float64(intent))
+	metrics.DebitTerminalStates.WithLabelValues(r.storeName, operationResp.Code).Inc()
 
-	message := _debitInmetiateGenericError
-	if msg, exist := _DebitInmediateSpecialResponse[operationResp.Code]; exist {
-		message = msg
-	}
+	message := debitMessage(operationResp.Code)
 
 	return &models.ValidateDebitInmediateResponse{
 		ID:        validateResp.ID,
@@ -214,29 +288,52 @@ func (r *r4Service) ValidateImmediateDebit(ctx context.Context, req *models.Vali
 	}, nil
 }
 
-// GetOperationByID
-func (r *r4Service) GetOperationByID(ctx context.Context, operationID string) (*r4bank.GetOperationResponse, error) {
-	hmacInput := operationID
-	payload := map[string]string{
-		"id": operationID,
+// pollOperation runs one traced polling iteration, waiting first unless the debit was already accepted
+func (r *r4Service) pollOperation(ctx context.Context, operationID string, iteration int, wait bool) (*r4bank.GetOperationResponse, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "r4.debit.poll", trace.WithAttributes(
+		attribute.String("r4.store", r.storeName),
+		attribute.String("r4.operation_id", operationID),
+		attribute.Int("r4.poll.iteration", iteration),
+	))
+	defer span.End()
+
+	if wait {
+		select {
+		case <-time.After(r.polling.Interval):
+		case <-ctx.Done():
+			logs.FromContext(ctx).Error("debit polling interrupted", zap.Error(ctx.Err()), zap.String("id", operationID))
+			span.SetStatus(codes.Error, ctx.Err().Error())
+			return nil, ctx.Err()
+		}
 	}
-	resp, err := r.r4Client.Do(ctx, hmacInput, payload, "ConsultarOperaciones")
+
+	operationResp, err := r.GetOperationByID(ctx, operationID)
 	if err != nil {
-		r.Logger.Error(err.Error(), zap.Any("payload", payload))
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
+	if operationResp != nil {
+		span.SetAttributes(attribute.String("r4.code", operationResp.Code))
+	}
+
+	return operationResp, nil
+}
 
-	var opResp r4bank.GetOperationResponse
-	if err := json.Unmarshal(resp, &opResp); err != nil {
-		r.Logger.Error(err.Error(), zap.Any("response", string(resp)))
+// GetOperationByID
+func (r *r4Service) GetOperationByID(ctx context.Context, operationID string) (*r4bank.GetOperationResponse, error) {
+	opResp, err := r.r4Client.GetOperation(ctx, r4bank.GetOperationRequest{ID: operationID})
+	if err != nil {
+		logs.FromContext(ctx).Error(err.Error(), zap.String("id", operationID))
 		return nil, err
 	}
 
-	r.Logger.Info("Operation response", zap.Any("operation", opResp))
+	logs.FromContext(ctx).Info("Operation response", zap.Any("operation", opResp))
 
-	return &r4bank.GetOperationResponse{
-		Code:      opResp.Code,
-		Success:   opResp.Success,
-		Reference: opResp.Reference,
-	}, nil
+	if r.operations != nil && opResp != nil {
+		if err := r.operations.Observed(ctx, r.storeName, operationID, opResp); err != nil {
+			logs.FromContext(ctx).Error("failed to record debit operation status", zap.Error(err), zap.String("id", operationID))
+		}
+	}
+
+	return opResp, nil
 }
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// fakeR4 answers ConsultarOperaciones with codes in order, repeating the last one
+type fakeR4 struct {
+	r4bank.Client
+	debit      *r4bank.ValidateDebitInmediateResponse
+	operations []string
+	polls      int
+	change     *r4bank.ChangePaidResponse
+	changeReq  r4bank.ChangeRequest
+}
+
+func (f *fakeR4) ImmediateDebit(_ context.Context, _ r4bank.ImmediateDebitRequest) (*r4bank.ValidateDebitInmediateResponse, error) {
+	return f.debit, nil
+}
+
+func (f *fakeR4) GetOperation(_ context.Context, req r4bank.GetOperationRequest) (*r4bank.GetOperationResponse, error) {
+	if req.ID != f.debit.ID {
+		return nil, errors.New("unknown operation")
+	}
+	code := f.operations[min(f.polls, len(f.operations)-1)]
+	f.polls++
+	return &r4bank.GetOperationResponse{Code: code, Success: code == "ACCP", Reference: "000123"}, nil
+}
+
+func (f *fakeR4) Change(_ context.Context, req r4bank.ChangeRequest) (*r4bank.ChangePaidResponse, error) {
+	f.changeReq = req
+	return f.change, nil
+}
+
+var fastPolling = DebitPolling{Attempts: 4, Interval: time.Millisecond}
+
+func TestValidateImmediateDebitPollsUntilTerminalCode(t *testing.T) {
+	fake := &fakeR4{
+		debit:      &r4bank.ValidateDebitInmediateResponse{Code: "AC00", ID: "op-1"},
+		operations: []string{"AC00", "AC00", "ACCP"},
+	}
+	service := NewR4Service("bone", fake, fastPolling, events.Discard, nil, nil)
+
+	resp, err := service.ValidateImmediateDebit(context.Background(), &models.ValidateOTPRequest{Amount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "ACCP" || !resp.Status || resp.Reference != "000123" || resp.Message != "Transacción Exitosa" {
+		t.Fatalf("response = %+v, want an accepted debit", resp)
+	}
+	if fake.polls != 3 {
+		t.Fatalf("polled %d times, want 3", fake.polls)
+	}
+}
+
+func TestValidateImmediateDebitGivesUpAfterAttempts(t *testing.T) {
+	fake := &fakeR4{
+		debit:      &r4bank.ValidateDebitInmediateResponse{Code: "AC00", ID: "op-1"},
+		operations: []string{"AC00"},
+	}
+	service := NewR4Service("bone", fake, fastPolling, events.Discard, nil, nil)
+
+	resp, err := service.ValidateImmediateDebit(context.Background(), &models.ValidateOTPRequest{Amount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "AC00" || resp.Status {
+		t.Fatalf("response = %+v, want a pending debit", resp)
+	}
+	if fake.polls != fastPolling.Attempts {
+		t.Fatalf("polled %d times, want %d", fake.polls, fastPolling.Attempts)
+	}
+}
+
+func TestValidateImmediateDebitStopsWhenCancelled(t *testing.T) {
+	fake := &fakeR4{
+		debit:      &r4bank.ValidateDebitInmediateResponse{Code: "AC00", ID: "op-1"},
+		operations: []string{"AC00"},
+	}
+	service := NewR4Service("bone", fake, DebitPolling{Attempts: 3, Interval: time.Hour}, events.Discard, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.ValidateImmediateDebit(ctx, &models.ValidateOTPRequest{Amount: 10}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestChangePaid(t *testing.T) {
+	fake := &fakeR4{change: &r4bank.ChangePaidResponse{Code: "00", Reference: 4567}}
+	service := NewR4Service("bone", fake, fastPolling, events.Discard, nil, nil)
+
+	resp, err := service.ChangePaid(context.Background(), &models.ChangePaidRequest{
+		Bank: "0105", Amount: 35.5, Phone: "04141234567", DNI: "V12345678",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Reference != "4567" {
+		t.Fatalf("reference = %s, want 4567", resp.Reference)
+	}
+	if got := fake.changeReq.HMACInput(); got != "0414123456735.500105V12345678" {
+		t.Fatalf("HMAC input = %s", got)
+	}
+
+	fake.change = &r4bank.ChangePaidResponse{Code: "AM04"}
+	if _, err := service.ChangePaid(context.Background(), &models.ChangePaidRequest{Amount: 1}); err == nil {
+		t.Fatal("a rejected payout did not return an error")
+	}
+}
+
+// recorder keeps the events published to it
+type recorder struct {
+	events []events.Event
+}
+
+func (r *recorder) Publish(_ context.Context, event events.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// fakeOperations keeps the codes observed for each debit
+type fakeOperations struct {
+	OperationService
+	observed []string
+}
+
+func (f *fakeOperations) Started(context.Context, string, *models.ValidateOTPRequest, *r4bank.ValidateDebitInmediateResponse) error {
+	return nil
+}
+
+func (f *fakeOperations) Observed(_ context.Context, _, operationID string, op *r4bank.GetOperationResponse) error {
+	f.observed = append(f.observed, operationID+"/"+op.Code)
+	return nil
+}
+
+// The debit events are written by OperationService along with the status, so a debit
+// still pending when polling gives up gets its event when a later poll sees the outcome
+func TestValidateImmediateDebitLeavesTheOutcomeEventToOperations(t *testing.T) {
+	fake := &fakeR4{
+		debit:      &r4bank.ValidateDebitInmediateResponse{Code: "AC00", ID: "op-1"},
+		operations: []string{"AC00", "AM04"},
+	}
+	published, operations := &recorder{}, &fakeOperations{}
+	service := NewR4Service("appa", fake, fastPolling, published, operations, nil)
+
+	if _, err := service.ValidateImmediateDebit(context.Background(), &models.ValidateOTPRequest{Amount: 10, Bank: "0102"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(published.events) != 0 {
+		t.Fatalf("published %+v, want the events left to the operations", published.events)
+	}
+	if len(operations.observed) != 2 || operations.observed[1] != "op-1/AM04" {
+		t.Fatalf("observed %v, want the final code of op-1", operations.observed)
+	}
+}
+
+// fakePayouts holds payouts above 100 in memory
+type fakePayouts struct {
+	ChangePayoutService
+	held    []models.ChangePayout
+	settled string
+}
+
+func (f *fakePayouts) RequiresApproval(amount float64) bool {
+	return amount > 100
+}
+
+func (f *fakePayouts) Hold(_ context.Context, store string, req *models.ChangePaidRequest) (*models.ChangePayout, error) {
+	payout := models.ChangePayout{ID: int64(len(f.held) + 1), Store: store, Amount: req.Amount, Phone: req.Phone, Status: "pending"}
+	f.held = append(f.held, payout)
+	return &payout, nil
+}
+
+func (f *fakePayouts) Record(_ context.Context, store string, req *models.ChangePaidRequest, reference string, _ error) (*models.ChangePayout, error) {
+	return &models.ChangePayout{ID: 99, Store: store, Amount: req.Amount, Reference: reference, Status: "paid"}, nil
+}
+
+func (f *fakePayouts) Claim(_ context.Context, _ string, id int64, _ string) (*models.ChangePayout, error) {
+	if id < 1 || int(id) > len(f.held) || f.held[id-1].Status != "pending" {
+		return nil, ErrPayoutNotPending
+	}
+	f.held[id-1].Status = "paying"
+	return &f.held[id-1], nil
+}
+
+func (f *fakePayouts) Settle(_ context.Context, id int64, reference string, _ error) error {
+	f.held[id-1].Status, f.held[id-1].Reference = "paid", reference
+	f.settled = reference
+	return nil
+}
+
+func (f *fakePayouts) Get(_ context.Context, _ string, id int64) (*models.ChangePayout, error) {
+	return &f.held[id-1], nil
+}
+
+func TestChangePaidHoldsPayoutsAboveTheThreshold(t *testing.T) {
+	fake := &fakeR4{change: &r4bank.ChangePaidResponse{Code: "00", Reference: 4567}}
+	payouts := &fakePayouts{}
+	service := NewR4Service("bone", fake, fastPolling, events.Discard, nil, payouts)
+
+	resp, err := service.ChangePaid(context.Background(), &models.ChangePaidRequest{Amount: 50, Phone: "04141234567"})
+	if err != nil || resp.Status != models.ChangeStatusPaid || resp.Reference != "4567" || resp.PayoutID != 99 {
+		t.Fatalf("ChangePaid(50) = %+v, %v, want it paid right away", resp, err)
+	}
+
+	fake.changeReq = r4bank.ChangeRequest{}
+	resp, err = service.ChangePaid(context.Background(), &models.ChangePaidRequest{Amount: 150, Phone: "04141234567"})
+	if err != nil || resp.Status != models.ChangeStatusPending || resp.PayoutID != 1 || resp.Reference != "" {
+		t.Fatalf("ChangePaid(150) = %+v, %v, want it held", resp, err)
+	}
+	if fake.changeReq.Phone != "" {
+		t.Fatal("a held payout was sent to R4")
+	}
+
+	payout, err := service.ApproveChange(context.Background(), 1, "finanzas@example.com")
+	if err != nil || payout.Status != "paid" || payouts.settled != "4567" || fake.changeReq.Amount != r4bank.Amount(150) {
+		t.Fatalf("ApproveChange = %+v, %v, want it paid through R4", payout, err)
+	}
+	if _, err := service.ApproveChange(context.Background(), 1, "finanzas@example.com"); !errors.Is(err, ErrPayoutNotPending) {
+		t.Fatalf("approving twice = %v, want ErrPayoutNotPending", err)
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/internal/events"
+	"bone_appetit_r4_service/internal/models"
+	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/logs"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned for subscriptions that do not exist
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrDeliveryNotFound is returned for deliveries that do not exist
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// subscriptionSecretPrefix starts every signing secret so they are recognizable
+const subscriptionSecretPrefix = "whsec_"
+
+// deliveryListLimit bounds how many deliveries a listing returns
+const deliveryListLimit = 100
+
+type SubscriptionService interface {
+	events.Publisher
+
+	Create(ctx context.Context, req *models.CreateSubscriptionRequest) (*models.CreatedSubscription, error)
+	List(ctx context.Context, store string) ([]models.Subscription, error)
+	SetDisabled(ctx context.Context, id int, disabled bool) error
+	Deliveries(ctx context.Context, subscriptionID int, status string) ([]models.Delivery, error)
+	Delivery(ctx context.Context, id int64) (*models.DeliveryDetail, error)
+	Retry(ctx context.Context, id int64) error
+}
+
+type subscriptionService struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionService creates a new SubscriptionService
+func NewSubscriptionService(db *gorm.DB) SubscriptionService {
+	return &subscriptionService{db: db}
+}
+
+// Create subscribes an endpoint to the events of a store, generating its signing secret
+func (s *subscriptionService) Create(ctx context.Context, req *models.CreateSubscriptionRequest) (*models.CreatedSubscription, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating subscription secret: %w", err)
+	}
+
+	row := dbModels.EventSubscription{
+		Store:       req.Store,
+		URL:         req.URL,
+		Secret:      subscriptionSecretPrefix + hex.EncodeToString(secret),
+		Events:      pq.StringArray(req.Events),
+		Description: req.Description,
+	}
+	if row.Events == nil {
+		row.Events = pq.StringArray{}
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		logs.FromContext(ctx).Error("failed to create subscription", zap.Error(err), zap.String("store", req.Store))
+		return nil, err
+	}
+
+	return &models.CreatedSubscription{Subscription: toSubscription(row), Secret: row.Secret}, nil
+}
+
+// List returns the subscriptions of store, or of every store when empty, newest first
+func (s *subscriptionService) List(ctx context.Context, store string) ([]models.Subscription, error) {
+	query := s.db.WithContext(ctx).Order("id DESC")
+	if store != "" {
+		query = query.Where("store = ?", store)
+	}
+
+	var rows []dbModels.EventSubscription
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]models.Subscription, 0, len(rows))
+	for _, row := range rows {
+		subscriptions = append(subscriptions, toSubscription(row))
+	}
+	return subscriptions, nil
+}
+
+// SetDisabled stops or resumes deliveries to a subscription. Events published while it
+// is disabled are not queued for it, pending deliveries wait until it is enabled again.
+func (s *subscriptionService) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	disabledAt := gorm.Expr("NULL")
+	if disabled {
+		disabledAt = gorm.Expr("COALESCE(disabled_at, NOW())")
+	}
+
+	result := s.db.WithContext(ctx).Model(&dbModels.EventSubscription{}).
+		Where("id = ?", id).
+		Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries of a subscription, optionally only those in status
+func (s *subscriptionService) Deliveries(ctx context.Context, subscriptionID int, status string) ([]models.Delivery, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&dbModels.EventSubscription{}).Where("id = ?", subscriptionID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	query := s.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(deliveryListLimit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var rows []dbModels.EventDelivery
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, toDelivery(row))
+	}
+	return deliveries, nil
+}
+
+// Delivery returns a delivery with its payload and every attempt made
+func (s *subscriptionService) Delivery(ctx context.Context, id int64) (*models.DeliveryDetail, error) {
+	var row dbModels.EventDelivery
+	err := s.db.WithContext(ctx).Where("id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts []dbModels.EventDeliveryAttempt
+	if err := s.db.WithContext(ctx).Where("delivery_id = ?", id).Order("id").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	detail := &models.DeliveryDetail{
+		Delivery: toDelivery(row),
+		Payload:  json.RawMessage(row.Payload),
+		Log:      make([]models.DeliveryAttempt, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		detail.Log = append(detail.Log, models.DeliveryAttempt{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMS: attempt.DurationMS,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+	return detail, nil
+}
+
+// Retry sends a delivery again as soon as possible, whatever its status. Failed
+// deliveries get one more attempt.
+func (s *subscriptionService) Retry(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).Model(&dbModels.EventDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":          dbModels.DeliveryPending,
+			"next_attempt_at": gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// Publish queues event for every active subscription of its store interested in its type.
+// It is called by the outbox relay, and the deliveries are sent by internal/delivery.
+// Publishing an event again does not queue it twice.
+func (s *subscriptionService) Publish(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event %s: %w", event.Type, err)
+	}
+
+	return s.db.WithContext(ctx).Exec(`
+		INSERT INTO event_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, ?, ?, CAST(? AS jsonb) FROM event_subscriptions
+		WHERE store = ? AND disabled_at IS NULL AND (cardinality(events) = 0 OR ? = ANY(events))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		event.ID, event.Type, string(payload), event.Store, event.Type,
+	).Error
+}
+
+// publish hands event to publisher, logging instead of failing the operation that produced it
+func publish(ctx context.Context, publisher events.Publisher, eventType, store string, data any) {
+	event := events.New(eventType, store, data)
+	if err := publisher.Publish(ctx, event); err != nil {
+		logs.FromContext(ctx).Error("failed to publish event", zap.Error(err), zap.String("event", eventType), zap.String("event_id", event.ID))
+	}
+}
+
+func toSubscription(row dbModels.EventSubscription) models.Subscription {
+	return models.Subscription{
+		ID:          row.ID,
+		Store:       row.Store,
+		URL:         row.URL,
+		Events:      []string(row.Events),
+		Description: row.Description,
+		CreatedAt:   row.CreatedAt,
+		DisabledAt:  row.DisabledAt,
+	}
+}
+
+func toDelivery(row dbModels.EventDelivery) models.Delivery {
+	return models.Delivery{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Status:         row.Status,
+		Attempts:       row.Attempts,
+		NextAttemptAt:  row.NextAttemptAt,
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		CreatedAt:      row.CreatedAt,
+		DeliveredAt:    row.DeliveredAt,
+	}
+}
@@ -1,8 +1,16 @@
 package services
 
 import (
+	"context"
+
+	"bone_appetit_r4_service/internal/events"
 	"bone_appetit_r4_service/internal/models"
+	"bone_appetit_r4_service/internal/outbox"
+	"bone_appetit_r4_service/pkg/banks"
 	dbModels "bone_appetit_r4_service/pkg/db/models"
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/validation"
 	"fmt"
 	"strconv"
 	"time"
@@ -12,37 +20,36 @@ import (
 )
 
 type WebhookService interface {
-	RegisterR4MobilePayment(payment *models.R4NotificaRequest, storeName string) error
-	RegisterR4MobilePaymentPreview(preview *models.R4ConsultaRequest, storeName string) error
+	RegisterR4MobilePayment(ctx context.Context, payment *models.R4NotificaRequest, storeName string) error
+	RegisterR4MobilePaymentPreview(ctx context.Context, preview *models.R4ConsultaRequest, storeName string) error
 }
 
 type webhookService struct {
-	db     *gorm.DB
-	logger *zap.Logger
-	loc    *time.Location
+	db  *gorm.DB
+	loc *time.Location
 }
 
-func NewWebhookService(db *gorm.DB, loc *time.Location, logger *zap.Logger) WebhookService {
-	return &webhookService{db: db, loc: loc, logger: logger}
+func NewWebhookService(db *gorm.DB, loc *time.Location) WebhookService {
+	return &webhookService{db: db, loc: loc}
 }
 
 // RegisterR4MobilePaymentPreview registers a new R4 mobile payment preview in the database
-func (s *webhookService) RegisterR4MobilePaymentPreview(preview *models.R4ConsultaRequest, storeName string) error {
+func (s *webhookService) RegisterR4MobilePaymentPreview(ctx context.Context, preview *models.R4ConsultaRequest, storeName string) error {
 	amount, err := strconv.ParseFloat(preview.Monto, 64)
 	if err != nil {
-		s.logger.Error("failed to parse preview amount", zap.Error(err))
+		logs.FromContext(ctx).Error("failed to parse preview amount", zap.Error(err))
 		return err
 	}
 
 	switch storeName {
 	case "bone":
-		if err := s.createR4BoneMobilePaymentPreview(amount); err != nil {
-			s.logger.Error("failed to register R4 mobile payment preview", zap.Error(err))
+		if err := s.createR4BoneMobilePaymentPreview(ctx, amount); err != nil {
+			logs.FromContext(ctx).Error("failed to register R4 mobile payment preview", zap.Error(err))
 			return err
 		}
 	case "appa":
-		if err := s.createR4AppaMobilePaymentPreview(amount); err != nil {
-			s.logger.Error("failed to register R4 Appa mobile payment preview", zap.Error(err))
+		if err := s.createR4AppaMobilePaymentPreview(ctx, amount); err != nil {
+			logs.FromContext(ctx).Error("failed to register R4 Appa mobile payment preview", zap.Error(err))
 			return err
 		}
 	default:
@@ -53,110 +60,130 @@ func (s *webhookService) RegisterR4MobilePaymentPreview(preview *models.R4Consul
 }
 
 // RegisterR4MobilePaymentProcess registers a new R4 mobile payment in the database
-func (s *webhookService) RegisterR4MobilePayment(payment *models.R4NotificaRequest, storeName string) error {
+func (s *webhookService) RegisterR4MobilePayment(ctx context.Context, payment *models.R4NotificaRequest, storeName string) error {
 
-	if exist, err := s.existReference(payment.Referencia, storeName); err != nil {
-		s.logger.Error("failed to check existing reference", zap.Error(err))
+	if exist, err := s.existReference(ctx, payment.Referencia, storeName); err != nil {
+		logs.FromContext(ctx).Error("failed to check existing reference", zap.Error(err))
 		return err
 	} else if exist {
-		s.logger.Info("R4 mobile payment already registered", zap.String("reference", payment.Referencia))
+		metrics.DuplicateNotifications.WithLabelValues(storeName).Inc()
+		logs.FromContext(ctx).Info("R4 mobile payment already registered", zap.String("reference", payment.Referencia))
 		return nil
 	}
 
-	bank := payment.BancoEmisor
-	if len(bank) == 3 {
-		bank = fmt.Sprintf("0%s", payment.BancoEmisor)
+	// Phones are stored like the filters and the feed branches are normalized
+	normalized := *payment
+	normalized.TelefonoEmisor = validation.NormalizePhone(payment.TelefonoEmisor)
+	normalized.TelefonoComercio = validation.NormalizePhone(payment.TelefonoComercio)
+	payment = &normalized
+
+	bank := banks.Normalize(payment.BancoEmisor)
+	if !banks.IsKnown(bank) {
+		logs.FromContext(ctx).Warn("R4 mobile payment from unknown bank", zap.String("bank", bank), zap.String("reference", payment.Referencia))
 	}
 
 	amount, err := strconv.ParseFloat(payment.Monto, 64)
 	if err != nil {
-		s.logger.Error("failed to parse payment amount", zap.Error(err))
+		logs.FromContext(ctx).Error("failed to parse payment amount", zap.Error(err))
 		return err
 	}
 
-	switch storeName {
-	case "bone":
-		if err := s.createR4BoneMobilePayment(payment, bank, amount); err != nil {
-			s.logger.Error("failed to register R4 mobile payment", zap.Error(err))
-			return err
+	date := time.Now().In(s.loc)
+	event := events.New(events.PaymentReceived, storeName, events.Payment{
+		Reference:     payment.Referencia,
+		Amount:        amount,
+		SenderPhone:   payment.TelefonoEmisor,
+		IssuingBank:   bank,
+		CommercePhone: payment.TelefonoComercio,
+		Date:          date,
+	})
+
+	// The payment.received event is only emitted if the payment is stored
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		switch storeName {
+		case "bone":
+			if err := s.createR4BoneMobilePayment(tx, payment, bank, amount, date); err != nil {
+				logs.FromContext(ctx).Error("failed to register R4 mobile payment", zap.Error(err))
+				return err
+			}
+		case "appa":
+			if err := s.createR4AppaMobilePayment(tx, payment, bank, amount, date); err != nil {
+				logs.FromContext(ctx).Error("failed to register R4 Appa mobile payment", zap.Error(err))
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown store name: %s", storeName)
 		}
-	case "appa":
-		if err := s.createR4AppaMobilePayment(payment, bank, amount); err != nil {
-			s.logger.Error("failed to register R4 Appa mobile payment", zap.Error(err))
+		if err := outbox.Write(tx, event); err != nil {
+			logs.FromContext(ctx).Error("failed to store payment.received event", zap.Error(err))
 			return err
 		}
-	default:
-		return fmt.Errorf("unknown store name: %s", storeName)
-	}
-
-	return nil
+		return nil
+	})
 }
 
-func (s *webhookService) createR4BoneMobilePaymentPreview(amount float64) error {
-	return s.db.Create(&dbModels.R4BoneMobilePaymentPreview{
+func (s *webhookService) createR4BoneMobilePaymentPreview(ctx context.Context, amount float64) error {
+	return s.db.WithContext(ctx).Create(&dbModels.R4BoneMobilePaymentPreview{
 		Amount: amount,
 	}).Error
 }
 
-func (s *webhookService) createR4AppaMobilePaymentPreview(amount float64) error {
-	return s.db.Create(&dbModels.R4AppaMobilePaymentPreview{
+func (s *webhookService) createR4AppaMobilePaymentPreview(ctx context.Context, amount float64) error {
+	return s.db.WithContext(ctx).Create(&dbModels.R4AppaMobilePaymentPreview{
 		Amount: amount,
 	}).Error
 }
 
 // createR4BoneMobilePayment registers a new R4 mobile payment in the database
 func (s *webhookService) createR4BoneMobilePayment(
+	tx *gorm.DB,
 	payment *models.R4NotificaRequest,
 	bank string,
 	amount float64,
+	date time.Time,
 ) error {
-	return s.db.Create(&dbModels.R4MobilePayment{
+	return tx.Create(&dbModels.R4MobilePayment{
 		IDCommerce:    payment.IdComercio,
 		CommercePhone: payment.TelefonoComercio,
 		SenderPhone:   payment.TelefonoEmisor,
 		IssuingBank:   bank,
 		Amount:        amount,
 		Reference:     payment.Referencia,
-		Date:          time.Now().In(s.loc),
+		Date:          date,
 		OrderID:       nil,
 	}).Error
 }
 
 // createR4BoneMobilePayment registers a new R4 mobile payment in the database
 func (s *webhookService) createR4AppaMobilePayment(
+	tx *gorm.DB,
 	payment *models.R4NotificaRequest,
 	bank string,
 	amount float64,
+	date time.Time,
 ) error {
-	return s.db.Create(&dbModels.R4AppaMobilePayment{
+	return tx.Create(&dbModels.R4AppaMobilePayment{
 		IDCommerce:    payment.IdComercio,
 		CommercePhone: payment.TelefonoComercio,
 		SenderPhone:   payment.TelefonoEmisor,
 		IssuingBank:   bank,
 		Amount:        amount,
 		Reference:     payment.Referencia,
-		Date:          time.Now().In(s.loc),
+		Date:          date,
 		OrderID:       nil,
 	}).Error
 }
 
 // existReference checks if a reference already exists in the specified table
-func (s *webhookService) existReference(reference, storeName string) (bool, error) {
-	var (
-		tableName string
-		count     int64
-	)
+func (s *webhookService) existReference(ctx context.Context, reference, storeName string) (bool, error) {
+	var count int64
 
-	switch storeName {
-	case "bone":
-		tableName = "r4_mobile_payments"
-	case "appa":
-		tableName = "r4_appa_mobile_payments"
-	default:
-		return false, fmt.Errorf("unknown store name: %s", storeName)
+	tableName, err := mobilePaymentTable(storeName)
+	if err != nil {
+		return false, err
 	}
 
-	if err := s.db.Table(tableName).Where("reference = ?", reference).Count(&count).Error; err != nil {
+	if err := s.db.WithContext(ctx).Table(tableName).Where("reference = ?", reference).Count(&count).Error; err != nil {
 		return false, err
 	}
 
@@ -0,0 +1,131 @@
+package banks
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+//go:embed catalog.json
+var embeddedCatalog []byte
+
+// Bank represents a bank registered with SUDEBAN
+type Bank struct {
+	Code           string `json:"code"`
+	ShortName      string `json:"short_name"`
+	Name           string `json:"name"`
+	ImmediateDebit bool   `json:"immediate_debit"`
+}
+
+// Catalog is an immutable, versioned set of banks indexed by code
+type Catalog struct {
+	Version string
+	banks   []Bank
+	byCode  map[string]Bank
+}
+
+type catalogFile struct {
+	Version string `json:"version"`
+	Banks   []Bank `json:"banks"`
+}
+
+var current atomic.Pointer[Catalog]
+
+func init() {
+	c, err := Load(bytes.NewReader(embeddedCatalog))
+	if err != nil {
+		panic(fmt.Sprintf("embedded bank catalog: %v", err))
+	}
+	current.Store(c)
+}
+
+// Load decodes a catalog file and validates its entries
+func Load(r io.Reader) (*Catalog, error) {
+	var file catalogFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("decoding bank catalog: %w", err)
+	}
+	if file.Version == "" {
+		return nil, fmt.Errorf("bank catalog has no version")
+	}
+
+	c := &Catalog{Version: file.Version, byCode: make(map[string]Bank, len(file.Banks))}
+	for _, b := range file.Banks {
+		b.Code = Normalize(b.Code)
+		if len(b.Code) != 4 || b.ShortName == "" {
+			return nil, fmt.Errorf("invalid bank catalog entry %q", b.Code)
+		}
+		if _, dup := c.byCode[b.Code]; dup {
+			return nil, fmt.Errorf("duplicated bank code %s", b.Code)
+		}
+		c.byCode[b.Code] = b
+		c.banks = append(c.banks, b)
+	}
+	sort.Slice(c.banks, func(i, j int) bool { return c.banks[i].Code < c.banks[j].Code })
+
+	return c, nil
+}
+
+// LoadFile reads a catalog from path
+func LoadFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// Default returns the catalog in use, the embedded one unless replaced with SetDefault
+func Default() *Catalog {
+	return current.Load()
+}
+
+// SetDefault replaces the catalog used by the package-level helpers
+func SetDefault(c *Catalog) {
+	current.Store(c)
+}
+
+// Lookup returns the bank with the given code
+func (c *Catalog) Lookup(code string) (Bank, bool) {
+	b, ok := c.byCode[Normalize(code)]
+	return b, ok
+}
+
+// List returns the banks ordered by code
+func (c *Catalog) List() []Bank {
+	return append([]Bank(nil), c.banks...)
+}
+
+// ShortName returns the short name of the bank, or an empty string if unknown
+func (c *Catalog) ShortName(code string) string {
+	return c.byCode[Normalize(code)].ShortName
+}
+
+// Normalize trims the code and restores the leading zero R4 drops on 3-digit codes
+func Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	if len(code) == 3 {
+		code = "0" + code
+	}
+	return code
+}
+
+// IsKnown reports whether the code belongs to a bank in the default catalog
+func IsKnown(code string) bool {
+	_, ok := Default().Lookup(code)
+	return ok
+}
+
+// SupportsImmediateDebit reports whether the bank can receive OTP and immediate debit requests
+func SupportsImmediateDebit(code string) bool {
+	b, ok := Default().Lookup(code)
+	return ok && b.ImmediateDebit
+}
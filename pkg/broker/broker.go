@@ -0,0 +1,23 @@
+// Package broker publishes messages to NATS JetStream, Kafka or RabbitMQ, or keeps them
+// in memory for tests and local development.
+//
+// Publish only returns once the broker acknowledged the message, so callers retrying
+// on error get at-least-once delivery. Consumers deduplicate on Message.ID.
+package broker
+
+import "context"
+
+// Message is what is published: Key is appended to the destination configured in each
+// publisher (the NATS subject prefix, the Kafka topic key or the RabbitMQ routing key).
+type Message struct {
+	ID      string
+	Key     string
+	Body    []byte
+	Headers map[string]string
+}
+
+// Publisher sends messages to a broker
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
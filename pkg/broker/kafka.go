@@ -0,0 +1,52 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka publishes to one topic, partitioned by message key so the events of a store
+// and type keep their order
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+// NewKafka creates a publisher for topic. Brokers are only contacted on the first publish.
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		// Messages are written one at a time, do not wait to fill a batch
+		BatchTimeout: 10 * time.Millisecond,
+	}}
+}
+
+// Publish sends msg and waits for every in-sync replica to store it
+func (k *Kafka) Publish(ctx context.Context, msg Message) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+1)
+	headers = append(headers, kafka.Header{Key: "id", Value: []byte(msg.ID)})
+	for key, value := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	err := k.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(msg.Key),
+		Value:   msg.Body,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("publishing to Kafka: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the writer
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
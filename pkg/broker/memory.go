@@ -0,0 +1,55 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryCapacity is how many messages Memory keeps, the oldest are dropped first
+const memoryCapacity = 1000
+
+// Memory is an in-process stand-in for a broker, for tests and running locally
+// without one. It keeps the latest messages and can be told to fail.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemory creates an empty Memory broker
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish keeps msg, or returns the error set with Fail
+func (m *Memory) Publish(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	if len(m.messages) == memoryCapacity {
+		m.messages = m.messages[1:]
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Fail makes Publish return err until it is called again with nil
+func (m *Memory) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Messages returns the messages published, oldest first
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS publishes to JetStream on the subject <prefix>.<key>. The stream capturing
+// <prefix>.> is created on first use, and JetStream drops duplicates by message ID.
+type NATS struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+
+	mu     sync.Mutex
+	stream bool
+}
+
+// NewNATS connects to url. The connection is retried in the background, so a broker
+// that is down does not keep the service from starting.
+func NewNATS(url, prefix string) (*NATS, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("bone-appetit-r4-service"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating JetStream context: %w", err)
+	}
+	return &NATS{conn: conn, js: js, prefix: prefix}, nil
+}
+
+// Publish sends msg and waits for JetStream to store it
+func (n *NATS) Publish(ctx context.Context, msg Message) error {
+	if err := n.ensureStream(ctx); err != nil {
+		return err
+	}
+
+	natsMsg := nats.NewMsg(n.prefix + "." + msg.Key)
+	natsMsg.Data = msg.Body
+	for key, value := range msg.Headers {
+		natsMsg.Header.Set(key, value)
+	}
+	if _, err := n.js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(msg.ID)); err != nil {
+		return fmt.Errorf("publishing to NATS: %w", err)
+	}
+	return nil
+}
+
+func (n *NATS) ensureStream(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stream {
+		return nil
+	}
+
+	// r4.events gives the stream R4_EVENTS
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "*", "_", ">", "_").Replace(n.prefix))
+	_, err := n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{n.prefix + ".>"},
+	})
+	if err != nil {
+		return fmt.Errorf("creating NATS stream %s: %w", name, err)
+	}
+	n.stream = true
+	return nil
+}
+
+// Close flushes and closes the connection
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
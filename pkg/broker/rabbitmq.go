@@ -0,0 +1,108 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQ publishes persistent messages to a durable topic exchange, routed by message
+// key, with publisher confirms
+type RabbitMQ struct {
+	url      string
+	exchange string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+// NewRabbitMQ creates a publisher for exchange. The connection is opened on the first
+// publish and reopened after it is lost.
+func NewRabbitMQ(url, exchange string) *RabbitMQ {
+	return &RabbitMQ{url: url, exchange: exchange}
+}
+
+// Publish sends msg and waits for the broker to confirm it
+func (r *RabbitMQ) Publish(ctx context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.connect(); err != nil {
+		return err
+	}
+
+	headers := make(amqp.Table, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, r.exchange, msg.Key, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID,
+		Timestamp:    time.Now(),
+		Headers:      headers,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		r.reset()
+		return fmt.Errorf("publishing to RabbitMQ: %w", err)
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		// The confirm may still come on this channel, a new one avoids mixing it up with the next message
+		r.reset()
+		return fmt.Errorf("waiting for RabbitMQ to confirm: %w", err)
+	}
+	if !acked {
+		return errors.New("RabbitMQ rejected the message")
+	}
+	return nil
+}
+
+// connect opens the connection and the confirm channel when there are none
+func (r *RabbitMQ) connect() error {
+	if r.channel != nil && !r.channel.IsClosed() {
+		return nil
+	}
+	r.reset()
+
+	conn, err := amqp.Dial(r.url)
+	if err != nil {
+		return fmt.Errorf("connecting to RabbitMQ: %w", err)
+	}
+	channel, err := conn.Channel()
+	if err == nil {
+		err = channel.Confirm(false)
+	}
+	if err == nil {
+		err = channel.ExchangeDeclare(r.exchange, amqp.ExchangeTopic, true, false, false, false, nil)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("setting up RabbitMQ exchange %s: %w", r.exchange, err)
+	}
+
+	r.conn, r.channel = conn, channel
+	return nil
+}
+
+func (r *RabbitMQ) reset() {
+	if r.conn != nil {
+		_ = r.conn.Close()
+	}
+	r.conn, r.channel = nil, nil
+}
+
+// Close closes the connection
+func (r *RabbitMQ) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+	return nil
+}
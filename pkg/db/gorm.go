@@ -1,29 +1,108 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
+	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"bone_appetit_r4_service/pkg/logs"
 )
 
+// Options describes how to reach a PostgreSQL database
+type Options struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+}
+
+// DSN builds a postgres:// URL, escaping credentials and names with special characters
+func (o Options) DSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(o.User, o.Password),
+		Host:   net.JoinHostPort(o.Host, o.Port),
+		Path:   "/" + o.Name,
+	}
+	if o.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {o.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// Pool configures the connection pool of a database
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Retry bounds how long Connect keeps trying while the database is unreachable.
+// The wait doubles after every failed attempt up to MaxBackoff.
+type Retry struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Connect opens the database, retrying with backoff until it answers a ping
+func Connect(ctx context.Context, dsn string, pool Pool, retry Retry) (*gorm.DB, error) {
+	wait := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		gormDB, err := NewDBSQLHandler(ctx, dsn, pool)
+		if err == nil {
+			return gormDB, nil
+		}
+		if attempt >= retry.Attempts {
+			return nil, fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		}
+
+		logs.FromContext(ctx).Warn("database unreachable, retrying",
+			zap.Error(err), zap.Int("attempt", attempt), zap.Duration("wait", wait))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		// A MaxBackoff below Backoff, zero when unset, must not shorten the wait
+		wait = max(min(wait*2, retry.MaxBackoff), retry.Backoff)
+	}
+}
+
 // NewDBSQLHandler creates a new database handler for PostgreSQL using GORM.
-func NewDBSQLHandler(conn string) (*gorm.DB, error) {
+func NewDBSQLHandler(ctx context.Context, conn string, pool Pool) (*gorm.DB, error) {
 	sqlDB, err := sql.Open("postgres", conn)
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Ping the database to check if the connection is successful
-	err = sqlDB.Ping()
-	if err != nil {
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
 		return nil, err
 	}
-	fmt.Println("Connected to the PostgreSQL database!")
+
 	gormDB, err := gorm.Open(postgres.New(postgres.Config{
 		Conn: sqlDB,
 	}), &gorm.Config{})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
 
 	return gormDB, nil
 }
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestOptionsDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "plain",
+			opts: Options{Host: "db.example.com", Port: "5432", User: "r4", Password: "secret", Name: "r4", SSLMode: "require"},
+			want: "dbname='r4' host='db.example.com' password='secret' port='5432' sslmode='require' user='r4'",
+		},
+		{
+			name: "special characters",
+			opts: Options{Host: "localhost", Port: "5432", User: "r4 user@corp", Password: "p@ss:w/rd?%# x", Name: "r4 db?%"},
+			want: "dbname='r4 db?%' host='localhost' password='p@ss:w/rd?%# x' port='5432' user='r4 user@corp'",
+		},
+		{
+			name: "quotes",
+			opts: Options{Host: "localhost", Port: "5432", User: "o'brien", Password: `back\slash`, Name: "r4"},
+			want: `dbname='r4' host='localhost' password='back\\slash' port='5432' user='o\'brien'`,
+		},
+		{
+			name: "IPv6 host",
+			opts: Options{Host: "::1", Port: "5433", User: "r4", Password: "secret", Name: "r4"},
+			want: "dbname='r4' host='::1' password='secret' port='5433' user='r4'",
+		},
+		{
+			name: "IPv6 host with a zone",
+			opts: Options{Host: "fe80::1%eth0", Port: "5432", User: "r4", Password: "secret", Name: "r4"},
+			want: "dbname='r4' host='fe80::1%eth0' password='secret' port='5432' user='r4'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pq.ParseURL(tt.opts.DSN())
+			if err != nil {
+				t.Fatalf("ParseURL(%q): %v", tt.opts.DSN(), err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseURL(%q) = %s, want %s", tt.opts.DSN(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectWaitsWithoutMaxBackoff(t *testing.T) {
+	// Nothing listens on port 1, every attempt fails at once
+	dsn := Options{Host: "127.0.0.1", Port: "1", User: "r4", Password: "r4", Name: "r4", SSLMode: "disable"}.DSN()
+
+	start := time.Now()
+	_, err := Connect(context.Background(), dsn, Pool{}, Retry{Attempts: 3, Backoff: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("Connect to a closed port succeeded")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 attempts took %v, want at least 2 waits of the 50ms backoff", elapsed)
+	}
+}
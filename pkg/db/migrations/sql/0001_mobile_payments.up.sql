@@ -1,6 +1,8 @@
+-- Tables the service writes R4 mobile payments and webhook previews to.
+-- Written with IF NOT EXISTS so databases created from the old schema.sql are adopted as is.
+-- It has no down script: reverting it would drop the payments of those databases.
+
 -- public.r4_mobile_payments definition
--- Drop table
--- DROP TABLE public.r4_mobile_payments;
 CREATE TABLE IF NOT EXISTS public.r4_mobile_payments
 (
     id int4 GENERATED ALWAYS AS IDENTITY( INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1 NO CYCLE) NOT NULL,
@@ -19,13 +21,11 @@ CREATE TABLE IF NOT EXISTS public.r4_mobile_payments
     updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
     CONSTRAINT r4_mobile_payments_pkey PRIMARY KEY (id)
 );
-CREATE INDEX idx_r4_mobile_payments_on_reference ON public.r4_mobile_payments (reference);
-CREATE INDEX idx_r4_mobile_payments_on_order_id ON public.r4_mobile_payments (order_id);
-CREATE INDEX idx_r4_mobile_payments_on_sender_phone ON public.r4_mobile_payments (sender_phone);
+CREATE INDEX IF NOT EXISTS idx_r4_mobile_payments_on_reference ON public.r4_mobile_payments (reference);
+CREATE INDEX IF NOT EXISTS idx_r4_mobile_payments_on_order_id ON public.r4_mobile_payments (order_id);
+CREATE INDEX IF NOT EXISTS idx_r4_mobile_payments_on_sender_phone ON public.r4_mobile_payments (sender_phone);
 
 -- public.r4_mobile_payments_previews definition
--- Drop table
--- DROP TABLE public.r4_mobile_payments_previews;
 CREATE TABLE IF NOT EXISTS public.r4_mobile_payments_previews
 (
     id int4 GENERATED ALWAYS AS IDENTITY( INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1 NO CYCLE) NOT NULL,
@@ -35,8 +35,6 @@ CREATE TABLE IF NOT EXISTS public.r4_mobile_payments_previews
 );
 
 -- public.r4_appa_mobile_payments definition
--- Drop table
--- DROP TABLE public.r4_appa_mobile_payments;
 CREATE TABLE IF NOT EXISTS public.r4_appa_mobile_payments
 (
     id int4 GENERATED ALWAYS AS IDENTITY( INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1 NO CYCLE) NOT NULL,
@@ -55,17 +53,15 @@ CREATE TABLE IF NOT EXISTS public.r4_appa_mobile_payments
     updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
     CONSTRAINT r4_appa_mobile_payments_pkey PRIMARY KEY (id)
 );
-CREATE INDEX idx_r4_appa_mobile_payments_on_reference ON public.r4_appa_mobile_payments (reference);
-CREATE INDEX idx_r4_appa_mobile_payments_on_order_id ON public.r4_appa_mobile_payments (order_id);
-CREATE INDEX idx_r4_appa_mobile_payments_on_sender_phone ON public.r4_appa_mobile_payments (sender_phone);
+CREATE INDEX IF NOT EXISTS idx_r4_appa_mobile_payments_on_reference ON public.r4_appa_mobile_payments (reference);
+CREATE INDEX IF NOT EXISTS idx_r4_appa_mobile_payments_on_order_id ON public.r4_appa_mobile_payments (order_id);
+CREATE INDEX IF NOT EXISTS idx_r4_appa_mobile_payments_on_sender_phone ON public.r4_appa_mobile_payments (sender_phone);
 
 -- public.r4_appa_mobile_payments_previews definition
--- Drop table
--- DROP TABLE public.r4_appa_mobile_payments_previews;
 CREATE TABLE IF NOT EXISTS public.r4_appa_mobile_payments_previews
 (
     id int4 GENERATED ALWAYS AS IDENTITY( INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1 NO CYCLE) NOT NULL,
     amount decimal(10,2) NOT NULL,
     created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
     CONSTRAINT r4_appa_mobile_payments_previews_pkey PRIMARY KEY (id)
-);
\ No newline at end of file
+);
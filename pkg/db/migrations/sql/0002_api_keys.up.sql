@@ -0,0 +1,14 @@
+-- API keys authenticating internal clients, such as the order service on the gRPC API.
+-- Only the SHA-256 of each key is stored, prefix identifies it in listings and logs.
+CREATE TABLE public.api_keys
+(
+    id int4 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    name varchar(255) NOT NULL,
+    store varchar(32) NOT NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash char(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITHOUT TIME ZONE,
+    revoked_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT api_keys_pkey PRIMARY KEY (id)
+);
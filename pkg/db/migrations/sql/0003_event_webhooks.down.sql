@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.event_delivery_attempts;
+DROP TABLE IF EXISTS public.event_deliveries;
+DROP TABLE IF EXISTS public.event_subscriptions;
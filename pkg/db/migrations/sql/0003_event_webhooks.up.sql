@@ -0,0 +1,53 @@
+-- Endpoints of downstream systems receiving the events of a store, signed with secret.
+-- An empty events list subscribes to every event type.
+CREATE TABLE public.event_subscriptions
+(
+    id int4 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    store varchar(32) NOT NULL,
+    url varchar(2048) NOT NULL,
+    secret varchar(64) NOT NULL,
+    events text[] NOT NULL DEFAULT '{}',
+    description varchar(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    disabled_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT event_subscriptions_pkey PRIMARY KEY (id)
+);
+
+-- One event to deliver to one subscription. The dispatcher picks pending rows whose
+-- next_attempt_at is due and moves them to delivered, or failed after the last attempt.
+CREATE TABLE public.event_deliveries
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    subscription_id int4 NOT NULL REFERENCES public.event_subscriptions (id) ON DELETE CASCADE,
+    event_id varchar(64) NOT NULL,
+    event_type varchar(64) NOT NULL,
+    payload jsonb NOT NULL,
+    status varchar(16) NOT NULL DEFAULT 'pending',
+    attempts int4 NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    last_status_code int4,
+    last_error text,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT event_deliveries_pkey PRIMARY KEY (id),
+    CONSTRAINT event_deliveries_status_check CHECK (status IN ('pending', 'delivered', 'failed')),
+    CONSTRAINT event_deliveries_event_unique UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX event_deliveries_due_idx ON public.event_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX event_deliveries_subscription_idx ON public.event_deliveries (subscription_id, id DESC);
+
+-- Every attempt made for a delivery, for support to see what the subscriber answered
+CREATE TABLE public.event_delivery_attempts
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    delivery_id int8 NOT NULL REFERENCES public.event_deliveries (id) ON DELETE CASCADE,
+    attempt int4 NOT NULL,
+    status_code int4,
+    error text,
+    duration_ms int4 NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT event_delivery_attempts_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX event_delivery_attempts_delivery_idx ON public.event_delivery_attempts (delivery_id);
@@ -0,0 +1,19 @@
+-- Domain events waiting to be relayed to the message broker and the webhook subscriptions.
+-- They are written in the same transaction as the change they describe, so an event is
+-- never lost nor emitted for a change that rolled back.
+CREATE TABLE public.outbox
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    event_id varchar(64) NOT NULL UNIQUE,
+    event_type varchar(64) NOT NULL,
+    store varchar(32) NOT NULL,
+    payload jsonb NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    attempts int4 NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error text,
+    published_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT outbox_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX outbox_unpublished_idx ON public.outbox (next_attempt_at, id) WHERE published_at IS NULL;
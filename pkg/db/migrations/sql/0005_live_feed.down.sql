@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.outbox_store_idx;
+DROP TRIGGER IF EXISTS outbox_notify ON public.outbox;
+DROP FUNCTION IF EXISTS public.notify_outbox_event();
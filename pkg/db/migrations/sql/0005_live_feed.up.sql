@@ -0,0 +1,15 @@
+-- Every instance LISTENs on outbox_events to push new events to the live feed.
+-- The notification only carries the outbox ID, payloads could exceed the 8000 bytes
+-- NOTIFY accepts. It is sent when the transaction commits, never for a rollback.
+CREATE FUNCTION public.notify_outbox_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify AFTER INSERT ON public.outbox
+    FOR EACH ROW EXECUTE FUNCTION public.notify_outbox_event();
+
+-- Feeds resuming from Last-Event-ID read the events of their store after it
+CREATE INDEX outbox_store_idx ON public.outbox (store, id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS public.backoffice_sessions;
+DROP TABLE IF EXISTS public.backoffice_users;
+DROP TABLE IF EXISTS public.change_payouts;
+DROP TABLE IF EXISTS public.debit_operation_statuses;
+DROP TABLE IF EXISTS public.debit_operations;
@@ -0,0 +1,84 @@
+-- Immediate debits requested through the service and every status R4 reported for them,
+-- so support can follow a debit after the request that created it is gone.
+CREATE TABLE public.debit_operations
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    store varchar(32) NOT NULL,
+    operation_id varchar(64) NOT NULL UNIQUE,
+    bank varchar(8) NOT NULL,
+    amount decimal(12,2) NOT NULL,
+    phone varchar(32) NOT NULL,
+    dni varchar(32) NOT NULL,
+    name varchar(80) NOT NULL DEFAULT '',
+    code varchar(8) NOT NULL,
+    reference varchar(64) NOT NULL DEFAULT '',
+    success boolean NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT debit_operations_pkey PRIMARY KEY (id)
+);
+CREATE INDEX debit_operations_store_idx ON public.debit_operations (store, id);
+
+-- A row is added each time the code of an operation changes, polling the same code
+-- again is not recorded
+CREATE TABLE public.debit_operation_statuses
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    debit_operation_id int8 NOT NULL REFERENCES public.debit_operations (id) ON DELETE CASCADE,
+    code varchar(8) NOT NULL,
+    reference varchar(64) NOT NULL DEFAULT '',
+    success boolean NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT debit_operation_statuses_pkey PRIMARY KEY (id)
+);
+CREATE INDEX debit_operation_statuses_operation_idx ON public.debit_operation_statuses (debit_operation_id, id);
+
+-- Change payouts (MBvuelto). Those above the approval threshold wait as pending until a
+-- finance user approves them, paying moves them to paid or failed.
+CREATE TABLE public.change_payouts
+(
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    store varchar(32) NOT NULL,
+    bank varchar(8) NOT NULL,
+    amount decimal(12,2) NOT NULL,
+    phone varchar(32) NOT NULL,
+    dni varchar(32) NOT NULL,
+    concept varchar(140) NOT NULL DEFAULT '',
+    status varchar(16) NOT NULL,
+    reference varchar(64) NOT NULL DEFAULT '',
+    error text,
+    decided_by varchar(255),
+    decided_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT change_payouts_pkey PRIMARY KEY (id),
+    CONSTRAINT change_payouts_status_check CHECK (status IN ('pending', 'paying', 'paid', 'failed', 'rejected'))
+);
+CREATE INDEX change_payouts_store_idx ON public.change_payouts (store, status, id);
+
+-- Support and finance staff signing in to the back office. Only finance approves payouts.
+CREATE TABLE public.backoffice_users
+(
+    id int4 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    email varchar(255) NOT NULL,
+    name varchar(255) NOT NULL,
+    role varchar(16) NOT NULL,
+    password_hash varchar(255) NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP WITHOUT TIME ZONE,
+    disabled_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT backoffice_users_pkey PRIMARY KEY (id),
+    CONSTRAINT backoffice_users_role_check CHECK (role IN ('support', 'finance'))
+);
+CREATE UNIQUE INDEX backoffice_users_email_idx ON public.backoffice_users (lower(email));
+
+-- Signed-in sessions, the cookie holds the token whose hash is stored here
+CREATE TABLE public.backoffice_sessions
+(
+    token_hash varchar(64) NOT NULL,
+    user_id int4 NOT NULL REFERENCES public.backoffice_users (id) ON DELETE CASCADE,
+    csrf_token varchar(64) NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CONSTRAINT backoffice_sessions_pkey PRIMARY KEY (token_hash)
+);
@@ -0,0 +1,13 @@
+-- Token buckets shared by every instance when rate_limit.store is postgres. A bucket
+-- holds the tokens left when it was last taken from, the refill since then is
+-- computed on the next take.
+CREATE UNLOGGED TABLE public.rate_limit_buckets
+(
+    key varchar(255) NOT NULL,
+    tokens double precision NOT NULL,
+    allowed boolean NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CONSTRAINT rate_limit_buckets_pkey PRIMARY KEY (key)
+);
+-- Idle buckets are full again and deleted
+CREATE INDEX rate_limit_buckets_updated_idx ON public.rate_limit_buckets (updated_at);
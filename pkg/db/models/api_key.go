@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type APIKey struct {
+	ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name       string     `gorm:"column:name" json:"name"`
+	Store      string     `gorm:"column:store" json:"store"`
+	Prefix     string     `gorm:"column:prefix" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash" json:"-"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"lastUsedAt"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Back-office roles, see the backoffice_users migration
+const (
+	RoleSupport = "support"
+	RoleFinance = "finance"
+)
+
+type BackofficeUser struct {
+	ID           int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Email        string     `gorm:"column:email" json:"email"`
+	Name         string     `gorm:"column:name" json:"name"`
+	Role         string     `gorm:"column:role" json:"role"`
+	PasswordHash string     `gorm:"column:password_hash" json:"-"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	LastLoginAt  *time.Time `gorm:"column:last_login_at" json:"lastLoginAt"`
+	DisabledAt   *time.Time `gorm:"column:disabled_at" json:"disabledAt"`
+}
+
+func (BackofficeUser) TableName() string {
+	return "backoffice_users"
+}
+
+type BackofficeSession struct {
+	TokenHash string    `gorm:"column:token_hash;primaryKey" json:"-"`
+	UserID    int       `gorm:"column:user_id" json:"userId"`
+	CSRFToken string    `gorm:"column:csrf_token" json:"-"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ExpiresAt time.Time `gorm:"column:expires_at" json:"expiresAt"`
+}
+
+func (BackofficeSession) TableName() string {
+	return "backoffice_sessions"
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Change payout statuses, see the change_payouts migration
+const (
+	PayoutPending  = "pending"
+	PayoutPaying   = "paying"
+	PayoutPaid     = "paid"
+	PayoutFailed   = "failed"
+	PayoutRejected = "rejected"
+)
+
+type ChangePayout struct {
+	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Store     string     `gorm:"column:store" json:"store"`
+	Bank      string     `gorm:"column:bank" json:"bank"`
+	Amount    float64    `gorm:"column:amount" json:"amount"`
+	Phone     string     `gorm:"column:phone" json:"phone"`
+	DNI       string     `gorm:"column:dni" json:"dni"`
+	Concept   string     `gorm:"column:concept" json:"concept"`
+	Status    string     `gorm:"column:status" json:"status"`
+	Reference string     `gorm:"column:reference" json:"reference"`
+	Error     *string    `gorm:"column:error" json:"error"`
+	DecidedBy *string    `gorm:"column:decided_by" json:"decidedBy"`
+	DecidedAt *time.Time `gorm:"column:decided_at" json:"decidedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ChangePayout) TableName() string {
+	return "change_payouts"
+}
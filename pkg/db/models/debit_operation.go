@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type DebitOperation struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Store       string    `gorm:"column:store" json:"store"`
+	OperationID string    `gorm:"column:operation_id" json:"operationId"`
+	Bank        string    `gorm:"column:bank" json:"bank"`
+	Amount      float64   `gorm:"column:amount" json:"amount"`
+	Phone       string    `gorm:"column:phone" json:"phone"`
+	DNI         string    `gorm:"column:dni" json:"dni"`
+	Name        string    `gorm:"column:name" json:"name"`
+	Code        string    `gorm:"column:code" json:"code"`
+	Reference   string    `gorm:"column:reference" json:"reference"`
+	Success     bool      `gorm:"column:success" json:"success"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (DebitOperation) TableName() string {
+	return "debit_operations"
+}
+
+type DebitOperationStatus struct {
+	ID               int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	DebitOperationID int64     `gorm:"column:debit_operation_id" json:"debitOperationId"`
+	Code             string    `gorm:"column:code" json:"code"`
+	Reference        string    `gorm:"column:reference" json:"reference"`
+	Success          bool      `gorm:"column:success" json:"success"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (DebitOperationStatus) TableName() string {
+	return "debit_operation_statuses"
+}
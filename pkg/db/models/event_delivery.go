@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Delivery statuses, see the event_deliveries migration
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type EventDelivery struct {
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID int        `gorm:"column:subscription_id" json:"subscriptionId"`
+	EventID        string     `gorm:"column:event_id" json:"eventId"`
+	EventType      string     `gorm:"column:event_type" json:"eventType"`
+	Payload        []byte     `gorm:"column:payload;type:jsonb" json:"-"`
+	Status         string     `gorm:"column:status" json:"status"`
+	Attempts       int        `gorm:"column:attempts" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at" json:"nextAttemptAt"`
+	LastStatusCode *int       `gorm:"column:last_status_code" json:"lastStatusCode"`
+	LastError      *string    `gorm:"column:last_error" json:"lastError"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at" json:"deliveredAt"`
+}
+
+func (EventDelivery) TableName() string {
+	return "event_deliveries"
+}
+
+type EventDeliveryAttempt struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeliveryID int64     `gorm:"column:delivery_id" json:"deliveryId"`
+	Attempt    int       `gorm:"column:attempt" json:"attempt"`
+	StatusCode *int      `gorm:"column:status_code" json:"statusCode"`
+	Error      *string   `gorm:"column:error" json:"error"`
+	DurationMS int       `gorm:"column:duration_ms" json:"durationMs"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (EventDeliveryAttempt) TableName() string {
+	return "event_delivery_attempts"
+}
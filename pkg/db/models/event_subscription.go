@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type EventSubscription struct {
+	ID          int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Store       string         `gorm:"column:store" json:"store"`
+	URL         string         `gorm:"column:url" json:"url"`
+	Secret      string         `gorm:"column:secret" json:"-"`
+	Events      pq.StringArray `gorm:"column:events;type:text[]" json:"events"`
+	Description string         `gorm:"column:description" json:"description"`
+	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	DisabledAt  *time.Time     `gorm:"column:disabled_at" json:"disabledAt"`
+}
+
+func (EventSubscription) TableName() string {
+	return "event_subscriptions"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type OutboxEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID       string     `gorm:"column:event_id" json:"eventId"`
+	EventType     string     `gorm:"column:event_type" json:"eventType"`
+	Store         string     `gorm:"column:store" json:"store"`
+	Payload       []byte     `gorm:"column:payload;type:jsonb" json:"-"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	Attempts      int        `gorm:"column:attempts" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;default:now()" json:"nextAttemptAt"`
+	LastError     *string    `gorm:"column:last_error" json:"lastError"`
+	PublishedAt   *time.Time `gorm:"column:published_at" json:"publishedAt"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
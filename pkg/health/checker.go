@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a dependency check
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusDegraded is reported but does not take the instance out of rotation
+	StatusDegraded Status = "degraded"
+	StatusFail     Status = "fail"
+)
+
+// Result is the outcome of a single dependency check
+type Result struct {
+	Status  Status         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// CheckFunc checks a dependency
+type CheckFunc func(ctx context.Context) Result
+
+// Report aggregates the results of every check
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the registered readiness checks concurrently
+type Checker struct {
+	readiness *Readiness
+	timeout   time.Duration
+	names     []string
+	checks    map[string]CheckFunc
+}
+
+// NewChecker creates a Checker bounded by timeout per check
+func NewChecker(readiness *Readiness, timeout time.Duration) *Checker {
+	return &Checker{
+		readiness: readiness,
+		timeout:   timeout,
+		checks:    make(map[string]CheckFunc),
+	}
+}
+
+// Add registers a named check
+func (c *Checker) Add(name string, check CheckFunc) {
+	if _, exists := c.checks[name]; !exists {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Ready runs every check. The report fails when any check fails or the instance is draining.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.names)+1)}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			result := check(checkCtx)
+
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	if !c.readiness.IsReady() {
+		report.Checks["lifecycle"] = Result{Status: StatusFail, Error: "instance is not accepting traffic"}
+	}
+
+	for _, result := range report.Checks {
+		switch {
+		case result.Status == StatusFail:
+			report.Status = StatusFail
+		case result.Status == StatusDegraded && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// Fail builds a failed result from err
+func Fail(err error) Result {
+	return Result{Status: StatusFail, Error: err.Error()}
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+
+	"bone_appetit_r4_service/pkg/db/migrations"
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// DatabaseCheck pings the database and reports the pool usage
+func DatabaseCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) Result {
+		if err := db.PingContext(ctx); err != nil {
+			return Fail(err)
+		}
+
+		stats := db.Stats()
+		return Result{Status: StatusOK, Details: map[string]any{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		}}
+	}
+}
+
+// SchemaCheck verifies every embedded migration has been applied to the database
+func SchemaCheck(migrator *migrations.Migrator) CheckFunc {
+	return func(ctx context.Context) Result {
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return Fail(err)
+		}
+
+		details := map[string]any{"version": version, "expected": migrator.Latest()}
+		if version < migrator.Latest() {
+			return Result{
+				Status:  StatusFail,
+				Error:   "database schema is behind, run the migrate command",
+				Details: details,
+			}
+		}
+
+		return Result{Status: StatusOK, Details: details}
+	}
+}
+
+// Optional reports the failures of check as degraded, for dependencies the
+// instance can still serve most traffic without
+func Optional(check CheckFunc) CheckFunc {
+	return func(ctx context.Context) Result {
+		result := check(ctx)
+		if result.Status == StatusFail {
+			result.Status = StatusDegraded
+		}
+		return result
+	}
+}
+
+// R4ClientCheck fails while the store's R4 credentials are invalid and reports its
+// circuit breaker. An open breaker degrades the report without taking the instance out
+// of rotation.
+func R4ClientCheck(client *r4bank.RestClient) CheckFunc {
+	return func(_ context.Context) Result {
+		if err := client.CredentialsError(); err != nil {
+			return Result{Status: StatusFail, Error: "invalid R4 credentials: " + err.Error()}
+		}
+
+		state := client.BreakerState()
+		result := Result{Status: StatusOK, Details: map[string]any{"circuit_breaker": state}}
+		if state != r4bank.BreakerClosed {
+			result.Status = StatusDegraded
+		}
+		return result
+	}
+}
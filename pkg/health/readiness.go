@@ -0,0 +1,28 @@
+package health
+
+import "sync/atomic"
+
+// Readiness reports whether the instance should receive traffic
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// NewReadiness creates a Readiness in the not-ready state
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// SetReady marks the instance as able to serve
+func (r *Readiness) SetReady() {
+	r.ready.Store(true)
+}
+
+// SetNotReady marks the instance as draining or not yet started
+func (r *Readiness) SetNotReady() {
+	r.ready.Store(false)
+}
+
+// IsReady reports the current state
+func (r *Readiness) IsReady() bool {
+	return r.ready.Load()
+}
@@ -1,34 +1,165 @@
 package ipfy
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
 )
 
-// GetIPInfo retrieves the public IP address of the server.
-func GetIPInfo() {
-	// URL del servicio que devuelve la IP pública
-	url := "https://api64.ipify.org?format=json"
+// DefaultProviderURL answers with the caller's public IPv4 address, the family R4
+// registers. https://api64.ipify.org answers with IPv6 when the server has it.
+const DefaultProviderURL = "https://api.ipify.org?format=json"
+
+// Result is the egress IP of the server compared with the IPs registered with R4
+type Result struct {
+	IP            string    `json:"ip"`
+	Registered    bool      `json:"registered"`
+	RegisteredIPs []string  `json:"registered_ips"`
+	Provider      string    `json:"provider"`
+	CheckedAt     time.Time `json:"checked_at"`
+	Cached        bool      `json:"cached"`
+}
+
+// Resolver discovers the public IP the server reaches R4 from. R4 only accepts
+// calls from the IPs registered for the commerce, so a change has to be noticed.
+type Resolver struct {
+	providerURL string
+	registered  []string
+	cacheTTL    time.Duration
+	client      *http.Client
+
+	mu   sync.Mutex
+	last *Result
+}
+
+// NewResolver creates a Resolver querying providerURL, which may answer with plain
+// text or ipify's {"ip": "..."} JSON. Results are cached for cacheTTL.
+func NewResolver(providerURL string, timeout, cacheTTL time.Duration, registered []string) *Resolver {
+	if providerURL == "" {
+		providerURL = DefaultProviderURL
+	}
+	return &Resolver{
+		providerURL: providerURL,
+		registered:  registered,
+		cacheTTL:    cacheTTL,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Lookup returns the egress IP, from the cache when it is fresh enough.
+// It warns when the IP is not one of the registered ones.
+func (r *Resolver) Lookup(ctx context.Context) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last != nil && time.Since(r.last.CheckedAt) < r.cacheTTL {
+		cached := *r.last
+		cached.Cached = true
+		return &cached, nil
+	}
+	return r.resolve(ctx)
+}
+
+// Refresh queries the provider even if the cached result is still fresh
+func (r *Resolver) Refresh(ctx context.Context) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.resolve(ctx)
+}
+
+// resolve queries the provider and caches the result, r.mu must be held
+func (r *Resolver) resolve(ctx context.Context) (*Result, error) {
+	ip, err := r.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		IP:            ip,
+		Registered:    isRegistered(r.registered, ip),
+		RegisteredIPs: r.registered,
+		Provider:      r.providerURL,
+		CheckedAt:     time.Now(),
+	}
+	r.last = result
+
+	logger := logs.FromContext(ctx).With(zap.String("ip", ip))
+	switch {
+	case len(r.registered) == 0:
+		logger.Info("public IP of the server, no registered IPs configured to compare")
+	case result.Registered:
+		metrics.EgressIPRegistered.Set(1)
+		logger.Info("public IP of the server is registered with R4")
+	default:
+		metrics.EgressIPRegistered.Set(0)
+		logger.Warn("public IP of the server is not registered with R4, calls to R4 may be rejected",
+			zap.Strings("registered_ips", r.registered))
+	}
+
+	copied := *result
+	return &copied, nil
+}
+
+func (r *Resolver) fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.providerURL, nil)
+	if err != nil {
+		return "", err
+	}
 
-	// Realizar una solicitud GET al servicio
-	resp, err := http.Get(url)
+	resp, err := r.client.Do(req)
 	if err != nil {
-		log.Fatalf("Error al realizar la solicitud HTTP: %v", err)
+		return "", fmt.Errorf("querying %s: %w", r.providerURL, err)
 	}
-	defer resp.Body.Close() // Asegurarse de cerrar el cuerpo de la respuesta
+	defer resp.Body.Close()
 
-	// Leer el cuerpo de la respuesta
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
 	if err != nil {
-		log.Fatalf("Error al leer la respuesta: %v", err)
+		return "", fmt.Errorf("reading %s: %w", r.providerURL, err)
 	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered %d", r.providerURL, resp.StatusCode)
+	}
+
+	return parseIP(body)
+}
+
+// isRegistered compares ip with the registered IPs as addresses, so "::ffff:200.1.2.3"
+// or a zero-padded IPv6 matches its usual form
+func isRegistered(registered []string, ip string) bool {
+	addr := net.ParseIP(ip)
+	for _, candidate := range registered {
+		if addr.Equal(net.ParseIP(candidate)) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Convertir el cuerpo a string y limpiar espacios en blanco
-	ipAddress := strings.TrimSpace(string(body))
+// parseIP accepts a plain text IP or a JSON object with an "ip" field
+func parseIP(body []byte) (string, error) {
+	text := strings.TrimSpace(string(body))
 
-	// Imprimir la dirección IP pública
-	fmt.Printf("La dirección IP pública del servidor es: %s\n", ipAddress)
+	var payload struct {
+		IP string `json:"ip"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.IP != "" {
+		text = payload.IP
+	}
+
+	if net.ParseIP(text) == nil {
+		return "", fmt.Errorf("provider did not answer with an IP address: %q", text)
+	}
+	return text, nil
 }
@@ -0,0 +1,73 @@
+package ipfy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseIP(t *testing.T) {
+	tests := []struct {
+		body, want string
+		valid      bool
+	}{
+		{`{"ip":"200.1.2.3"}`, "200.1.2.3", true},
+		{"200.1.2.3\n", "200.1.2.3", true},
+		{`{"ip":"2001:db8::1"}`, "2001:db8::1", true},
+		{"  2001:db8::1 ", "2001:db8::1", true},
+		{`{"ip":""}`, "", false},
+		{`{"address":"200.1.2.3"}`, "", false},
+		{"<html>rate limited</html>", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parseIP([]byte(tt.body))
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("parseIP(%q) = %q, %v; want %q, valid %v", tt.body, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestIsRegistered(t *testing.T) {
+	registered := []string{"200.1.2.3", "2001:db8::1"}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"200.1.2.3", true},
+		{"::ffff:200.1.2.3", true},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", true},
+		{"2001:DB8::1", true},
+		{"200.1.2.4", false},
+		{"2001:db8::2", false},
+	}
+	for _, tt := range tests {
+		if got := isRegistered(registered, tt.ip); got != tt.want {
+			t.Errorf("isRegistered(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+	if isRegistered(nil, "200.1.2.3") {
+		t.Error("an IP is registered when none are")
+	}
+}
+
+func TestResolverComparesWithTheRegisteredIPs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ip":"::ffff:200.1.2.3"}`))
+	}))
+	defer server.Close()
+
+	resolver := NewResolver(server.URL, time.Second, time.Minute, []string{"200.1.2.3"})
+	result, err := resolver.Lookup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Registered || result.Cached {
+		t.Fatalf("Lookup = %+v, want a registered IP, not cached", result)
+	}
+	if result, _ := resolver.Lookup(context.Background()); !result.Cached {
+		t.Error("second Lookup within the TTL was not cached")
+	}
+}
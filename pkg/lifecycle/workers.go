@@ -0,0 +1,47 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers tracks background goroutines so shutdown can wait for them to finish
+type Workers struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWorkers creates an empty set of workers
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a tracked goroutine.
+// The context passed to fn is only cancelled when Shutdown gives up waiting.
+func (w *Workers) Go(fn func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		fn(w.ctx)
+	}()
+}
+
+// Shutdown waits for the running workers until ctx expires, then cancels them
+func (w *Workers) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
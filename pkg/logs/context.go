@@ -0,0 +1,25 @@
+package logs
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithContext stores a request-scoped logger in ctx
+func WithContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger stored in ctx.
+// Outside a request it falls back to the global logger installed with zap.ReplaceGlobals.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return zap.L()
+}
@@ -0,0 +1,205 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces values that must never reach the logs
+const Redacted = "[REDACTED]"
+
+type maskFunc func(string) string
+
+// sensitiveKeys maps normalized field names (lowercase, without separators) to their masking.
+// They cover both the R4 payload fields and our own API fields.
+var sensitiveKeys = map[string]maskFunc{
+	"otp":           redactAll,
+	"token":         redactAll,
+	"commerce":      redactAll,
+	"commercetoken": redactAll,
+	"authorization": redactAll,
+	"secret":        redactAll,
+	"password":      redactAll,
+
+	"telefono":         maskTail,
+	"telefonodestino":  maskTail,
+	"telefonoemisor":   maskTail,
+	"telefonocomercio": maskTail,
+	"phone":            maskTail,
+	"senderphone":      maskTail,
+	"commercephone":    maskTail,
+
+	"cuenta":        maskTail,
+	"account":       maskTail,
+	"accountnumber": maskTail,
+
+	"cedula": maskDNI,
+	"dni":    maskDNI,
+
+	"nombre": maskName,
+	"name":   maskName,
+}
+
+var keySeparators = strings.NewReplacer("_", "", "-", "", ".", "")
+
+// Redact masks value according to the sensitivity of key, leaving other values untouched
+func Redact(key, value string) string {
+	if mask, ok := sensitiveKeys[normalizeKey(key)]; ok {
+		return mask(value)
+	}
+	return value
+}
+
+// Payload logs a request payload with its sensitive fields masked
+func Payload(key string, payload map[string]string) zap.Field {
+	return zap.Object(key, redactedMap(payload))
+}
+
+// JSON logs a JSON body with its sensitive fields masked.
+// Bodies that are not JSON are replaced by their size since their content cannot be inspected.
+func JSON(key string, data []byte) zap.Field {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return zap.String(key, fmt.Sprintf("[%d bytes, not JSON]", len(data)))
+	}
+	return zap.Any(key, redactValue("", value))
+}
+
+type redactedMap map[string]string
+
+func (m redactedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range m {
+		enc.AddString(k, Redact(k, v))
+	}
+	return nil
+}
+
+func redactValue(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = redactValue(k, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redactValue(key, item)
+		}
+		return out
+	case string:
+		return Redact(key, v)
+	default:
+		if _, ok := sensitiveKeys[normalizeKey(key)]; ok && v != nil {
+			return Redact(key, fmt.Sprint(v))
+		}
+		return v
+	}
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(keySeparators.Replace(key))
+}
+
+func redactAll(string) string {
+	return Redacted
+}
+
+// maskTail keeps the last 4 characters, e.g. 04141234567 -> *******4567
+func maskTail(value string) string {
+	n := utf8.RuneCountInString(value)
+	if n <= 4 {
+		return strings.Repeat("*", n)
+	}
+	runes := []rune(value)
+	return strings.Repeat("*", n-4) + string(runes[n-4:])
+}
+
+// maskDNI keeps the nationality prefix and the last 3 digits, e.g. V12345678 -> V*****678
+func maskDNI(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	prefix := ""
+	if unicode.IsLetter(runes[0]) {
+		prefix, runes = string(runes[0]), runes[1:]
+	}
+	return prefix + strings.Repeat("*", len(runes)-3) + string(runes[len(runes)-3:])
+}
+
+// maskName keeps the first letter of each word, e.g. Maria Perez -> M*** P***
+func maskName(value string) string {
+	words := strings.Fields(value)
+	for i, w := range words {
+		r, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(r) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// redactingCore masks sensitive top-level fields, whatever the call site used to log them
+type redactingCore struct {
+	zapcore.Core
+}
+
+// NewRedactingCore wraps core so sensitive fields never reach its output
+func NewRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = redactField(f)
+	}
+	return out
+}
+
+func redactField(f zapcore.Field) zapcore.Field {
+	if _, sensitive := sensitiveKeys[normalizeKey(f.Key)]; sensitive {
+		if f.Type == zapcore.StringType {
+			return zap.String(f.Key, Redact(f.Key, f.String))
+		}
+		return zap.String(f.Key, Redacted)
+	}
+
+	// Raw payload maps and request structs logged with zap.Any are masked field by field
+	if f.Type == zapcore.ReflectType {
+		switch v := f.Interface.(type) {
+		case map[string]string:
+			return Payload(f.Key, v)
+		case map[string]any:
+			return zap.Any(f.Key, redactValue(f.Key, v))
+		default:
+			if data, err := json.Marshal(v); err == nil && len(data) > 0 && data[0] == '{' {
+				return JSON(f.Key, data)
+			}
+		}
+	}
+
+	return f
+}
@@ -0,0 +1,132 @@
+package logs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newTestLogger builds a JSON logger like NewZapLogger writing into buf
+func newTestLogger(buf *bytes.Buffer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.DebugLevel)
+	return zap.New(NewRedactingCore(core))
+}
+
+func assertNotContains(t *testing.T, output string, secrets ...string) {
+	t.Helper()
+	for _, secret := range secrets {
+		if strings.Contains(output, secret) {
+			t.Errorf("log output leaks %q:\n%s", secret, output)
+		}
+	}
+}
+
+func assertContains(t *testing.T, output string, values ...string) {
+	t.Helper()
+	for _, value := range values {
+		if !strings.Contains(output, value) {
+			t.Errorf("log output is missing %q:\n%s", value, output)
+		}
+	}
+}
+
+func TestPayloadMasksSensitiveFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	logger.Error("R4 API error", Payload("payload", map[string]string{
+		"Banco":    "0102",
+		"Monto":    "150.00",
+		"Telefono": "04141234567",
+		"Cedula":   "V12345678",
+		"Nombre":   "Maria Perez",
+		"OTP":      "98765432",
+		"Concepto": "Orden 42",
+	}))
+
+	out := buf.String()
+	assertNotContains(t, out, "98765432", "04141234567", "12345678", "Maria", "Perez")
+	assertContains(t, out, `"OTP":"[REDACTED]"`, `"Telefono":"*******4567"`, `"Cedula":"V*****678"`,
+		`"Nombre":"M*** P***"`, `"Banco":"0102"`, `"Monto":"150.00"`)
+}
+
+func TestJSONMasksNestedBodies(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	body := []byte(`{"code":"AM04","data":{"TelefonoEmisor":"584241112233","cedula":"E87654321","otp":123456},"items":[{"phone":"04261234567"}]}`)
+	logger.Error("R4 API error", JSON("body", body))
+
+	out := buf.String()
+	assertNotContains(t, out, "584241112233", "87654321", "123456", "04261234567")
+	assertContains(t, out, `"code":"AM04"`, `"otp":"[REDACTED]"`)
+}
+
+func TestJSONDropsUnparseableBodies(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	logger.Error("R4 API error", JSON("body", []byte("Telefono=04141234567&OTP=1234")))
+
+	out := buf.String()
+	assertNotContains(t, out, "04141234567", "1234&")
+	assertContains(t, out, "not JSON")
+}
+
+func TestCoreMasksTopLevelFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf).With(zap.String("commerce_token", "tok-abcdef"))
+
+	logger.Warn("webhook rejected",
+		zap.String("Authorization", "c0ffee1234"),
+		zap.String("otp", "55555555"),
+		zap.String("secret", "s3cr3t"),
+		zap.String("sender_phone", "04161230000"),
+		zap.Any("payload", map[string]string{"Cedula": "J000029610", "OTP": "11112222"}),
+		zap.Int("password", 424242),
+	)
+
+	out := buf.String()
+	assertNotContains(t, out, "tok-abcdef", "c0ffee1234", "55555555", "s3cr3t", "04161230000",
+		"000029610", "11112222", "424242")
+	assertContains(t, out, `"sender_phone":"*******0000"`, `"Cedula":"J******610"`)
+}
+
+func TestCoreMasksStructs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	type debitRequest struct {
+		Bank  string `json:"Banco"`
+		Phone string `json:"Telefono"`
+		OTP   string `json:"OTP"`
+	}
+	logger.Error("R4 API error", zap.Any("request", debitRequest{Bank: "0102", Phone: "04141234567", OTP: "98765432"}))
+
+	out := buf.String()
+	assertNotContains(t, out, "04141234567", "98765432")
+	assertContains(t, out, `"Banco":"0102"`, `"OTP":"[REDACTED]"`)
+}
+
+func TestRedactLeavesOtherFieldsUntouched(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"Referencia", "000123456", "000123456"},
+		{"IdComercio", "J123", "J123"},
+		{"TelefonoDestino", "0412", "****"},
+		{"DNI", "V1234", "V*234"},
+		{"Cuenta", "01020000000012345678", "****************5678"},
+		{"Authorization", "", Redacted},
+	}
+
+	for _, tt := range tests {
+		if got := Redact(tt.key, tt.value); got != tt.want {
+			t.Errorf("Redact(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
@@ -5,12 +5,23 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// NewZapLogger creates a new instance of ZapLogger.
+// NewZapLogger creates a new instance of ZapLogger writing to stdout.
+// Sensitive fields (OTP, tokens, phones, cédulas, names) are masked before being written.
 func NewZapLogger() *zap.Logger {
+	return newZapLogger("stdout")
+}
+
+// NewCLILogger creates a logger like NewZapLogger writing to stderr, so the
+// logs of a support command do not mix with the JSON or tables it prints
+func NewCLILogger() *zap.Logger {
+	return newZapLogger("stderr")
+}
+
+func newZapLogger(output string) *zap.Logger {
 	config := zap.Config{
 		Encoding:         "json",
 		Level:            zap.NewAtomicLevelAt(zap.InfoLevel),
-		OutputPaths:      []string{"stdout"},
+		OutputPaths:      []string{output},
 		ErrorOutputPaths: []string{"stderr"},
 		EncoderConfig: zapcore.EncoderConfig{
 			TimeKey:        "timestamp",
@@ -27,5 +38,5 @@ func NewZapLogger() *zap.Logger {
 		},
 	}
 
-	return zap.Must(config.Build())
+	return zap.Must(config.Build(zap.WrapCore(NewRedactingCore)))
 }
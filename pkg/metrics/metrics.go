@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "r4_service"
+
+var (
+	// R4Requests counts calls to the R4 API by store, endpoint and R4 response code
+	R4Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "r4_requests_total",
+		Help:      "Calls to the R4 API by store, endpoint and R4 code.",
+	}, []string{"store", "endpoint", "code"})
+
+	// R4RequestDuration observes the latency of the calls to the R4 API
+	R4RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "r4_request_duration_seconds",
+		Help:      "Latency of the calls to the R4 API.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20},
+	}, []string{"store", "endpoint"})
+
+	// WebhooksReceived counts the R4consulta/R4notifica webhooks by outcome
+	WebhooksReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_received_total",
+		Help:      "Webhooks received from R4 by store, type and outcome.",
+	}, []string{"store", "type", "outcome"})
+
+	// DuplicateNotifications counts R4notifica webhooks whose reference was already registered
+	DuplicateNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_notifications_total",
+		Help:      "R4notifica webhooks with an already registered reference.",
+	}, []string{"store"})
+
+	// DebitPollingIterations observes how many ConsultarOperaciones calls a debit needed
+	DebitPollingIterations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "debit_polling_iterations",
+		Help:      "ConsultarOperaciones calls made until an immediate debit left AC00.",
+		Buckets:   []float64{1, 2, 3, 4, 5, 6, 7},
+	}, []string{"store"})
+
+	// DebitTerminalStates counts the final R4 code of each immediate debit
+	DebitTerminalStates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "debit_terminal_states_total",
+		Help:      "Immediate debits by final R4 code (AC00 means polling gave up).",
+	}, []string{"store", "code"})
+
+	// ChangePayouts counts MBvuelto payouts by outcome: paid, rejected, error or held for approval
+	ChangePayouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "change_payouts_total",
+		Help:      "Change payouts (MBvuelto) by store and outcome.",
+	}, []string{"store", "outcome"})
+
+	// ChangePayoutAmount sums the bolívares paid out as change
+	ChangePayoutAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "change_payout_amount_bs_total",
+		Help:      "Bolívares successfully paid out as change.",
+	}, []string{"store"})
+
+	// WebhookAuth counts authentications by which secret matched: current, previous, missing or rejected
+	WebhookAuth = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Authorization header checks by store and matching secret (current, previous, missing, rejected).",
+	}, []string{"store", "secret"})
+
+	// ConfigReloads counts credential reloads by outcome
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Credential reloads triggered by SIGHUP or file changes, by outcome.",
+	}, []string{"outcome"})
+
+	// EgressIPRegistered is 1 when the last egress IP lookup matched an IP registered with R4
+	EgressIPRegistered = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "egress_ip_registered",
+		Help:      "Whether the last egress IP lookup matched an IP registered with R4 (1) or not (0).",
+	})
+
+	// HTTPRequests counts the requests served by route and status
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes the latency of the requests served
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests served.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "route"})
+
+	// GRPCRequests counts the gRPC calls served by method and status code
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls served by method and status code.",
+	}, []string{"method", "code"})
+
+	// GRPCRequestDuration observes the latency of the gRPC calls served, streams included
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of the gRPC calls served, until the end of the stream for streaming calls.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 120},
+	}, []string{"method"})
+
+	// EventDeliveries counts the event webhook attempts by event type and outcome
+	// (delivered, retrying or failed once attempts are exhausted)
+	EventDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_deliveries_total",
+		Help:      "Event webhook delivery attempts by event type and outcome.",
+	}, []string{"event", "outcome"})
+
+	// EventDeliveryDuration observes how long subscribers take to answer an event webhook
+	EventDeliveryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_delivery_duration_seconds",
+		Help:      "Latency of the event webhook requests sent to subscribers.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"event"})
+
+	// OutboxEvents counts the outbox relay attempts by event type and outcome (published or error)
+	OutboxEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Outbox events relayed to the broker and webhook subscriptions by event type and outcome.",
+	}, []string{"event", "outcome"})
+
+	// OutboxBacklog is how many outbox events are waiting to be relayed
+	OutboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbox_backlog",
+		Help:      "Outbox events not relayed yet.",
+	})
+
+	// FeedSubscribers is how many live feeds are open on this instance by store
+	FeedSubscribers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "feed_subscribers",
+		Help:      "Live payment feeds open on this instance by store.",
+	}, []string{"store"})
+
+	// RateLimitDecisions counts the rate limit checks by rule and outcome (allowed, limited or error)
+	RateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_decisions_total",
+		Help:      "Rate limit checks by rule and outcome (allowed, limited, error).",
+	}, []string{"rule", "outcome"})
+)
+
+// RegisterDBStats exposes the connection pool statistics of db under the given name
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
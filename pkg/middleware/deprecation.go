@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated flags the responses of routes under prefix with the Deprecation header
+// (RFC 9745) and links the route replacing each one, found by swapping prefix for successor.
+func Deprecated(since time.Time, prefix, successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if path, ok := strings.CutPrefix(c.Request.URL.Path, prefix); ok {
+			c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, path))
+		}
+		c.Next()
+	}
+}
@@ -1,41 +1,101 @@
 package middleware
 
 import (
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
 	"bone_appetit_r4_service/pkg/r4bank"
-	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type WebhookAuthMiddleware struct {
+// secretPair is the commerce token and secret whose HMAC R4 sends as Authorization
+type secretPair struct {
 	secret        string
 	commerceToken string
+	expiresAt     time.Time
+}
+
+type WebhookAuthMiddleware struct {
+	store string
+	now   func() time.Time
+
+	mu       sync.RWMutex
+	current  secretPair
+	previous *secretPair
 }
 
-func NewWebhookAuthMiddleware(secret, commerceToken string) *WebhookAuthMiddleware {
+func NewWebhookAuthMiddleware(store, secret, commerceToken string) *WebhookAuthMiddleware {
 	return &WebhookAuthMiddleware{
-		secret:        secret,
-		commerceToken: commerceToken,
+		store: store,
+		now:   time.Now,
+		current: secretPair{
+			secret:        secret,
+			commerceToken: commerceToken,
+		},
 	}
 }
 
+// Rotate installs a new secret and commerce token. The previous ones keep being
+// accepted during overlap so requests signed before the switch are not rejected.
+func (m *WebhookAuthMiddleware) Rotate(secret, commerceToken string, overlap time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current.secret == secret && m.current.commerceToken == commerceToken {
+		return
+	}
+
+	previous := m.current
+	previous.expiresAt = m.now().Add(overlap)
+	m.previous = &previous
+	m.current = secretPair{secret: secret, commerceToken: commerceToken}
+}
+
+// match returns which secret signed authHeader: "current", "previous" or "" if none
+func (m *WebhookAuthMiddleware) match(authHeader string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if r4bank.ValidateAuthToken(m.current.commerceToken, m.current.secret, authHeader) {
+		return "current"
+	}
+	if m.previous != nil && m.now().Before(m.previous.expiresAt) &&
+		r4bank.ValidateAuthToken(m.previous.commerceToken, m.previous.secret, authHeader) {
+		return "previous"
+	}
+	return ""
+}
+
 // Auth middleware function to validate webhook requests
 func (m *WebhookAuthMiddleware) Auth() gin.HandlerFunc {
+	return m.AuthWith(func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"abono": false})
+	})
+}
+
+// AuthWith validates requests like Auth, answering the rejected ones with reject
+func (m *WebhookAuthMiddleware) AuthWith(reject gin.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			fmt.Println("Missing Authorization header")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"abono": false})
+			metrics.WebhookAuth.WithLabelValues(m.store, "missing").Inc()
+			logs.FromContext(c.Request.Context()).Warn("missing Authorization header")
+			reject(c)
 			return
 		}
 
 		// Validate the authorization header
-		if !r4bank.ValidateAuthToken(m.commerceToken, m.secret, authHeader) {
-			fmt.Println("Invalid Authorization token", authHeader)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"abono": false})
+		secret := m.match(authHeader)
+		if secret == "" {
+			metrics.WebhookAuth.WithLabelValues(m.store, "rejected").Inc()
+			logs.FromContext(c.Request.Context()).Warn("invalid Authorization token")
+			reject(c)
 			return
 		}
+		metrics.WebhookAuth.WithLabelValues(m.store, secret).Inc()
 
 		c.Next()
 	}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// signed answers whether m lets a webhook signed with secret and commerceToken through
+func signed(m *WebhookAuthMiddleware, secret, commerceToken string) bool {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/R4notifica", m.Auth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/R4notifica", nil)
+	req.Header.Set("Authorization", r4bank.GenerateAuthToken(commerceToken, secret))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code == http.StatusOK
+}
+
+func TestWebhookAuthRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewWebhookAuthMiddleware("bone", "secret-1", "token-1")
+	m.now = func() time.Time { return now }
+
+	if !signed(m, "secret-1", "token-1") || signed(m, "secret-2", "token-1") {
+		t.Fatal("only the configured secret must be accepted")
+	}
+
+	m.Rotate("secret-2", "token-2", time.Minute)
+	if !signed(m, "secret-2", "token-2") {
+		t.Fatal("the new secret was rejected")
+	}
+	now = now.Add(59 * time.Second)
+	if !signed(m, "secret-1", "token-1") {
+		t.Fatal("the previous secret was rejected within the overlap")
+	}
+	now = now.Add(time.Second)
+	if signed(m, "secret-1", "token-1") {
+		t.Fatal("the previous secret was accepted after the overlap")
+	}
+
+	// A second rotation leaves only the one before it as previous
+	m.Rotate("secret-3", "token-3", time.Minute)
+	if !signed(m, "secret-3", "token-3") || !signed(m, "secret-2", "token-2") {
+		t.Fatal("the new or the previous secret was rejected after a second rotation")
+	}
+	if signed(m, "secret-1", "token-1") {
+		t.Fatal("the secret before the previous one was accepted")
+	}
+}
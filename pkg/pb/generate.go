@@ -0,0 +1,8 @@
+// Package pb holds the Go code generated from the protobuf definitions in /proto.
+// Regenerate it from the repository root with buf, protoc-gen-go and protoc-gen-go-grpc
+// installed:
+//
+//	go generate ./pkg/pb
+package pb
+
+//go:generate sh -c "cd ../.. && buf generate"
@@ -0,0 +1,1214 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: r4/v1/r4.proto
+
+package r4v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetBCVRateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBCVRateRequest) Reset() {
+	*x = GetBCVRateRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBCVRateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBCVRateRequest) ProtoMessage() {}
+
+func (x *GetBCVRateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBCVRateRequest.ProtoReflect.Descriptor instead.
+func (*GetBCVRateRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{0}
+}
+
+type GetBCVRateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// date is YYYY-MM-DD
+	Date          string  `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Rate          float64 `protobuf:"fixed64,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBCVRateResponse) Reset() {
+	*x = GetBCVRateResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBCVRateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBCVRateResponse) ProtoMessage() {}
+
+func (x *GetBCVRateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBCVRateResponse.ProtoReflect.Descriptor instead.
+func (*GetBCVRateResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBCVRateResponse) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *GetBCVRateResponse) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+type GenerateOTPRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bank          string                 `protobuf:"bytes,1,opt,name=bank,proto3" json:"bank,omitempty"`
+	Amount        float64                `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Phone         string                 `protobuf:"bytes,3,opt,name=phone,proto3" json:"phone,omitempty"`
+	Dni           string                 `protobuf:"bytes,4,opt,name=dni,proto3" json:"dni,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateOTPRequest) Reset() {
+	*x = GenerateOTPRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateOTPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateOTPRequest) ProtoMessage() {}
+
+func (x *GenerateOTPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateOTPRequest.ProtoReflect.Descriptor instead.
+func (*GenerateOTPRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerateOTPRequest) GetBank() string {
+	if x != nil {
+		return x.Bank
+	}
+	return ""
+}
+
+func (x *GenerateOTPRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *GenerateOTPRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *GenerateOTPRequest) GetDni() string {
+	if x != nil {
+		return x.Dni
+	}
+	return ""
+}
+
+type GenerateOTPResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateOTPResponse) Reset() {
+	*x = GenerateOTPResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateOTPResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateOTPResponse) ProtoMessage() {}
+
+func (x *GenerateOTPResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateOTPResponse.ProtoReflect.Descriptor instead.
+func (*GenerateOTPResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{3}
+}
+
+type ValidateImmediateDebitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bank          string                 `protobuf:"bytes,1,opt,name=bank,proto3" json:"bank,omitempty"`
+	Amount        float64                `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Phone         string                 `protobuf:"bytes,3,opt,name=phone,proto3" json:"phone,omitempty"`
+	Dni           string                 `protobuf:"bytes,4,opt,name=dni,proto3" json:"dni,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Otp           string                 `protobuf:"bytes,6,opt,name=otp,proto3" json:"otp,omitempty"`
+	Concept       string                 `protobuf:"bytes,7,opt,name=concept,proto3" json:"concept,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateImmediateDebitRequest) Reset() {
+	*x = ValidateImmediateDebitRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateImmediateDebitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateImmediateDebitRequest) ProtoMessage() {}
+
+func (x *ValidateImmediateDebitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateImmediateDebitRequest.ProtoReflect.Descriptor instead.
+func (*ValidateImmediateDebitRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ValidateImmediateDebitRequest) GetBank() string {
+	if x != nil {
+		return x.Bank
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *ValidateImmediateDebitRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitRequest) GetDni() string {
+	if x != nil {
+		return x.Dni
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitRequest) GetOtp() string {
+	if x != nil {
+		return x.Otp
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitRequest) GetConcept() string {
+	if x != nil {
+		return x.Concept
+	}
+	return ""
+}
+
+type ValidateImmediateDebitResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// code is the R4 code, AC00 while pending and ACCP once accepted
+	Code      string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Reference string `protobuf:"bytes,3,opt,name=reference,proto3" json:"reference,omitempty"`
+	Message   string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	// accepted is whether the debit went through
+	Accepted      bool `protobuf:"varint,5,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateImmediateDebitResponse) Reset() {
+	*x = ValidateImmediateDebitResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateImmediateDebitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateImmediateDebitResponse) ProtoMessage() {}
+
+func (x *ValidateImmediateDebitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateImmediateDebitResponse.ProtoReflect.Descriptor instead.
+func (*ValidateImmediateDebitResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ValidateImmediateDebitResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitResponse) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ValidateImmediateDebitResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+type ChangePaidRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bank          string                 `protobuf:"bytes,1,opt,name=bank,proto3" json:"bank,omitempty"`
+	Amount        float64                `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Phone         string                 `protobuf:"bytes,3,opt,name=phone,proto3" json:"phone,omitempty"`
+	Dni           string                 `protobuf:"bytes,4,opt,name=dni,proto3" json:"dni,omitempty"`
+	Concept       string                 `protobuf:"bytes,5,opt,name=concept,proto3" json:"concept,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangePaidRequest) Reset() {
+	*x = ChangePaidRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangePaidRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangePaidRequest) ProtoMessage() {}
+
+func (x *ChangePaidRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangePaidRequest.ProtoReflect.Descriptor instead.
+func (*ChangePaidRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ChangePaidRequest) GetBank() string {
+	if x != nil {
+		return x.Bank
+	}
+	return ""
+}
+
+func (x *ChangePaidRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *ChangePaidRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *ChangePaidRequest) GetDni() string {
+	if x != nil {
+		return x.Dni
+	}
+	return ""
+}
+
+func (x *ChangePaidRequest) GetConcept() string {
+	if x != nil {
+		return x.Concept
+	}
+	return ""
+}
+
+type ChangePaidResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// reference is empty while the payout is pending
+	Reference string `protobuf:"bytes,1,opt,name=reference,proto3" json:"reference,omitempty"`
+	// pending is whether the payout waits for a finance user to approve it
+	Pending       bool  `protobuf:"varint,2,opt,name=pending,proto3" json:"pending,omitempty"`
+	PayoutId      int64 `protobuf:"varint,3,opt,name=payout_id,json=payoutId,proto3" json:"payout_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangePaidResponse) Reset() {
+	*x = ChangePaidResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangePaidResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangePaidResponse) ProtoMessage() {}
+
+func (x *ChangePaidResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangePaidResponse.ProtoReflect.Descriptor instead.
+func (*ChangePaidResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ChangePaidResponse) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *ChangePaidResponse) GetPending() bool {
+	if x != nil {
+		return x.Pending
+	}
+	return false
+}
+
+func (x *ChangePaidResponse) GetPayoutId() int64 {
+	if x != nil {
+		return x.PayoutId
+	}
+	return 0
+}
+
+type GetOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOperationRequest) Reset() {
+	*x = GetOperationRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOperationRequest) ProtoMessage() {}
+
+func (x *GetOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOperationRequest.ProtoReflect.Descriptor instead.
+func (*GetOperationRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetOperationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetOperationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     *Operation             `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOperationResponse) Reset() {
+	*x = GetOperationResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOperationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOperationResponse) ProtoMessage() {}
+
+func (x *GetOperationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOperationResponse.ProtoReflect.Descriptor instead.
+func (*GetOperationResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetOperationResponse) GetOperation() *Operation {
+	if x != nil {
+		return x.Operation
+	}
+	return nil
+}
+
+type WatchOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOperationRequest) Reset() {
+	*x = WatchOperationRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOperationRequest) ProtoMessage() {}
+
+func (x *WatchOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOperationRequest.ProtoReflect.Descriptor instead.
+func (*WatchOperationRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchOperationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type WatchOperationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     *Operation             `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOperationResponse) Reset() {
+	*x = WatchOperationResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOperationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOperationResponse) ProtoMessage() {}
+
+func (x *WatchOperationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOperationResponse.ProtoReflect.Descriptor instead.
+func (*WatchOperationResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchOperationResponse) GetOperation() *Operation {
+	if x != nil {
+		return x.Operation
+	}
+	return nil
+}
+
+type Operation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Reference     string                 `protobuf:"bytes,3,opt,name=reference,proto3" json:"reference,omitempty"`
+	Success       bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_r4_v1_r4_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Operation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Operation) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Operation) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *Operation) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type FindPaymentsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// from and to are YYYY-MM-DD days in the service time zone, both included
+	From        string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To          string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Reference   string `protobuf:"bytes,3,opt,name=reference,proto3" json:"reference,omitempty"`
+	SenderPhone string `protobuf:"bytes,4,opt,name=sender_phone,json=senderPhone,proto3" json:"sender_phone,omitempty"`
+	// bank is the issuing bank code
+	Bank string `protobuf:"bytes,5,opt,name=bank,proto3" json:"bank,omitempty"`
+	// limit defaults to 100, at most 1000
+	Limit         int32 `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32 `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindPaymentsRequest) Reset() {
+	*x = FindPaymentsRequest{}
+	mi := &file_r4_v1_r4_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindPaymentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindPaymentsRequest) ProtoMessage() {}
+
+func (x *FindPaymentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindPaymentsRequest.ProtoReflect.Descriptor instead.
+func (*FindPaymentsRequest) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FindPaymentsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *FindPaymentsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *FindPaymentsRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *FindPaymentsRequest) GetSenderPhone() string {
+	if x != nil {
+		return x.SenderPhone
+	}
+	return ""
+}
+
+func (x *FindPaymentsRequest) GetBank() string {
+	if x != nil {
+		return x.Bank
+	}
+	return ""
+}
+
+func (x *FindPaymentsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *FindPaymentsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type FindPaymentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payments      []*Payment             `protobuf:"bytes,1,rep,name=payments,proto3" json:"payments,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindPaymentsResponse) Reset() {
+	*x = FindPaymentsResponse{}
+	mi := &file_r4_v1_r4_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindPaymentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindPaymentsResponse) ProtoMessage() {}
+
+func (x *FindPaymentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindPaymentsResponse.ProtoReflect.Descriptor instead.
+func (*FindPaymentsResponse) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FindPaymentsResponse) GetPayments() []*Payment {
+	if x != nil {
+		return x.Payments
+	}
+	return nil
+}
+
+func (x *FindPaymentsResponse) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *FindPaymentsResponse) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type Payment struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Reference       string                 `protobuf:"bytes,2,opt,name=reference,proto3" json:"reference,omitempty"`
+	Amount          float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	SenderPhone     string                 `protobuf:"bytes,4,opt,name=sender_phone,json=senderPhone,proto3" json:"sender_phone,omitempty"`
+	CommercePhone   string                 `protobuf:"bytes,5,opt,name=commerce_phone,json=commercePhone,proto3" json:"commerce_phone,omitempty"`
+	IssuingBank     string                 `protobuf:"bytes,6,opt,name=issuing_bank,json=issuingBank,proto3" json:"issuing_bank,omitempty"`
+	IssuingBankName string                 `protobuf:"bytes,7,opt,name=issuing_bank_name,json=issuingBankName,proto3" json:"issuing_bank_name,omitempty"`
+	// order_id is unset until the payment is linked to an order
+	OrderId       *int64                 `protobuf:"varint,8,opt,name=order_id,json=orderId,proto3,oneof" json:"order_id,omitempty"`
+	Date          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=date,proto3" json:"date,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Payment) Reset() {
+	*x = Payment{}
+	mi := &file_r4_v1_r4_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Payment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Payment) ProtoMessage() {}
+
+func (x *Payment) ProtoReflect() protoreflect.Message {
+	mi := &file_r4_v1_r4_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Payment.ProtoReflect.Descriptor instead.
+func (*Payment) Descriptor() ([]byte, []int) {
+	return file_r4_v1_r4_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Payment) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Payment) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *Payment) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Payment) GetSenderPhone() string {
+	if x != nil {
+		return x.SenderPhone
+	}
+	return ""
+}
+
+func (x *Payment) GetCommercePhone() string {
+	if x != nil {
+		return x.CommercePhone
+	}
+	return ""
+}
+
+func (x *Payment) GetIssuingBank() string {
+	if x != nil {
+		return x.IssuingBank
+	}
+	return ""
+}
+
+func (x *Payment) GetIssuingBankName() string {
+	if x != nil {
+		return x.IssuingBankName
+	}
+	return ""
+}
+
+func (x *Payment) GetOrderId() int64 {
+	if x != nil && x.OrderId != nil {
+		return *x.OrderId
+	}
+	return 0
+}
+
+func (x *Payment) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *Payment) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+var File_r4_v1_r4_proto protoreflect.FileDescriptor
+
+const file_r4_v1_r4_proto_rawDesc = "" +
+	"\n" +
+	"\x0er4/v1/r4.proto\x12\x05r4.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x13\n" +
+	"\x11GetBCVRateRequest\"<\n" +
+	"\x12GetBCVRateResponse\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x12\n" +
+	"\x04rate\x18\x02 \x01(\x01R\x04rate\"h\n" +
+	"\x12GenerateOTPRequest\x12\x12\n" +
+	"\x04bank\x18\x01 \x01(\tR\x04bank\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x01R\x06amount\x12\x14\n" +
+	"\x05phone\x18\x03 \x01(\tR\x05phone\x12\x10\n" +
+	"\x03dni\x18\x04 \x01(\tR\x03dni\"\x15\n" +
+	"\x13GenerateOTPResponse\"\xb3\x01\n" +
+	"\x1dValidateImmediateDebitRequest\x12\x12\n" +
+	"\x04bank\x18\x01 \x01(\tR\x04bank\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x01R\x06amount\x12\x14\n" +
+	"\x05phone\x18\x03 \x01(\tR\x05phone\x12\x10\n" +
+	"\x03dni\x18\x04 \x01(\tR\x03dni\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x10\n" +
+	"\x03otp\x18\x06 \x01(\tR\x03otp\x12\x18\n" +
+	"\aconcept\x18\a \x01(\tR\aconcept\"\x98\x01\n" +
+	"\x1eValidateImmediateDebitResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x1c\n" +
+	"\treference\x18\x03 \x01(\tR\treference\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x1a\n" +
+	"\baccepted\x18\x05 \x01(\bR\baccepted\"\x81\x01\n" +
+	"\x11ChangePaidRequest\x12\x12\n" +
+	"\x04bank\x18\x01 \x01(\tR\x04bank\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x01R\x06amount\x12\x14\n" +
+	"\x05phone\x18\x03 \x01(\tR\x05phone\x12\x10\n" +
+	"\x03dni\x18\x04 \x01(\tR\x03dni\x12\x18\n" +
+	"\aconcept\x18\x05 \x01(\tR\aconcept\"i\n" +
+	"\x12ChangePaidResponse\x12\x1c\n" +
+	"\treference\x18\x01 \x01(\tR\treference\x12\x18\n" +
+	"\apending\x18\x02 \x01(\bR\apending\x12\x1b\n" +
+	"\tpayout_id\x18\x03 \x01(\x03R\bpayoutId\"%\n" +
+	"\x13GetOperationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"F\n" +
+	"\x14GetOperationResponse\x12.\n" +
+	"\toperation\x18\x01 \x01(\v2\x10.r4.v1.OperationR\toperation\"'\n" +
+	"\x15WatchOperationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"H\n" +
+	"\x16WatchOperationResponse\x12.\n" +
+	"\toperation\x18\x01 \x01(\v2\x10.r4.v1.OperationR\toperation\"g\n" +
+	"\tOperation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x1c\n" +
+	"\treference\x18\x03 \x01(\tR\treference\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\"\xbc\x01\n" +
+	"\x13FindPaymentsRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x1c\n" +
+	"\treference\x18\x03 \x01(\tR\treference\x12!\n" +
+	"\fsender_phone\x18\x04 \x01(\tR\vsenderPhone\x12\x12\n" +
+	"\x04bank\x18\x05 \x01(\tR\x04bank\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\a \x01(\x05R\x06offset\"p\n" +
+	"\x14FindPaymentsResponse\x12*\n" +
+	"\bpayments\x18\x01 \x03(\v2\x0e.r4.v1.PaymentR\bpayments\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\x80\x03\n" +
+	"\aPayment\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1c\n" +
+	"\treference\x18\x02 \x01(\tR\treference\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12!\n" +
+	"\fsender_phone\x18\x04 \x01(\tR\vsenderPhone\x12%\n" +
+	"\x0ecommerce_phone\x18\x05 \x01(\tR\rcommercePhone\x12!\n" +
+	"\fissuing_bank\x18\x06 \x01(\tR\vissuingBank\x12*\n" +
+	"\x11issuing_bank_name\x18\a \x01(\tR\x0fissuingBankName\x12\x1e\n" +
+	"\border_id\x18\b \x01(\x03H\x00R\aorderId\x88\x01\x01\x12.\n" +
+	"\x04date\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAtB\v\n" +
+	"\t_order_id2\xd8\x03\n" +
+	"\tR4Service\x12A\n" +
+	"\n" +
+	"GetBCVRate\x12\x18.r4.v1.GetBCVRateRequest\x1a\x19.r4.v1.GetBCVRateResponse\x12D\n" +
+	"\vGenerateOTP\x12\x19.r4.v1.GenerateOTPRequest\x1a\x1a.r4.v1.GenerateOTPResponse\x12e\n" +
+	"\x16ValidateImmediateDebit\x12$.r4.v1.ValidateImmediateDebitRequest\x1a%.r4.v1.ValidateImmediateDebitResponse\x12A\n" +
+	"\n" +
+	"ChangePaid\x12\x18.r4.v1.ChangePaidRequest\x1a\x19.r4.v1.ChangePaidResponse\x12G\n" +
+	"\fGetOperation\x12\x1a.r4.v1.GetOperationRequest\x1a\x1b.r4.v1.GetOperationResponse\x12O\n" +
+	"\x0eWatchOperation\x12\x1c.r4.v1.WatchOperationRequest\x1a\x1d.r4.v1.WatchOperationResponse0\x012Y\n" +
+	"\x0ePaymentService\x12G\n" +
+	"\fFindPayments\x12\x1a.r4.v1.FindPaymentsRequest\x1a\x1b.r4.v1.FindPaymentsResponseB+Z)bone_appetit_r4_service/pkg/pb/r4/v1;r4v1b\x06proto3"
+
+var (
+	file_r4_v1_r4_proto_rawDescOnce sync.Once
+	file_r4_v1_r4_proto_rawDescData []byte
+)
+
+func file_r4_v1_r4_proto_rawDescGZIP() []byte {
+	file_r4_v1_r4_proto_rawDescOnce.Do(func() {
+		file_r4_v1_r4_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_r4_v1_r4_proto_rawDesc), len(file_r4_v1_r4_proto_rawDesc)))
+	})
+	return file_r4_v1_r4_proto_rawDescData
+}
+
+var file_r4_v1_r4_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_r4_v1_r4_proto_goTypes = []any{
+	(*GetBCVRateRequest)(nil),              // 0: r4.v1.GetBCVRateRequest
+	(*GetBCVRateResponse)(nil),             // 1: r4.v1.GetBCVRateResponse
+	(*GenerateOTPRequest)(nil),             // 2: r4.v1.GenerateOTPRequest
+	(*GenerateOTPResponse)(nil),            // 3: r4.v1.GenerateOTPResponse
+	(*ValidateImmediateDebitRequest)(nil),  // 4: r4.v1.ValidateImmediateDebitRequest
+	(*ValidateImmediateDebitResponse)(nil), // 5: r4.v1.ValidateImmediateDebitResponse
+	(*ChangePaidRequest)(nil),              // 6: r4.v1.ChangePaidRequest
+	(*ChangePaidResponse)(nil),             // 7: r4.v1.ChangePaidResponse
+	(*GetOperationRequest)(nil),            // 8: r4.v1.GetOperationRequest
+	(*GetOperationResponse)(nil),           // 9: r4.v1.GetOperationResponse
+	(*WatchOperationRequest)(nil),          // 10: r4.v1.WatchOperationRequest
+	(*WatchOperationResponse)(nil),         // 11: r4.v1.WatchOperationResponse
+	(*Operation)(nil),                      // 12: r4.v1.Operation
+	(*FindPaymentsRequest)(nil),            // 13: r4.v1.FindPaymentsRequest
+	(*FindPaymentsResponse)(nil),           // 14: r4.v1.FindPaymentsResponse
+	(*Payment)(nil),                        // 15: r4.v1.Payment
+	(*timestamppb.Timestamp)(nil),          // 16: google.protobuf.Timestamp
+}
+var file_r4_v1_r4_proto_depIdxs = []int32{
+	12, // 0: r4.v1.GetOperationResponse.operation:type_name -> r4.v1.Operation
+	12, // 1: r4.v1.WatchOperationResponse.operation:type_name -> r4.v1.Operation
+	15, // 2: r4.v1.FindPaymentsResponse.payments:type_name -> r4.v1.Payment
+	16, // 3: r4.v1.Payment.date:type_name -> google.protobuf.Timestamp
+	16, // 4: r4.v1.Payment.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: r4.v1.R4Service.GetBCVRate:input_type -> r4.v1.GetBCVRateRequest
+	2,  // 6: r4.v1.R4Service.GenerateOTP:input_type -> r4.v1.GenerateOTPRequest
+	4,  // 7: r4.v1.R4Service.ValidateImmediateDebit:input_type -> r4.v1.ValidateImmediateDebitRequest
+	6,  // 8: r4.v1.R4Service.ChangePaid:input_type -> r4.v1.ChangePaidRequest
+	8,  // 9: r4.v1.R4Service.GetOperation:input_type -> r4.v1.GetOperationRequest
+	10, // 10: r4.v1.R4Service.WatchOperation:input_type -> r4.v1.WatchOperationRequest
+	13, // 11: r4.v1.PaymentService.FindPayments:input_type -> r4.v1.FindPaymentsRequest
+	1,  // 12: r4.v1.R4Service.GetBCVRate:output_type -> r4.v1.GetBCVRateResponse
+	3,  // 13: r4.v1.R4Service.GenerateOTP:output_type -> r4.v1.GenerateOTPResponse
+	5,  // 14: r4.v1.R4Service.ValidateImmediateDebit:output_type -> r4.v1.ValidateImmediateDebitResponse
+	7,  // 15: r4.v1.R4Service.ChangePaid:output_type -> r4.v1.ChangePaidResponse
+	9,  // 16: r4.v1.R4Service.GetOperation:output_type -> r4.v1.GetOperationResponse
+	11, // 17: r4.v1.R4Service.WatchOperation:output_type -> r4.v1.WatchOperationResponse
+	14, // 18: r4.v1.PaymentService.FindPayments:output_type -> r4.v1.FindPaymentsResponse
+	12, // [12:19] is the sub-list for method output_type
+	5,  // [5:12] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_r4_v1_r4_proto_init() }
+func file_r4_v1_r4_proto_init() {
+	if File_r4_v1_r4_proto != nil {
+		return
+	}
+	file_r4_v1_r4_proto_msgTypes[15].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_r4_v1_r4_proto_rawDesc), len(file_r4_v1_r4_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_r4_v1_r4_proto_goTypes,
+		DependencyIndexes: file_r4_v1_r4_proto_depIdxs,
+		MessageInfos:      file_r4_v1_r4_proto_msgTypes,
+	}.Build()
+	File_r4_v1_r4_proto = out.File
+	file_r4_v1_r4_proto_goTypes = nil
+	file_r4_v1_r4_proto_depIdxs = nil
+}
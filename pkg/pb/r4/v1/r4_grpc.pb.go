@@ -0,0 +1,445 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: r4/v1/r4.proto
+
+package r4v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	R4Service_GetBCVRate_FullMethodName             = "/r4.v1.R4Service/GetBCVRate"
+	R4Service_GenerateOTP_FullMethodName            = "/r4.v1.R4Service/GenerateOTP"
+	R4Service_ValidateImmediateDebit_FullMethodName = "/r4.v1.R4Service/ValidateImmediateDebit"
+	R4Service_ChangePaid_FullMethodName             = "/r4.v1.R4Service/ChangePaid"
+	R4Service_GetOperation_FullMethodName           = "/r4.v1.R4Service/GetOperation"
+	R4Service_WatchOperation_FullMethodName         = "/r4.v1.R4Service/WatchOperation"
+)
+
+// R4ServiceClient is the client API for R4Service service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// R4Service calls R4 on behalf of the store the API key belongs to. Every call must
+// carry the key in the x-api-key metadata.
+type R4ServiceClient interface {
+	// GetBCVRate returns the BCV exchange rate for USD on the current date
+	GetBCVRate(ctx context.Context, in *GetBCVRateRequest, opts ...grpc.CallOption) (*GetBCVRateResponse, error)
+	// GenerateOTP sends the payer the OTP authorizing an immediate debit
+	GenerateOTP(ctx context.Context, in *GenerateOTPRequest, opts ...grpc.CallOption) (*GenerateOTPResponse, error)
+	// ValidateImmediateDebit debits the payer with the OTP they received and waits for
+	// the result, returning the debit still pending (AC00) if R4 does not settle it in time
+	ValidateImmediateDebit(ctx context.Context, in *ValidateImmediateDebitRequest, opts ...grpc.CallOption) (*ValidateImmediateDebitResponse, error)
+	// ChangePaid pays change in bolívares to a mobile payment account
+	ChangePaid(ctx context.Context, in *ChangePaidRequest, opts ...grpc.CallOption) (*ChangePaidResponse, error)
+	// GetOperation returns the state of an immediate debit
+	GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*GetOperationResponse, error)
+	// WatchOperation sends the state of an immediate debit and then every change,
+	// ending once it leaves AC00
+	WatchOperation(ctx context.Context, in *WatchOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchOperationResponse], error)
+}
+
+type r4ServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewR4ServiceClient(cc grpc.ClientConnInterface) R4ServiceClient {
+	return &r4ServiceClient{cc}
+}
+
+func (c *r4ServiceClient) GetBCVRate(ctx context.Context, in *GetBCVRateRequest, opts ...grpc.CallOption) (*GetBCVRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBCVRateResponse)
+	err := c.cc.Invoke(ctx, R4Service_GetBCVRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *r4ServiceClient) GenerateOTP(ctx context.Context, in *GenerateOTPRequest, opts ...grpc.CallOption) (*GenerateOTPResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateOTPResponse)
+	err := c.cc.Invoke(ctx, R4Service_GenerateOTP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *r4ServiceClient) ValidateImmediateDebit(ctx context.Context, in *ValidateImmediateDebitRequest, opts ...grpc.CallOption) (*ValidateImmediateDebitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateImmediateDebitResponse)
+	err := c.cc.Invoke(ctx, R4Service_ValidateImmediateDebit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *r4ServiceClient) ChangePaid(ctx context.Context, in *ChangePaidRequest, opts ...grpc.CallOption) (*ChangePaidResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangePaidResponse)
+	err := c.cc.Invoke(ctx, R4Service_ChangePaid_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *r4ServiceClient) GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*GetOperationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOperationResponse)
+	err := c.cc.Invoke(ctx, R4Service_GetOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *r4ServiceClient) WatchOperation(ctx context.Context, in *WatchOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchOperationResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &R4Service_ServiceDesc.Streams[0], R4Service_WatchOperation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchOperationRequest, WatchOperationResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type R4Service_WatchOperationClient = grpc.ServerStreamingClient[WatchOperationResponse]
+
+// R4ServiceServer is the server API for R4Service service.
+// All implementations must embed UnimplementedR4ServiceServer
+// for forward compatibility.
+//
+// R4Service calls R4 on behalf of the store the API key belongs to. Every call must
+// carry the key in the x-api-key metadata.
+type R4ServiceServer interface {
+	// GetBCVRate returns the BCV exchange rate for USD on the current date
+	GetBCVRate(context.Context, *GetBCVRateRequest) (*GetBCVRateResponse, error)
+	// GenerateOTP sends the payer the OTP authorizing an immediate debit
+	GenerateOTP(context.Context, *GenerateOTPRequest) (*GenerateOTPResponse, error)
+	// ValidateImmediateDebit debits the payer with the OTP they received and waits for
+	// the result, returning the debit still pending (AC00) if R4 does not settle it in time
+	ValidateImmediateDebit(context.Context, *ValidateImmediateDebitRequest) (*ValidateImmediateDebitResponse, error)
+	// ChangePaid pays change in bolívares to a mobile payment account
+	ChangePaid(context.Context, *ChangePaidRequest) (*ChangePaidResponse, error)
+	// GetOperation returns the state of an immediate debit
+	GetOperation(context.Context, *GetOperationRequest) (*GetOperationResponse, error)
+	// WatchOperation sends the state of an immediate debit and then every change,
+	// ending once it leaves AC00
+	WatchOperation(*WatchOperationRequest, grpc.ServerStreamingServer[WatchOperationResponse]) error
+	mustEmbedUnimplementedR4ServiceServer()
+}
+
+// UnimplementedR4ServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedR4ServiceServer struct{}
+
+func (UnimplementedR4ServiceServer) GetBCVRate(context.Context, *GetBCVRateRequest) (*GetBCVRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBCVRate not implemented")
+}
+func (UnimplementedR4ServiceServer) GenerateOTP(context.Context, *GenerateOTPRequest) (*GenerateOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateOTP not implemented")
+}
+func (UnimplementedR4ServiceServer) ValidateImmediateDebit(context.Context, *ValidateImmediateDebitRequest) (*ValidateImmediateDebitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateImmediateDebit not implemented")
+}
+func (UnimplementedR4ServiceServer) ChangePaid(context.Context, *ChangePaidRequest) (*ChangePaidResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePaid not implemented")
+}
+func (UnimplementedR4ServiceServer) GetOperation(context.Context, *GetOperationRequest) (*GetOperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperation not implemented")
+}
+func (UnimplementedR4ServiceServer) WatchOperation(*WatchOperationRequest, grpc.ServerStreamingServer[WatchOperationResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchOperation not implemented")
+}
+func (UnimplementedR4ServiceServer) mustEmbedUnimplementedR4ServiceServer() {}
+func (UnimplementedR4ServiceServer) testEmbeddedByValue()                   {}
+
+// UnsafeR4ServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to R4ServiceServer will
+// result in compilation errors.
+type UnsafeR4ServiceServer interface {
+	mustEmbedUnimplementedR4ServiceServer()
+}
+
+func RegisterR4ServiceServer(s grpc.ServiceRegistrar, srv R4ServiceServer) {
+	// If the following call pancis, it indicates UnimplementedR4ServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&R4Service_ServiceDesc, srv)
+}
+
+func _R4Service_GetBCVRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBCVRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(R4ServiceServer).GetBCVRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: R4Service_GetBCVRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(R4ServiceServer).GetBCVRate(ctx, req.(*GetBCVRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _R4Service_GenerateOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(R4ServiceServer).GenerateOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: R4Service_GenerateOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(R4ServiceServer).GenerateOTP(ctx, req.(*GenerateOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _R4Service_ValidateImmediateDebit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateImmediateDebitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(R4ServiceServer).ValidateImmediateDebit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: R4Service_ValidateImmediateDebit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(R4ServiceServer).ValidateImmediateDebit(ctx, req.(*ValidateImmediateDebitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _R4Service_ChangePaid_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(R4ServiceServer).ChangePaid(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: R4Service_ChangePaid_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(R4ServiceServer).ChangePaid(ctx, req.(*ChangePaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _R4Service_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(R4ServiceServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: R4Service_GetOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(R4ServiceServer).GetOperation(ctx, req.(*GetOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _R4Service_WatchOperation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchOperationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(R4ServiceServer).WatchOperation(m, &grpc.GenericServerStream[WatchOperationRequest, WatchOperationResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type R4Service_WatchOperationServer = grpc.ServerStreamingServer[WatchOperationResponse]
+
+// R4Service_ServiceDesc is the grpc.ServiceDesc for R4Service service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var R4Service_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "r4.v1.R4Service",
+	HandlerType: (*R4ServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBCVRate",
+			Handler:    _R4Service_GetBCVRate_Handler,
+		},
+		{
+			MethodName: "GenerateOTP",
+			Handler:    _R4Service_GenerateOTP_Handler,
+		},
+		{
+			MethodName: "ValidateImmediateDebit",
+			Handler:    _R4Service_ValidateImmediateDebit_Handler,
+		},
+		{
+			MethodName: "ChangePaid",
+			Handler:    _R4Service_ChangePaid_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _R4Service_GetOperation_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchOperation",
+			Handler:       _R4Service_WatchOperation_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "r4/v1/r4.proto",
+}
+
+const (
+	PaymentService_FindPayments_FullMethodName = "/r4.v1.PaymentService/FindPayments"
+)
+
+// PaymentServiceClient is the client API for PaymentService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PaymentService queries the mobile payments R4 notified to the store the API key belongs to
+type PaymentServiceClient interface {
+	// FindPayments lists the payments matching the filters, newest first
+	FindPayments(ctx context.Context, in *FindPaymentsRequest, opts ...grpc.CallOption) (*FindPaymentsResponse, error)
+}
+
+type paymentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPaymentServiceClient(cc grpc.ClientConnInterface) PaymentServiceClient {
+	return &paymentServiceClient{cc}
+}
+
+func (c *paymentServiceClient) FindPayments(ctx context.Context, in *FindPaymentsRequest, opts ...grpc.CallOption) (*FindPaymentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindPaymentsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_FindPayments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PaymentServiceServer is the server API for PaymentService service.
+// All implementations must embed UnimplementedPaymentServiceServer
+// for forward compatibility.
+//
+// PaymentService queries the mobile payments R4 notified to the store the API key belongs to
+type PaymentServiceServer interface {
+	// FindPayments lists the payments matching the filters, newest first
+	FindPayments(context.Context, *FindPaymentsRequest) (*FindPaymentsResponse, error)
+	mustEmbedUnimplementedPaymentServiceServer()
+}
+
+// UnimplementedPaymentServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPaymentServiceServer struct{}
+
+func (UnimplementedPaymentServiceServer) FindPayments(context.Context, *FindPaymentsRequest) (*FindPaymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
+func (UnimplementedPaymentServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePaymentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PaymentServiceServer will
+// result in compilation errors.
+type UnsafePaymentServiceServer interface {
+	mustEmbedUnimplementedPaymentServiceServer()
+}
+
+func RegisterPaymentServiceServer(s grpc.ServiceRegistrar, srv PaymentServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPaymentServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PaymentService_ServiceDesc, srv)
+}
+
+func _PaymentService_FindPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindPaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).FindPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_FindPayments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).FindPayments(ctx, req.(*FindPaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PaymentService_ServiceDesc is the grpc.ServiceDesc for PaymentService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PaymentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "r4.v1.PaymentService",
+	HandlerType: (*PaymentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FindPayments",
+			Handler:    _PaymentService_FindPayments_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "r4/v1/r4.proto",
+}
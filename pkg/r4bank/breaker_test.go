@@ -0,0 +1,121 @@
+package r4bank
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker on a clock the test moves with advance
+func newTestBreaker(threshold int, openTimeout time.Duration) (*breaker, func(time.Duration)) {
+	now := time.Unix(1700000000, 0)
+	var mu sync.Mutex
+	b := newBreaker(threshold, openTimeout)
+	b.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return b, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		b.allow()
+		b.record(false)
+	}
+	// A success in between starts the count again
+	b.allow()
+	b.record(true)
+	for i := 0; i < 2; i++ {
+		b.allow()
+		b.record(false)
+	}
+	if state := b.current(); state != BreakerClosed {
+		t.Fatalf("state after 2 consecutive failures = %s, want closed", state)
+	}
+
+	b.allow()
+	b.record(false)
+	if state := b.current(); state != BreakerOpen {
+		t.Fatalf("state after 3 consecutive failures = %s, want open", state)
+	}
+	if b.allow() {
+		t.Fatal("an open breaker allowed a call")
+	}
+}
+
+func TestBreakerProbesOnceAfterTheTimeout(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+	b.allow()
+	b.record(false)
+
+	advance(59 * time.Second)
+	if b.allow() {
+		t.Fatal("allowed a call before the open timeout")
+	}
+	advance(time.Second)
+	if state := b.current(); state != BreakerHalfOpen {
+		t.Fatalf("state after the open timeout = %s, want half-open", state)
+	}
+
+	// Only one of many concurrent callers gets to probe
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != 1 {
+		t.Fatalf("%d probes allowed, want 1", n)
+	}
+
+	// A failed probe opens the breaker for another timeout
+	b.record(false)
+	if state := b.current(); state != BreakerOpen || b.allow() {
+		t.Fatalf("state after a failed probe = %s, want open", state)
+	}
+	advance(time.Minute)
+	if !b.allow() {
+		t.Fatal("no probe after the second timeout")
+	}
+	b.record(true)
+	if state := b.current(); state != BreakerClosed || !b.allow() || !b.allow() {
+		t.Fatalf("state after a successful probe = %s, want closed", state)
+	}
+}
+
+func TestBreakerReleaseLetsAnotherProbeThrough(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+	b.allow()
+	b.record(false)
+	advance(time.Minute)
+
+	if !b.allow() {
+		t.Fatal("no probe after the timeout")
+	}
+	// The caller of the probe gave up, which says nothing about R4
+	b.release()
+	if state := b.current(); state != BreakerHalfOpen {
+		t.Fatalf("state after a released probe = %s, want half-open", state)
+	}
+	if !b.allow() {
+		t.Fatal("a released probe kept the next one out")
+	}
+	if b.allow() {
+		t.Fatal("two probes allowed at once")
+	}
+}
@@ -7,39 +7,82 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
+	"bone_appetit_r4_service/pkg/telemetry"
 )
 
 type RestClient struct {
+	store   string
+	creds   atomic.Pointer[credentials]
+	client  *http.Client
+	breaker *breaker
+}
+
+// credentials are swapped as a whole so a call never mixes an old URL with a new token
+type credentials struct {
 	baseURL string
 	token   string
-	client  *http.Client
-	logger  *zap.Logger
+	// err tells why they cannot be used, nil when they look valid
+	err error
 }
 
+// NewClient creates the client of a store. Invalid credentials are logged and reported by
+// CredentialsError, and calls fail until valid ones are set.
 func NewClient(
+	store string,
 	endpoint string,
 	token string,
+	timeout time.Duration,
 	logger *zap.Logger,
 ) *RestClient {
-	uuidToken := GenerateAuthToken(token, "boneappetitR4ServiceSecretKey")
-	if !ValidateAuthToken(token, "boneappetitR4ServiceSecretKey", uuidToken) {
-		logger.Error("Invalid R4Bank token")
-		return nil
-	} else {
-		logger.Info("R4Bank token is valid", zap.String("uuidToken", uuidToken))
+	client := &RestClient{
+		store:   store,
+		client:  &http.Client{Timeout: timeout},
+		breaker: newBreaker(defaultFailureThreshold, defaultOpenTimeout),
+	}
+	client.UpdateCredentials(endpoint, token)
+	if err := client.CredentialsError(); err != nil {
+		logger.Error("invalid R4 credentials", zap.String("store", store), zap.Error(err))
+	}
+	return client
+}
+
+// UpdateCredentials replaces the entry point and commerce token used by the next calls
+func (r *RestClient) UpdateCredentials(endpoint, token string) {
+	r.creds.Store(&credentials{baseURL: endpoint, token: token, err: ValidateCredentials(endpoint, token)})
+}
+
+// CredentialsError tells why the current credentials cannot be used, nil when they look valid
+func (r *RestClient) CredentialsError() error {
+	return r.creds.Load().err
+}
+
+// ValidateCredentials checks the entry point is an http(s) URL and the commerce token is
+// set. It does not call R4, a token R4 does not know is only seen in the responses.
+func ValidateCredentials(endpoint, token string) error {
+	if strings.TrimSpace(token) == "" {
+		return errors.New("the commerce token is empty")
 	}
-	return &RestClient{
-		baseURL: endpoint,
-		token:   token,
-		client:  &http.Client{Timeout: 20 * time.Second},
-		logger:  logger,
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the entry point %q is not an http(s) URL", endpoint)
 	}
+	return nil
 }
 
 func GenerateAuthToken(key, message string) string {
@@ -53,47 +96,166 @@ func ValidateAuthToken(key, message, token string) bool {
 	return hmac.Equal([]byte(expected), []byte(token))
 }
 
-func (r *RestClient) Do(
-	ctx context.Context,
-	hmacInput string,
-	payload map[string]string,
-	endpoint string,
-) ([]byte, error) {
-	mac := hmac.New(sha256.New, []byte(r.token))
-	mac.Write([]byte(hmacInput))
-	auth := hex.EncodeToString(mac.Sum(nil))
+// BreakerState returns the state of the circuit breaker protecting R4
+func (r *RestClient) BreakerState() BreakerState {
+	return r.breaker.current()
+}
+
+// Client is the R4 Conecta API used by the services
+type Client interface {
+	BCVRate(ctx context.Context, req BCVRateRequest) (*BCVResponse, error)
+	GenerateOTP(ctx context.Context, req OTPRequest) (*OTPResponse, error)
+	ImmediateDebit(ctx context.Context, req ImmediateDebitRequest) (*ValidateDebitInmediateResponse, error)
+	GetOperation(ctx context.Context, req GetOperationRequest) (*GetOperationResponse, error)
+	Change(ctx context.Context, req ChangeRequest) (*ChangePaidResponse, error)
+}
+
+var _ Client = (*RestClient)(nil)
+
+// BCVRate calls MBbcv
+func (r *RestClient) BCVRate(ctx context.Context, req BCVRateRequest) (*BCVResponse, error) {
+	var resp BCVResponse
+	return &resp, r.call(ctx, "MBbcv", req, &resp)
+}
+
+// GenerateOTP calls GenerarOtp
+func (r *RestClient) GenerateOTP(ctx context.Context, req OTPRequest) (*OTPResponse, error) {
+	var resp OTPResponse
+	return &resp, r.call(ctx, "GenerarOtp", req, &resp)
+}
+
+// ImmediateDebit calls DebitoInmediato
+func (r *RestClient) ImmediateDebit(ctx context.Context, req ImmediateDebitRequest) (*ValidateDebitInmediateResponse, error) {
+	var resp ValidateDebitInmediateResponse
+	return &resp, r.call(ctx, "DebitoInmediato", req, &resp)
+}
 
-	body, err := json.Marshal(payload)
+// GetOperation calls ConsultarOperaciones
+func (r *RestClient) GetOperation(ctx context.Context, req GetOperationRequest) (*GetOperationResponse, error) {
+	var resp GetOperationResponse
+	return &resp, r.call(ctx, "ConsultarOperaciones", req, &resp)
+}
+
+// Change calls MBvuelto
+func (r *RestClient) Change(ctx context.Context, req ChangeRequest) (*ChangePaidResponse, error) {
+	var resp ChangePaidResponse
+	return &resp, r.call(ctx, "MBvuelto", req, &resp)
+}
+
+// call sends req to endpoint and decodes the response into out
+func (r *RestClient) call(ctx context.Context, endpoint string, req Request, out any) error {
+	data, err := r.do(ctx, endpoint, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		logs.FromContext(ctx).Error(err.Error(), logs.JSON("response", data))
+		return fmt.Errorf("error decodificando respuesta: %w", err)
+	}
+	return nil
+}
+
+// do signs req with the commerce token and posts it to endpoint
+func (r *RestClient) do(ctx context.Context, endpoint string, request Request) (data []byte, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "r4."+endpoint, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("r4.store", r.store),
+			attribute.String("r4.endpoint", endpoint),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	logger := logs.FromContext(ctx)
+
+	creds := r.creds.Load()
+	if creds.err != nil {
+		logger.Error("R4 credentials are invalid", zap.String("endpoint", endpoint), zap.Error(creds.err))
+		return nil, fmt.Errorf("R4 credentials are invalid: %w", creds.err)
+	}
+	auth := GenerateAuthToken(creds.token, request.HMACInput())
+
+	body, err := json.Marshal(request)
 	if err != nil {
-		r.logger.Error(err.Error(), zap.Any("payload", payload))
+		logger.Error(err.Error(), zap.String("endpoint", endpoint))
 		return nil, fmt.Errorf("error marshaling JSON: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s", r.baseURL, endpoint)
 	req, err := http.NewRequestWithContext(
-		ctx, http.MethodPost, url, bytes.NewReader(body),
+		ctx, http.MethodPost, creds.baseURL+"/"+endpoint, bytes.NewReader(body),
 	)
 	if err != nil {
-		r.logger.Error(err.Error())
+		logger.Error(err.Error())
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", auth)
-	req.Header.Set("Commerce", r.token)
+	req.Header.Set("Commerce", creds.token)
+
+	if !r.breaker.allow() {
+		metrics.R4Requests.WithLabelValues(r.store, endpoint, "circuit_open").Inc()
+		logger.Error(ErrCircuitOpen.Error(), zap.String("endpoint", endpoint))
+		return nil, ErrCircuitOpen
+	}
 
+	start := time.Now()
 	resp, err := r.client.Do(req)
 	if err != nil {
-		r.logger.Error(err.Error(), zap.Any("payload", payload))
+		metrics.R4RequestDuration.WithLabelValues(r.store, endpoint).Observe(time.Since(start).Seconds())
+		metrics.R4Requests.WithLabelValues(r.store, endpoint, "transport_error").Inc()
+		// A cancelled caller says nothing about R4's health
+		if ctx.Err() != nil {
+			r.breaker.release()
+		} else {
+			r.breaker.record(false)
+		}
+		logger.Error(err.Error(), logs.JSON("payload", body))
 		return nil, fmt.Errorf("error en request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	data, _ := io.ReadAll(resp.Body)
+	data, _ = io.ReadAll(resp.Body)
+	code := responseCode(resp.StatusCode, data)
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", resp.StatusCode),
+		attribute.String("r4.code", code),
+	)
+	metrics.R4RequestDuration.WithLabelValues(r.store, endpoint).Observe(time.Since(start).Seconds())
+	metrics.R4Requests.WithLabelValues(r.store, endpoint, code).Inc()
+	r.breaker.record(resp.StatusCode < 500)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		r.logger.Error("R4 API error: ", zap.String("body", string(data)), zap.Any("payload", payload))
-		return nil, fmt.Errorf("R4 API error: %s", string(data))
+		logger.Error("R4 API error", zap.Int("status", resp.StatusCode), zap.String("code", code),
+			logs.JSON("body", data), logs.JSON("payload", body))
+		return nil, &APIError{Status: resp.StatusCode, Code: code}
 	}
 
 	return data, nil
 }
+
+// APIError is returned when R4 answers with an HTTP error. The body is only logged,
+// redacted: errors reach the logs and the API responses as they are.
+type APIError struct {
+	Status int
+	// Code is the R4 code of the response, http_<status> when it has none
+	Code string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("R4 API error: HTTP %d, code %s", e.Status, e.Code)
+}
+
+// responseCode extracts the R4 code of a response, falling back to the HTTP status
+func responseCode(status int, body []byte) string {
+	var envelope struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Code != "" {
+		return envelope.Code
+	}
+	return fmt.Sprintf("http_%d", status)
+}
@@ -0,0 +1,45 @@
+package r4bank
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		endpoint, token string
+		valid           bool
+	}{
+		{"https://r4conecta.mibanco.com.ve", "token", true},
+		{"http://localhost:8081", "token", true},
+		{"https://r4conecta.mibanco.com.ve", "", false},
+		{"https://r4conecta.mibanco.com.ve", "  ", false},
+		{"", "token", false},
+		{"r4conecta.mibanco.com.ve", "token", false},
+		{"ftp://r4conecta.mibanco.com.ve", "token", false},
+		{"https://", "token", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateCredentials(tt.endpoint, tt.token); (err == nil) != tt.valid {
+			t.Errorf("ValidateCredentials(%q, %q) = %v, want valid %v", tt.endpoint, tt.token, err, tt.valid)
+		}
+	}
+}
+
+func TestClientWithInvalidCredentials(t *testing.T) {
+	client := NewClient("bone", "not a url", "token", time.Second, zap.NewNop())
+	if client.CredentialsError() == nil {
+		t.Fatal("invalid entry point not reported")
+	}
+	if _, err := client.BCVRate(context.Background(), BCVRateRequest{}); err == nil {
+		t.Fatal("a call with invalid credentials succeeded")
+	}
+
+	client.UpdateCredentials("https://r4conecta.mibanco.com.ve", "token")
+	if err := client.CredentialsError(); err != nil {
+		t.Fatalf("valid credentials reported: %v", err)
+	}
+}
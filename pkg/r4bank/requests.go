@@ -0,0 +1,95 @@
+package r4bank
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Request is a call to R4. HMACInput is the canonical string R4 expects signed in
+// the Authorization header, its field order differs per operation.
+type Request interface {
+	HMACInput() string
+}
+
+// Amount is a bolívar amount, sent to R4 as a string with two decimals
+type Amount float64
+
+func (a Amount) String() string {
+	return fmt.Sprintf("%.2f", float64(a))
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value, err := strconv.Unquote(string(data))
+	if err != nil {
+		value = string(data)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	*a = Amount(f)
+	return nil
+}
+
+// BCVRateRequest asks MBbcv for the BCV exchange rate of a currency on a date (YYYY-MM-DD)
+type BCVRateRequest struct {
+	Currency string `json:"Moneda"`
+	Date     string `json:"Fechavalor"`
+}
+
+func (r BCVRateRequest) HMACInput() string {
+	return r.Date + r.Currency
+}
+
+// OTPRequest asks GenerarOtp to send the payer the OTP authorizing an immediate debit
+type OTPRequest struct {
+	Bank   string `json:"Banco"`
+	Amount Amount `json:"Monto"`
+	Phone  string `json:"Telefono"`
+	DNI    string `json:"Cedula"`
+}
+
+func (r OTPRequest) HMACInput() string {
+	return r.Bank + r.Amount.String() + r.Phone + r.DNI
+}
+
+// ImmediateDebitRequest asks DebitoInmediato to debit the payer with the OTP they received
+type ImmediateDebitRequest struct {
+	Bank    string `json:"Banco"`
+	Amount  Amount `json:"Monto"`
+	Phone   string `json:"Telefono"`
+	DNI     string `json:"Cedula"`
+	Name    string `json:"Nombre"`
+	OTP     string `json:"OTP"`
+	Concept string `json:"Concepto"`
+}
+
+func (r ImmediateDebitRequest) HMACInput() string {
+	return r.Bank + r.DNI + r.Phone + r.Amount.String() + r.OTP
+}
+
+// GetOperationRequest asks ConsultarOperaciones for the state of an immediate debit
+type GetOperationRequest struct {
+	ID string `json:"id"`
+}
+
+func (r GetOperationRequest) HMACInput() string {
+	return r.ID
+}
+
+// ChangeRequest asks MBvuelto to pay change to a mobile payment account
+type ChangeRequest struct {
+	Phone   string `json:"TelefonoDestino"`
+	DNI     string `json:"Cedula"`
+	Bank    string `json:"Banco"`
+	Amount  Amount `json:"Monto"`
+	Concept string `json:"Concepto"`
+}
+
+func (r ChangeRequest) HMACInput() string {
+	return r.Phone + r.Amount.String() + r.Bank + r.DNI
+}
@@ -0,0 +1,352 @@
+// Package r4sim simulates the R4 Conecta API of Mi Banco for development and tests.
+// It checks the Authorization and Commerce headers like R4, issues and verifies OTPs,
+// moves immediate debits from AC00 to a terminal code after a delay, and can send
+// signed R4consulta/R4notifica webhooks to the service.
+package r4sim
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// R4 codes returned by the simulator
+const (
+	CodePending           = "AC00"
+	CodeAccepted          = "ACCP"
+	CodeInsufficientFunds = "AM04"
+	CodeInvalidOTP        = "TKCM"
+	CodeInvalidAmount     = "MD15"
+)
+
+// Config describes the commerce the simulator plays the bank for
+type Config struct {
+	// CommerceToken is the token the service sends in the Commerce header and signs with
+	CommerceToken string
+	// Secret is the webhook secret, R4 signs webhooks with HMAC(CommerceToken, Secret)
+	Secret string
+	// Rate is the BCV USD rate returned by MBbcv
+	Rate float64
+	// DebitDelay is how long an immediate debit stays AC00 before reaching its final code
+	DebitDelay time.Duration
+	// DebitCode is the final code of debits with a valid OTP, ACCP by default
+	DebitCode string
+	// Balance makes debits above it end in AM04, zero means unlimited
+	Balance float64
+	// OTPTTL is how long an OTP can be used, 5 minutes by default
+	OTPTTL time.Duration
+	Logger *zap.Logger
+}
+
+// Fault makes the next Times calls to Endpoint fail. Status answers with that HTTP
+// status, Code answers 200 with that R4 code, Delay holds the response.
+type Fault struct {
+	Endpoint string        `json:"endpoint"`
+	Status   int           `json:"status,omitempty"`
+	Code     string        `json:"code,omitempty"`
+	Delay    time.Duration `json:"delay,omitempty"`
+	Times    int           `json:"times"`
+}
+
+type otp struct {
+	code      string
+	key       string
+	expiresAt time.Time
+}
+
+type operation struct {
+	id        string
+	code      string
+	reference string
+	createdAt time.Time
+}
+
+// Simulator is an http.Handler serving the R4 endpoints used by the service
+type Simulator struct {
+	cfg    Config
+	logger *zap.Logger
+	mux    *http.ServeMux
+
+	mu         sync.Mutex
+	otps       map[string]otp
+	operations map[string]*operation
+	faults     []*Fault
+	calls      map[string]int
+}
+
+// New creates a Simulator
+func New(cfg Config) *Simulator {
+	if cfg.DebitCode == "" {
+		cfg.DebitCode = CodeAccepted
+	}
+	if cfg.OTPTTL == 0 {
+		cfg.OTPTTL = 5 * time.Minute
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = 36.5
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	s := &Simulator{
+		cfg:        cfg,
+		logger:     logger,
+		mux:        http.NewServeMux(),
+		otps:       make(map[string]otp),
+		operations: make(map[string]*operation),
+		calls:      make(map[string]int),
+	}
+	s.route("MBbcv", []string{"Fechavalor", "Moneda"}, s.bcv)
+	s.route("GenerarOtp", []string{"Banco", "Monto", "Telefono", "Cedula"}, s.generateOTP)
+	s.route("DebitoInmediato", []string{"Banco", "Cedula", "Telefono", "Monto", "OTP"}, s.debit)
+	s.route("ConsultarOperaciones", []string{"id"}, s.operation)
+	s.route("MBvuelto", []string{"TelefonoDestino", "Monto", "Banco", "Cedula"}, s.change)
+	s.mountControl()
+
+	return s
+}
+
+// NewServer starts the simulator on a local port, close it when done
+func NewServer(cfg Config) (*Simulator, *httptest.Server) {
+	s := New(cfg)
+	return s, httptest.NewServer(s)
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Inject queues a fault for an endpoint
+func (s *Simulator) Inject(f Fault) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// OTP returns the last OTP issued for phone, as the customer would read it in the SMS
+func (s *Simulator) OTP(phone string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.otps[phone]
+	return o.code, ok
+}
+
+// Calls returns how many requests an endpoint received
+func (s *Simulator) Calls(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[endpoint]
+}
+
+type handlerFunc func(payload map[string]string) (int, any)
+
+// route serves an endpoint whose Authorization is the HMAC of the fields, in order
+func (s *Simulator) route(endpoint string, hmacFields []string, handle handlerFunc) {
+	s.mux.HandleFunc("POST /"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls[endpoint]++
+		s.mu.Unlock()
+
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, response{Code: "400", Message: "invalid JSON: " + err.Error()})
+			return
+		}
+
+		if r.Header.Get("Commerce") != s.cfg.CommerceToken {
+			writeJSON(w, http.StatusUnauthorized, response{Code: "401", Message: "unknown commerce"})
+			return
+		}
+		var input strings.Builder
+		for _, field := range hmacFields {
+			input.WriteString(payload[field])
+		}
+		if !r4bank.ValidateAuthToken(s.cfg.CommerceToken, input.String(), r.Header.Get("Authorization")) {
+			writeJSON(w, http.StatusUnauthorized, response{Code: "401", Message: "invalid Authorization"})
+			return
+		}
+
+		if fault := s.takeFault(endpoint); fault != nil {
+			if fault.Delay > 0 {
+				select {
+				case <-time.After(fault.Delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			switch {
+			case fault.Status != 0:
+				writeJSON(w, fault.Status, response{Code: strconv.Itoa(fault.Status), Message: "injected failure"})
+				return
+			case fault.Code != "":
+				writeJSON(w, http.StatusOK, response{Code: fault.Code, Message: "injected failure"})
+				return
+			}
+		}
+
+		status, body := handle(payload)
+		s.logger.Info("r4sim", zap.String("endpoint", endpoint), zap.Int("status", status))
+		writeJSON(w, status, body)
+	})
+}
+
+func (s *Simulator) takeFault(endpoint string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Endpoint != endpoint {
+			continue
+		}
+		f.Times--
+		if f.Times == 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		copied := *f
+		return &copied
+	}
+	return nil
+}
+
+type response struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Simulator) bcv(payload map[string]string) (int, any) {
+	return http.StatusOK, r4bank.BCVResponse{
+		Code:       "00",
+		Fechavalor: payload["Fechavalor"],
+		Tipocambio: s.cfg.Rate,
+	}
+}
+
+// otpKey ties an OTP to the debit it was requested for
+func otpKey(payload map[string]string) string {
+	return strings.Join([]string{payload["Banco"], payload["Monto"], payload["Telefono"], payload["Cedula"]}, "|")
+}
+
+func (s *Simulator) generateOTP(payload map[string]string) (int, any) {
+	if _, err := parseAmount(payload["Monto"]); err != nil {
+		return http.StatusOK, r4bank.OTPResponse{Code: CodeInvalidAmount, Message: err.Error()}
+	}
+
+	code := randomDigits(8)
+	s.mu.Lock()
+	s.otps[payload["Telefono"]] = otp{code: code, key: otpKey(payload), expiresAt: time.Now().Add(s.cfg.OTPTTL)}
+	s.mu.Unlock()
+
+	// The simulator has no SMS, the OTP is logged for whoever is testing
+	s.logger.Info("r4sim OTP issued", zap.String("phone", payload["Telefono"]), zap.String("sim_code", code))
+
+	return http.StatusOK, r4bank.OTPResponse{Code: "202", Message: "OTP enviado", Success: true}
+}
+
+func (s *Simulator) debit(payload map[string]string) (int, any) {
+	amount, err := parseAmount(payload["Monto"])
+	if err != nil {
+		return http.StatusOK, r4bank.ValidateDebitInmediateResponse{Code: CodeInvalidAmount, Message: err.Error()}
+	}
+
+	op := &operation{id: newID(), createdAt: time.Now(), code: s.cfg.DebitCode}
+
+	s.mu.Lock()
+	issued, ok := s.otps[payload["Telefono"]]
+	switch {
+	case !ok || issued.code != payload["OTP"] || issued.key != otpKey(payload) || time.Now().After(issued.expiresAt):
+		op.code = CodeInvalidOTP
+	case s.cfg.Balance > 0 && amount > s.cfg.Balance:
+		op.code = CodeInsufficientFunds
+	default:
+		// An OTP authorizes a single debit
+		delete(s.otps, payload["Telefono"])
+	}
+	if op.code == CodeAccepted {
+		op.reference = randomDigits(8)
+	}
+	s.operations[op.id] = op
+	s.mu.Unlock()
+
+	return http.StatusOK, r4bank.ValidateDebitInmediateResponse{
+		Code:    CodePending,
+		Message: "En espera de respuesta del banco",
+		ID:      op.id,
+	}
+}
+
+func (s *Simulator) operation(payload map[string]string) (int, any) {
+	s.mu.Lock()
+	op, ok := s.operations[payload["id"]]
+	s.mu.Unlock()
+	if !ok {
+		return http.StatusNotFound, response{Code: "404", Message: "operation not found"}
+	}
+
+	if time.Since(op.createdAt) < s.cfg.DebitDelay {
+		return http.StatusOK, r4bank.GetOperationResponse{Code: CodePending}
+	}
+	return http.StatusOK, r4bank.GetOperationResponse{
+		Code:      op.code,
+		Reference: op.reference,
+		Success:   op.code == CodeAccepted,
+	}
+}
+
+func (s *Simulator) change(payload map[string]string) (int, any) {
+	amount, err := parseAmount(payload["Monto"])
+	if err != nil {
+		return http.StatusOK, r4bank.ChangePaidResponse{Code: CodeInvalidAmount, Message: err.Error()}
+	}
+	if s.cfg.Balance > 0 && amount > s.cfg.Balance {
+		return http.StatusOK, r4bank.ChangePaidResponse{Code: CodeInsufficientFunds, Message: "Saldo insuficiente"}
+	}
+
+	reference, _ := strconv.Atoi(randomDigits(8))
+	return http.StatusOK, r4bank.ChangePaidResponse{Code: "00", Message: "Vuelto enviado", Reference: reference}
+}
+
+func parseAmount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
+
+func randomDigits(n int) string {
+	var b strings.Builder
+	for range n {
+		d, _ := rand.Int(rand.Reader, big.NewInt(10))
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
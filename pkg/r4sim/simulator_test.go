@@ -0,0 +1,121 @@
+package r4sim
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+func newClient(t *testing.T, url, token string) *r4bank.RestClient {
+	t.Helper()
+	client := r4bank.NewClient("bone", url, token, 5*time.Second, zap.NewNop())
+	if client == nil {
+		t.Fatal("could not create R4 client")
+	}
+	return client
+}
+
+func TestImmediateDebitLifecycle(t *testing.T) {
+	sim, srv := NewServer(Config{CommerceToken: "token", DebitDelay: 50 * time.Millisecond})
+	defer srv.Close()
+	client := newClient(t, srv.URL, "token")
+	ctx := context.Background()
+
+	otpReq := r4bank.OTPRequest{Bank: "0102", Amount: 10, Phone: "04141234567", DNI: "V12345678"}
+	if _, err := client.GenerateOTP(ctx, otpReq); err != nil {
+		t.Fatalf("GenerarOtp: %v", err)
+	}
+	code, ok := sim.OTP("04141234567")
+	if !ok {
+		t.Fatal("no OTP issued")
+	}
+
+	debit, err := client.ImmediateDebit(ctx, r4bank.ImmediateDebitRequest{
+		Bank: "0102", Amount: 10, Phone: "04141234567", DNI: "V12345678", Name: "Maria Perez", OTP: code,
+	})
+	if err != nil {
+		t.Fatalf("DebitoInmediato: %v", err)
+	}
+	if debit.Code != CodePending {
+		t.Fatalf("debit code = %s, want %s", debit.Code, CodePending)
+	}
+
+	poll := func() *r4bank.GetOperationResponse {
+		op, err := client.GetOperation(ctx, r4bank.GetOperationRequest{ID: debit.ID})
+		if err != nil {
+			t.Fatalf("ConsultarOperaciones: %v", err)
+		}
+		return op
+	}
+	if op := poll(); op.Code != CodePending {
+		t.Fatalf("operation code before the delay = %s, want %s", op.Code, CodePending)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if op := poll(); op.Code != CodeAccepted || !op.Success || op.Reference == "" {
+		t.Fatalf("operation after the delay = %+v, want an accepted debit with a reference", op)
+	}
+}
+
+func TestWrongOTPEndsInTKCM(t *testing.T) {
+	_, srv := NewServer(Config{CommerceToken: "token"})
+	defer srv.Close()
+	client := newClient(t, srv.URL, "token")
+	ctx := context.Background()
+
+	debit, err := client.ImmediateDebit(ctx, r4bank.ImmediateDebitRequest{
+		Bank: "0102", Amount: 10, Phone: "04141234567", DNI: "V12345678", OTP: "00000000",
+	})
+	if err != nil {
+		t.Fatalf("DebitoInmediato: %v", err)
+	}
+
+	op, err := client.GetOperation(ctx, r4bank.GetOperationRequest{ID: debit.ID})
+	if err != nil {
+		t.Fatalf("ConsultarOperaciones: %v", err)
+	}
+	if op.Code != CodeInvalidOTP {
+		t.Fatalf("operation code = %s, want %s", op.Code, CodeInvalidOTP)
+	}
+}
+
+func TestRejectsBadSignatureAndInjectsFaults(t *testing.T) {
+	sim, srv := NewServer(Config{CommerceToken: "token"})
+	defer srv.Close()
+	ctx := context.Background()
+	rate := r4bank.BCVRateRequest{Currency: "USD", Date: "2025-01-02"}
+
+	if _, err := newClient(t, srv.URL, "other-token").BCVRate(ctx, rate); err == nil {
+		t.Fatal("call with an unknown commerce token succeeded")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/MBbcv", strings.NewReader(`{"Moneda":"USD","Fechavalor":"2025-01-02"}`))
+	req.Header.Set("Commerce", "token")
+	req.Header.Set("Authorization", r4bank.GenerateAuthToken("token", "USD2025-01-02"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("call signed in the wrong field order = %d, want 401", resp.StatusCode)
+	}
+
+	client := newClient(t, srv.URL, "token")
+	sim.Inject(Fault{Endpoint: "MBbcv", Status: 503})
+	if _, err := client.BCVRate(ctx, rate); err == nil {
+		t.Fatal("injected 503 was not returned")
+	}
+	got, err := client.BCVRate(ctx, rate)
+	if err != nil {
+		t.Fatalf("call after the fault was consumed: %v", err)
+	}
+	if got.Code != "00" || got.Fechavalor != rate.Date {
+		t.Fatalf("MBbcv = %+v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package r4sim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"bone_appetit_r4_service/pkg/r4bank"
+)
+
+// Consulta is the R4consulta webhook R4 sends before a mobile payment
+type Consulta struct {
+	IdCliente        string `json:"IdCliente"`
+	Monto            string `json:"Monto"`
+	TelefonoComercio string `json:"TelefonoComercio"`
+}
+
+// Notifica is the R4notifica webhook R4 sends when a mobile payment is credited
+type Notifica struct {
+	IdComercio       string `json:"IdComercio"`
+	TelefonoComercio string `json:"TelefonoComercio"`
+	TelefonoEmisor   string `json:"TelefonoEmisor"`
+	Concepto         string `json:"Concepto"`
+	BancoEmisor      string `json:"BancoEmisor"`
+	Monto            string `json:"Monto"`
+	FechaHora        string `json:"FechaHora"`
+	Referencia       string `json:"Referencia"`
+	CodigoRed        string `json:"CodigoRed"`
+}
+
+// SendConsulta posts a signed R4consulta webhook to baseURL, e.g. http://localhost:8080/appa
+func (s *Simulator) SendConsulta(ctx context.Context, baseURL string, req Consulta) (int, error) {
+	return s.sendWebhook(ctx, baseURL+"/R4consulta", req)
+}
+
+// SendNotifica posts a signed R4notifica webhook to baseURL. Empty fields get test values.
+func (s *Simulator) SendNotifica(ctx context.Context, baseURL string, req Notifica) (int, error) {
+	if req.Referencia == "" {
+		req.Referencia = randomDigits(8)
+	}
+	if req.FechaHora == "" {
+		req.FechaHora = time.Now().Format("2006-01-02 15:04:05")
+	}
+	if req.BancoEmisor == "" {
+		req.BancoEmisor = "0102"
+	}
+	if req.CodigoRed == "" {
+		req.CodigoRed = "00"
+	}
+	return s.sendWebhook(ctx, baseURL+"/R4notifica", req)
+}
+
+// sendWebhook signs body like R4: Authorization is HMAC(commerce token, secret)
+func (s *Simulator) sendWebhook(ctx context.Context, url string, body any) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", r4bank.GenerateAuthToken(s.cfg.CommerceToken, s.cfg.Secret))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending webhook to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+// mountControl adds the /_sim endpoints used to drive the standalone simulator:
+//
+//	GET  /_sim/otp?phone=04141234567  last OTP issued for the phone
+//	POST /_sim/faults                 queue a Fault
+//	POST /_sim/consulta?target=URL    send an R4consulta webhook
+//	POST /_sim/notifica?target=URL    send an R4notifica webhook
+func (s *Simulator) mountControl() {
+	s.mux.HandleFunc("GET /_sim/otp", func(w http.ResponseWriter, r *http.Request) {
+		code, ok := s.OTP(r.URL.Query().Get("phone"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, response{Code: "404", Message: "no OTP issued for this phone"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"otp": code})
+	})
+
+	s.mux.HandleFunc("POST /_sim/faults", func(w http.ResponseWriter, r *http.Request) {
+		var f Fault
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil || f.Endpoint == "" {
+			writeJSON(w, http.StatusBadRequest, response{Code: "400", Message: "expected a fault with an endpoint"})
+			return
+		}
+		s.Inject(f)
+		writeJSON(w, http.StatusAccepted, f)
+	})
+
+	s.mux.HandleFunc("POST /_sim/consulta", func(w http.ResponseWriter, r *http.Request) {
+		var req Consulta
+		s.forward(w, r, &req, func(target string) (int, error) { return s.SendConsulta(r.Context(), target, req) })
+	})
+
+	s.mux.HandleFunc("POST /_sim/notifica", func(w http.ResponseWriter, r *http.Request) {
+		var req Notifica
+		s.forward(w, r, &req, func(target string) (int, error) { return s.SendNotifica(r.Context(), target, req) })
+	})
+}
+
+func (s *Simulator) forward(w http.ResponseWriter, r *http.Request, req any, send func(target string) (int, error)) {
+	target := strings.TrimSuffix(r.URL.Query().Get("target"), "/")
+	if target == "" {
+		writeJSON(w, http.StatusBadRequest, response{Code: "400", Message: "target query parameter is required"})
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, response{Code: "400", Message: "invalid JSON: " + err.Error()})
+		return
+	}
+
+	status, err := send(target)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, response{Code: "502", Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"status": status})
+}
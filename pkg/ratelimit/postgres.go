@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// refilled is the tokens of the existing bucket once refilled for the time elapsed since
+// it was last taken from, up to its capacity ($2) at $3 tokens per second
+const refilled = `LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM (statement_timestamp() AT TIME ZONE 'UTC' - b.updated_at))::float8 * $3::float8)`
+
+// takeQuery refills the bucket and takes a token in a single statement, so concurrent
+// instances never share a token. A new bucket starts full, minus the token taken.
+const takeQuery = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $2::float8 - 1, true, statement_timestamp() AT TIME ZONE 'UTC')
+ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+    allowed = ` + refilled + ` >= 1,
+    updated_at = statement_timestamp() AT TIME ZONE 'UTC'
+RETURNING b.tokens, b.allowed`
+
+// PostgresStore keeps the buckets in the rate_limit_buckets table, shared by every
+// instance using the same database.
+type PostgresStore struct {
+	db *sql.DB
+
+	mu sync.Mutex
+	// longest is the longest period seen, after which any bucket is full again
+	longest   time.Duration
+	lastSweep time.Time
+}
+
+// NewPostgresStore creates a store on db, migrated with rate_limit_buckets
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take implements Store
+func (p *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	p.sweep(ctx, limit.Per)
+
+	var tokens float64
+	var allowed bool
+	err := p.db.QueryRowContext(ctx, takeQuery, key, float64(limit.Requests), limit.rate()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("taking rate limit token: %w", err)
+	}
+	if allowed {
+		return Result{Allowed: true}, nil
+	}
+	return Result{RetryAfter: retryAfter(tokens, limit)}, nil
+}
+
+// Refund implements Store
+func (p *PostgresStore) Refund(ctx context.Context, key string, limit Limit) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = LEAST($2::float8, tokens + 1) WHERE key = $1`,
+		key, float64(limit.Requests))
+	if err != nil {
+		return fmt.Errorf("refunding rate limit token: %w", err)
+	}
+	return nil
+}
+
+// sweep deletes the buckets idle for longer than the longest period, at most once every
+// sweepEvery per instance. A failed sweep is retried on the next one.
+func (p *PostgresStore) sweep(ctx context.Context, per time.Duration) {
+	p.mu.Lock()
+	if per > p.longest {
+		p.longest = per
+	}
+	now := time.Now()
+	if now.Sub(p.lastSweep) < sweepEvery {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSweep, per = now, p.longest
+	p.mu.Unlock()
+
+	_, _ = p.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets
+		WHERE updated_at < statement_timestamp() AT TIME ZONE 'UTC' - $1::interval`,
+		fmt.Sprintf("%d seconds", int64(per.Seconds())+1))
+}
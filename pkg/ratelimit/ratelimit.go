@@ -0,0 +1,201 @@
+// Package ratelimit throttles callers with token buckets kept in memory, for a single
+// instance, or in Postgres, shared by every instance of the service.
+//
+// A Limit of N requests per period is a bucket holding up to N tokens that refills at
+// N per period: bursts up to N go through, then one request every period/N.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bone_appetit_r4_service/pkg/logs"
+	"bone_appetit_r4_service/pkg/metrics"
+)
+
+// Rules the service limits
+const (
+	OTPPerClient    = "otp_per_client"
+	OTPPerTarget    = "otp_per_target"
+	ChangePerClient = "change_per_client"
+	ChangePerTarget = "change_per_target"
+	WebhookPerIP    = "webhook_per_ip"
+)
+
+// Limit allows Requests every Per. The zero Limit does not limit anything.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit reads a limit written as requests/period, e.g. 3/10m. An empty string or
+// 0 is no limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must be requests/period, e.g. 3/10m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("limit %q: requests must be a non-negative integer", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: period must be a positive duration", s)
+	}
+	if n == 0 {
+		return Limit{}, nil
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// Unlimited reports whether the limit lets everything through
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate is how many tokens the bucket gets back per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// MarshalText writes the limit as requests/period
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText reads the limit with ParseLimit, so it can be set in YAML
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until the bucket has a token again, when not allowed
+	RetryAfter time.Duration
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as sent in Retry-After
+func (r Result) RetryAfterSeconds() int {
+	return int(math.Ceil(r.RetryAfter.Seconds()))
+}
+
+// Store keeps the buckets
+type Store interface {
+	// Take removes a token from the bucket of key, refilled according to limit
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Refund gives back a token taken from the bucket of key, up to its capacity
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// takeToken refills tokens for the time elapsed and takes one if there is one, returning
+// the tokens left
+func takeToken(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	if elapsed > 0 {
+		tokens = math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.rate())
+	}
+	if tokens >= 1 {
+		return tokens - 1, Result{Allowed: true}
+	}
+	return tokens, Result{RetryAfter: retryAfter(tokens, limit)}
+}
+
+// retryAfter is how long a bucket with tokens takes to refill up to one token
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+}
+
+// Key names a bucket: the rule whose limit applies and who is limited, e.g. the API
+// client or the customer's phone
+type Key struct {
+	Rule  string
+	Value string
+}
+
+// Limiter checks the configured rules against a store
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// NewLimiter creates a limiter applying limits, keyed by rule, with the buckets in store.
+// Rules without a limit are not checked.
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Allow takes a token from the bucket of every key, returning the first one refused.
+// When one is refused the tokens already taken are given back, so a customer over its
+// limit does not use up the bucket of the API client or store calling for it.
+// A nil limiter allows everything, and so does a failing store: throttling is not worth
+// refusing payments when the database is struggling.
+func (l *Limiter) Allow(ctx context.Context, keys ...Key) Result {
+	if l == nil {
+		return Result{Allowed: true}
+	}
+	var taken []Key
+	for _, key := range keys {
+		limit := l.limits[key.Rule]
+		if limit.Unlimited() || key.Value == "" {
+			continue
+		}
+
+		result, err := l.store.Take(ctx, bucketKey(key), limit)
+		if err != nil {
+			metrics.RateLimitDecisions.WithLabelValues(key.Rule, "error").Inc()
+			logs.FromContext(ctx).Warn("rate limit check failed, allowing the request",
+				zap.String("rule", key.Rule), zap.Error(err))
+			continue
+		}
+		if !result.Allowed {
+			metrics.RateLimitDecisions.WithLabelValues(key.Rule, "limited").Inc()
+			logs.FromContext(ctx).Info("rate limited", zap.String("rule", key.Rule),
+				zap.Duration("retry_after", result.RetryAfter))
+			l.refund(ctx, taken)
+			return result
+		}
+		taken = append(taken, key)
+	}
+	for _, key := range taken {
+		metrics.RateLimitDecisions.WithLabelValues(key.Rule, "allowed").Inc()
+	}
+	return Result{Allowed: true}
+}
+
+// refund gives back the tokens of a request refused by a later key
+func (l *Limiter) refund(ctx context.Context, keys []Key) {
+	for _, key := range keys {
+		if err := l.store.Refund(ctx, bucketKey(key), l.limits[key.Rule]); err != nil {
+			logs.FromContext(ctx).Warn("could not refund rate limit token",
+				zap.String("rule", key.Rule), zap.Error(err))
+		}
+	}
+}
+
+// bucketKey is the store key of the bucket of key
+func bucketKey(key Key) string {
+	return key.Rule + ":" + key.Value
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"3/10m", Limit{Requests: 3, Per: 10 * time.Minute}, false},
+		{" 120 / 1m ", Limit{Requests: 120, Per: time.Minute}, false},
+		{"", Limit{}, false},
+		{"0", Limit{}, false},
+		{"0/1m", Limit{}, false},
+		{"3", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"3/0s", Limit{}, true},
+		{"3/minute", Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMemoryStoreRefillsAtTheLimitRate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Per: 30 * time.Second}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if result, _ := store.Take(ctx, "phone:04141234567", limit); !result.Allowed {
+			t.Fatalf("request %d of the burst was limited", i+1)
+		}
+	}
+	result, _ := store.Take(ctx, "phone:04141234567", limit)
+	if result.Allowed || result.RetryAfterSeconds() != 10 {
+		t.Fatalf("fourth request = %+v, want limited for 10s", result)
+	}
+	if result, _ := store.Take(ctx, "phone:04241234567", limit); !result.Allowed {
+		t.Fatal("another phone shares the bucket")
+	}
+
+	now = now.Add(4 * time.Second)
+	if result, _ := store.Take(ctx, "phone:04141234567", limit); result.Allowed || result.RetryAfterSeconds() != 6 {
+		t.Fatalf("after 4s = %+v, want limited for 6s", result)
+	}
+	now = now.Add(6 * time.Second)
+	if result, _ := store.Take(ctx, "phone:04141234567", limit); !result.Allowed {
+		t.Fatal("limited after the bucket got a token back")
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Take(ctx, "ip:10.0.0.1", Limit{Requests: 10, Per: time.Second})
+	store.Take(ctx, "phone:04141234567", Limit{Requests: 3, Per: time.Hour})
+
+	now = now.Add(2 * sweepEvery)
+	store.Take(ctx, "ip:10.0.0.2", Limit{Requests: 10, Per: time.Second})
+	if _, ok := store.buckets["ip:10.0.0.1"]; ok {
+		t.Error("a full bucket was kept")
+	}
+	if _, ok := store.buckets["phone:04141234567"]; !ok {
+		t.Error("a bucket still refilling was dropped")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func (failingStore) Refund(context.Context, string, Limit) error {
+	return errors.New("connection refused")
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{
+		OTPPerClient: {Requests: 5, Per: time.Minute},
+		OTPPerTarget: {Requests: 1, Per: time.Minute},
+	})
+
+	keys := []Key{{OTPPerClient, "key:1"}, {OTPPerTarget, "phone:04141234567"}, {ChangePerTarget, "dni:V12345678"}}
+	if result := limiter.Allow(ctx, keys...); !result.Allowed {
+		t.Fatal("first request limited")
+	}
+	if result := limiter.Allow(ctx, keys...); result.Allowed || result.RetryAfterSeconds() != 60 {
+		t.Fatalf("second request to the phone = %+v, want limited for 60s", result)
+	}
+	if result := limiter.Allow(ctx, Key{OTPPerClient, "key:1"}, Key{OTPPerTarget, "phone:04241234567"}); !result.Allowed {
+		t.Fatal("another phone of the same client limited")
+	}
+
+	var unset *Limiter
+	if !unset.Allow(ctx, keys...).Allowed {
+		t.Error("a nil limiter limited")
+	}
+	failing := NewLimiter(failingStore{}, map[string]Limit{OTPPerTarget: {Requests: 1, Per: time.Minute}})
+	if !failing.Allow(ctx, keys...).Allowed {
+		t.Error("a failing store limited")
+	}
+}
+
+func TestLimiterRefundsTheClientWhenATargetIsRefused(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	limiter := NewLimiter(store, map[string]Limit{
+		OTPPerClient: {Requests: 2, Per: time.Hour},
+		OTPPerTarget: {Requests: 1, Per: time.Hour},
+	})
+	request := func(phone string) Result {
+		return limiter.Allow(ctx, Key{OTPPerClient, "store:bone"}, Key{OTPPerTarget, "phone:" + phone})
+	}
+
+	if !request("04141234567").Allowed {
+		t.Fatal("first OTP limited")
+	}
+	for i := 0; i < 5; i++ {
+		if request("04141234567").Allowed {
+			t.Fatal("OTPs to the same phone not limited")
+		}
+	}
+	if tokens := store.buckets[OTPPerClient+":store:bone"].tokens; tokens != 1 {
+		t.Fatalf("store bucket has %v tokens after the refusals, want 1", tokens)
+	}
+	if !request("04241234567").Allowed {
+		t.Fatal("another customer limited by the refusals of the first one")
+	}
+	if request("04161234567").Allowed {
+		t.Fatal("store over its limit not limited")
+	}
+}
@@ -0,0 +1,11 @@
+package telemetry
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+// InstrumentGORM creates a span for every query run with a context, e.g. db.WithContext(ctx)
+func InstrumentGORM(db *gorm.DB) error {
+	return db.Use(tracing.NewPlugin(tracing.WithoutMetrics()))
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"bone_appetit_r4_service/pkg/banks"
+)
+
+var messages = map[string]string{
+	"required":    "is required",
+	"vephone":     "must be a Venezuelan mobile number (0412, 0414, 0416, 0424 or 0426)",
+	"vedni":       "must be a cédula or RIF with a V, E, J or G prefix",
+	"vebank":      "must be a known bank code",
+	"vebankdebit": "must be a bank that supports immediate debit",
+	"veamount":    fmt.Sprintf("must be greater than 0, at most %.2f and have at most two decimals", MaxAmount),
+	"numeric":     "must contain only digits",
+}
+
+// RegisterGinValidators registers the Venezuelan validators on gin's default validator
+// and makes field errors use the JSON field names.
+func RegisterGinValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin validator engine is not go-playground/validator")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	validators := map[string]validator.Func{
+		"vephone": func(fl validator.FieldLevel) bool { return IsMobilePhone(fl.Field().String()) },
+		"vedni":   func(fl validator.FieldLevel) bool { return IsDNI(fl.Field().String()) },
+		"vebank":  func(fl validator.FieldLevel) bool { return banks.IsKnown(fl.Field().String()) },
+		"vebankdebit": func(fl validator.FieldLevel) bool {
+			return banks.SupportsImmediateDebit(fl.Field().String())
+		},
+		"veamount": func(fl validator.FieldLevel) bool {
+			return IsAmount(fl.Field().Float())
+		},
+	}
+	for tag, fn := range validators {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("registering %s validator: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// FieldErrors maps each invalid field to a readable message.
+// It returns nil when err is not a validation error (e.g. malformed JSON).
+func FieldErrors(err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		msg, ok := messages[fe.Tag()]
+		if !ok {
+			msg = fmt.Sprintf("failed on the %s rule", fe.Tag())
+			if fe.Param() != "" {
+				msg = fmt.Sprintf("failed on the %s=%s rule", fe.Tag(), fe.Param())
+			}
+		}
+		fields[fe.Field()] = msg
+	}
+
+	return fields
+}
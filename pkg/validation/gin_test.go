@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type changeRequest struct {
+	Bank    string  `json:"bank" binding:"required,vebank"`
+	Amount  float64 `json:"amount" binding:"veamount"`
+	Phone   string  `json:"phone" binding:"vephone"`
+	DNI     string  `json:"dni" binding:"vedni"`
+	Concept string  `json:"concept" binding:"max=5"`
+	Skipped string  `json:"-" binding:"numeric"`
+}
+
+func TestFieldErrors(t *testing.T) {
+	if err := RegisterGinValidators(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := binding.Validator.ValidateStruct(&changeRequest{
+		Amount:  10.005,
+		Phone:   "02121234567",
+		DNI:     "J123456785",
+		Concept: "Vuelto de la orden",
+		Skipped: "12a",
+	})
+	want := map[string]string{
+		"bank":    messages["required"],
+		"amount":  messages["veamount"],
+		"phone":   messages["vephone"],
+		"dni":     messages["vedni"],
+		"concept": "failed on the max=5 rule",
+		"Skipped": messages["numeric"],
+	}
+	got := FieldErrors(err)
+	if len(got) != len(want) {
+		t.Fatalf("FieldErrors = %v, want %v", got, want)
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("FieldErrors[%q] = %q, want %q", field, got[field], msg)
+		}
+	}
+
+	valid := changeRequest{Bank: "0105", Amount: 35.75, Phone: "04141234567", DNI: "V12345678", Concept: "Orden", Skipped: "42"}
+	if err := binding.Validator.ValidateStruct(&valid); err != nil {
+		t.Errorf("valid request: %v", err)
+	}
+	if fields := FieldErrors(errors.New("unexpected EOF")); fields != nil {
+		t.Errorf("FieldErrors of a non-validation error = %v, want nil", fields)
+	}
+}
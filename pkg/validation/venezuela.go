@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// MaxAmount is the largest amount in bolívares accepted for a single operation
+const MaxAmount = 1_000_000.00
+
+var (
+	mobilePattern = regexp.MustCompile(`^04(12|14|16|24|26)\d{7}$`)
+	dniPattern    = regexp.MustCompile(`^([VEJG])(\d{6,9})$`)
+	nonDigits     = regexp.MustCompile(`\D`)
+	dniSeparators = strings.NewReplacer("-", "", ".", "", " ", "")
+)
+
+// rifWeights are the SENIAT weights applied to the prefix value and the 8 RIF digits
+var rifWeights = []int{4, 3, 2, 7, 6, 5, 4, 3, 2}
+
+var rifPrefixValues = map[byte]int{'V': 1, 'E': 2, 'J': 3, 'G': 5}
+
+// NormalizePhone converts a Venezuelan mobile number to the local 04XXXXXXXXX form.
+// It accepts separators and the 58 / +58 / 0058 international prefixes.
+func NormalizePhone(phone string) string {
+	digits := nonDigits.ReplaceAllString(phone, "")
+	switch {
+	case strings.HasPrefix(digits, "0058") && len(digits) == 14:
+		digits = "0" + digits[4:]
+	case strings.HasPrefix(digits, "58") && len(digits) == 12:
+		digits = "0" + digits[2:]
+	case strings.HasPrefix(digits, "4") && len(digits) == 10:
+		digits = "0" + digits
+	}
+	return digits
+}
+
+// IsMobilePhone reports whether phone is a Venezuelan mobile number once normalized
+func IsMobilePhone(phone string) bool {
+	return mobilePattern.MatchString(NormalizePhone(phone))
+}
+
+// NormalizeDNI uppercases a cédula or RIF and removes dashes, dots and spaces
+func NormalizeDNI(dni string) string {
+	return strings.ToUpper(dniSeparators.Replace(strings.TrimSpace(dni)))
+}
+
+// IsDNI reports whether dni is a valid cédula (V/E) or RIF (V/E/J/G).
+// Legal entities (J/G) must carry the 9-digit RIF with a valid check digit.
+func IsDNI(dni string) bool {
+	m := dniPattern.FindStringSubmatch(NormalizeDNI(dni))
+	if m == nil {
+		return false
+	}
+
+	prefix, digits := m[1][0], m[2]
+	if prefix == 'J' || prefix == 'G' {
+		return len(digits) == 9 && validRIFCheckDigit(prefix, digits)
+	}
+	return true
+}
+
+// IsAmount reports whether amount is positive, bounded and has at most two decimals
+func IsAmount(amount float64) bool {
+	if amount <= 0 || amount > MaxAmount {
+		return false
+	}
+	cents := amount * 100
+	return math.Abs(cents-math.Round(cents)) < 1e-6
+}
+
+func validRIFCheckDigit(prefix byte, digits string) bool {
+	sum := rifPrefixValues[prefix] * rifWeights[0]
+	for i := 0; i < 8; i++ {
+		sum += int(digits[i]-'0') * rifWeights[i+1]
+	}
+
+	check := 11 - sum%11
+	if check >= 10 {
+		check = 0
+	}
+	return int(digits[8]-'0') == check
+}
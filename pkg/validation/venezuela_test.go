@@ -0,0 +1,113 @@
+package validation
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"04141234567", "04141234567"},
+		{"0414-123.45.67", "04141234567"},
+		{"(0414) 123 4567", "04141234567"},
+		{"+58 414 1234567", "04141234567"},
+		{"584141234567", "04141234567"},
+		{"00584141234567", "04141234567"},
+		{"4141234567", "04141234567"},
+		{"0414123456", "0414123456"},
+		{"5841412345678", "5841412345678"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.in); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsMobilePhone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"04121234567", true},
+		{"+58 426 1234567", true},
+		{"04241234567", true},
+		{"02121234567", false},
+		{"04151234567", false},
+		{"0414123456", false},
+		{"abc", false},
+	}
+	for _, tt := range tests {
+		if got := IsMobilePhone(tt.in); got != tt.want {
+			t.Errorf("IsMobilePhone(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDNI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"V12345678", "V12345678"},
+		{" v-12.345.678 ", "V12345678"},
+		{"j-12345678-4", "J123456784"},
+		{"E 1234567", "E1234567"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeDNI(tt.in); got != tt.want {
+			t.Errorf("NormalizeDNI(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsDNI(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"V12345678", true},
+		{"e-1234567", true},
+		{"V123456", true},
+		{"V12345", false},
+		{"V1234567890", false},
+		{"P12345678", false},
+		{"12345678", false},
+		// Legal entities need the full RIF with its check digit
+		{"J-12345678-4", true},
+		{"J123456785", false},
+		{"J12345678", false},
+		{"G-20000000-7", true},
+		{"G200000008", false},
+		// A remainder of 0 or 1 gives check digit 0
+		{"J000000000", true},
+		// Natural persons may send a RIF, its check digit is not verified
+		{"V123456789", true},
+	}
+	for _, tt := range tests {
+		if got := IsDNI(tt.in); got != tt.want {
+			t.Errorf("IsDNI(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsAmount(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want bool
+	}{
+		{0.01, true},
+		{35.75, true},
+		{0.1 + 0.2, true},
+		{MaxAmount, true},
+		{0, false},
+		{-10, false},
+		{MaxAmount + 0.01, false},
+		{10.005, false},
+	}
+	for _, tt := range tests {
+		if got := IsAmount(tt.in); got != tt.want {
+			t.Errorf("IsAmount(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
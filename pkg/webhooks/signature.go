@@ -0,0 +1,80 @@
+// Package webhooks signs the event webhooks the service sends and lets subscribers verify them.
+//
+// Each request carries X-R4-Signature: t=<unix time>,v1=<hex HMAC-SHA256>, where the HMAC is
+// keyed with the subscription secret over "<unix time>.<body>". Receivers should reject
+// signatures older than a few minutes to prevent replays.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every event
+const (
+	SignatureHeader = "X-R4-Signature"
+	EventHeader     = "X-R4-Event"
+	EventIDHeader   = "X-R4-Event-ID"
+	DeliveryHeader  = "X-R4-Delivery"
+)
+
+var (
+	// ErrInvalidSignature is returned when the header is malformed or no signature matches
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrExpiredSignature is returned when the signature is older than the tolerance
+	ErrExpiredSignature = errors.New("webhook signature expired")
+)
+
+// Sign returns the X-R4-Signature value for body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + compute(secret, timestamp, body)
+}
+
+// Verify checks an X-R4-Signature value against body. Signatures made more than
+// tolerance ago, or that far in the future, are rejected.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignature
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+
+	expected := compute(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func compute(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
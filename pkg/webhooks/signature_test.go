@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyAcceptsSignedBody(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"payment.received"}`)
+	header := Sign("whsec_test", time.Now(), body)
+
+	if err := Verify("whsec_test", header, body, 5*time.Minute); err != nil {
+		t.Fatalf("Verify(%s) = %v", header, err)
+	}
+}
+
+func TestVerifyRejections(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		want   error
+	}{
+		{"other secret", "whsec_other", Sign("whsec_test", now, body), body, ErrInvalidSignature},
+		{"tampered body", "whsec_test", Sign("whsec_test", now, body), []byte(`{"id":"evt_2"}`), ErrInvalidSignature},
+		{"old signature", "whsec_test", Sign("whsec_test", now.Add(-time.Hour), body), body, ErrExpiredSignature},
+		{"no timestamp", "whsec_test", "v1=" + strings.Repeat("0", 64), body, ErrInvalidSignature},
+		{"no signature", "whsec_test", "t=1700000000", body, ErrInvalidSignature},
+		{"garbage", "whsec_test", "sha256", body, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}